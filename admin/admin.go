@@ -0,0 +1,129 @@
+// Package admin serves the introspection and the admin endpoints of the
+// debug listener of every binary. /debug/vars shows, next to the memory
+// statistics of expvar, the instances known by the clients, the circuit
+// breakers, the concurrency limiters, the build and the flags in effect.
+// The actions under /admin/ change the clients at runtime and require the
+// admin token.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/go-kit/kit/log"
+)
+
+// HeaderToken carries the admin token, "Authorization: Bearer <token>" is
+// accepted as well.
+const HeaderToken = "X-Admin-Token"
+
+// Register mounts the endpoints on the debug mux m. The admin actions are
+// refused when token is empty. Every action is logged with logger.
+func Register(m *http.ServeMux, token string, logger log.Logger) {
+	m.Handle("/debug/vars", expvar.Handler())
+	m.Handle("/admin/breaker", authorized(token, logger, http.HandlerFunc(forceOpen)))
+	m.Handle("/admin/drain", authorized(token, logger, http.HandlerFunc(drain)))
+}
+
+// Authorized tells whether r carries token. It is false when token is
+// empty, which disables the admin actions.
+func Authorized(token string, r *http.Request) bool {
+	given := r.Header.Get(HeaderToken)
+	if given == "" {
+		given = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// authorized only passes the POST requests carrying token to next.
+func authorized(token string, logger log.Logger, next http.Handler) http.Handler {
+	guarded := Protect(token, logger, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		guarded.ServeHTTP(w, r)
+	})
+}
+
+// Protect passes the GET requests to next, and the others only when they
+// carry token, e.g. for /debug/loglevel which shows the level to anyone but
+// changes it for the admins only. The changes are logged with logger.
+func Protect(token string, logger log.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token == "" {
+			http.Error(w, "admin actions are disabled, set -admin.token", http.StatusForbidden)
+			return
+		}
+		if !Authorized(token, r) {
+			logger.Log("action", r.URL.Path, "remote", r.RemoteAddr, "err", "invalid admin token")
+			http.Error(w, "invalid admin token", http.StatusUnauthorized)
+			return
+		}
+		logger.Log("action", r.URL.Path, "query", r.URL.RawQuery, "remote", r.RemoteAddr)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forceOpen forces the circuit breakers of an instance open, or releases
+// them with open=false:
+//
+//	POST /admin/breaker?service=topic&instance=10.0.0.3:8084&method=GetTopic&open=true
+//
+// Without a method, every method of the instance is matched.
+func forceOpen(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	open, err := parseBool(q.Get("open"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	matched := balancer.ForceOpen(q.Get("service"), q.Get("instance"), q.Get("method"), open)
+	if len(matched) == 0 {
+		http.Error(w, "no such breaker", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, matched)
+}
+
+// drain takes an instance out of the rotation of the clients, or puts it
+// back with drain=false:
+//
+//	POST /admin/drain?service=feed&instance=10.0.0.2:8082&drain=true
+func drain(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	on, err := parseBool(q.Get("drain"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !balancer.Drain(q.Get("service"), q.Get("instance"), on) {
+		http.Error(w, "no such instance", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, balancer.Clients()[q.Get("service")])
+}
+
+// parseBool defaults to true.
+func parseBool(s string) (bool, error) {
+	if s == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,35 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestProtect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, c := range []struct {
+		token, method, given string
+		want                 int
+	}{
+		{"secret", http.MethodGet, "", http.StatusOK},
+		{"secret", http.MethodPut, "", http.StatusUnauthorized},
+		{"secret", http.MethodPut, "guess", http.StatusUnauthorized},
+		{"secret", http.MethodPut, "secret", http.StatusOK},
+		// changes are disabled without a token.
+		{"", http.MethodPut, "", http.StatusForbidden},
+		{"", http.MethodGet, "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(c.method, "/debug/loglevel?level=debug", nil)
+		if c.given != "" {
+			req.Header.Set("Authorization", "Bearer "+c.given)
+		}
+		w := httptest.NewRecorder()
+		Protect(c.token, log.NewNopLogger(), ok).ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s with token %q: got %d, want %d", c.method, c.given, w.Code, c.want)
+		}
+	}
+}
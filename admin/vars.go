@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"expvar"
+	"flag"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/buptmiao/microservice-app/limit"
+)
+
+// secretFlags are the words of the names of the flags whose values are not
+// shown.
+var secretFlags = []string{"token", "secret", "password"}
+
+var started = time.Now()
+
+func init() {
+	expvar.Publish("build", expvar.Func(build))
+	expvar.Publish("config", expvar.Func(config))
+	expvar.Publish("clients", expvar.Func(func() interface{} { return balancer.Clients() }))
+	expvar.Publish("breakers", expvar.Func(func() interface{} { return balancer.Breakers() }))
+	expvar.Publish("limiters", expvar.Func(func() interface{} { return limit.All() }))
+}
+
+// build describes the binary, with the revision it was built from when
+// known.
+func build() interface{} {
+	res := map[string]string{
+		"go":      runtime.Version(),
+		"started": started.Format(time.RFC3339),
+		"uptime":  time.Since(started).Truncate(time.Second).String(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		res["path"] = info.Path
+		res["module"] = info.Main.Version
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				res[strings.TrimPrefix(s.Key, "vcs.")] = s.Value
+			}
+		}
+	}
+	return res
+}
+
+// config returns the value of every flag, the secrets masked.
+func config() interface{} {
+	res := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		res[f.Name] = f.Value.String()
+		for _, word := range secretFlags {
+			if strings.Contains(f.Name, word) && res[f.Name] != "" {
+				res[f.Name] = "[REDACTED]"
+			}
+		}
+	})
+	return res
+}
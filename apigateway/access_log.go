@@ -0,0 +1,54 @@
+package apigateway
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// AccessLog logs the inbound requests: failed and slower than slow ones
+// always, a sample of the others, between 0 and 1. The lines carry the
+// request and trace ids and the identity of the caller, see RequestID.
+func AccessLog(logger log.Logger, sample float64, slow time.Duration) gin.HandlerFunc {
+	logger = log.With(logger, "component", "access")
+	return func(c *gin.Context) {
+		begin := time.Now()
+		c.Next()
+		took := time.Since(begin)
+
+		status := c.Writer.Status()
+		var leveled func(log.Logger) log.Logger
+		switch {
+		case status >= http.StatusInternalServerError:
+			leveled = level.Error
+		case status >= http.StatusBadRequest || took >= slow:
+			leveled = level.Warn
+		case rand.Float64() < sample:
+			leveled = level.Info
+		default:
+			return
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = routeUnmatched
+		}
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		leveled(logging.With(c.Request.Context(), logger)).Log(
+			"method", c.Request.Method,
+			"route", route,
+			"status", status,
+			"took", took,
+			"slow", took >= slow,
+			"bytes", size,
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
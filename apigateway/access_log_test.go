@@ -0,0 +1,107 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/buptmiao/microservice-app/telemetry"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+)
+
+// accessLines serves each path through a gateway with the access log and
+// returns the lines logged.
+func accessLines(t *testing.T, sample float64, slow time.Duration, paths ...string) []map[string]interface{} {
+	var buf bytes.Buffer
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AccessLog(log.NewJSONLogger(&buf), sample, slow), RequestID(), Trace())
+	router.GET("/api/test/:status", func(c *gin.Context) {
+		switch c.Param("status") {
+		case "400":
+			c.Status(http.StatusBadRequest)
+		case "500":
+			c.Status(http.StatusInternalServerError)
+		default:
+			c.String(http.StatusOK, "ok")
+		}
+	})
+	for _, path := range paths {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		line := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestAccessLog(t *testing.T) {
+	// successes are not sampled, failures always are.
+	lines := accessLines(t, 0, time.Hour, "/api/test/200", "/api/test/400", "/api/test/500", "/nowhere")
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, want the 3 failures: %v", len(lines), lines)
+	}
+	for i, want := range []struct {
+		level, route string
+		status       float64
+	}{
+		{"warn", "/api/test/:status", 400},
+		{"error", "/api/test/:status", 500},
+		{"warn", routeUnmatched, 404},
+	} {
+		line := lines[i]
+		if line["level"] != want.level || line["route"] != want.route || line["status"] != want.status {
+			t.Errorf("line %d = %v, want %+v", i, line, want)
+		}
+		if line["request_id"] == nil || line["slow"] != false {
+			t.Errorf("line %d = %v", i, line)
+		}
+	}
+
+	// slow requests always are.
+	lines = accessLines(t, 0, time.Nanosecond, "/api/test/200")
+	if len(lines) != 1 || lines[0]["level"] != "warn" || lines[0]["slow"] != true {
+		t.Fatalf("slow request logged as %v", lines)
+	}
+
+	// every success is logged with a sample of 1.
+	lines = accessLines(t, 1, time.Hour, "/api/test/200", "/api/test/200")
+	if len(lines) != 2 || lines[0]["level"] != "info" || lines[0]["bytes"] != float64(2) {
+		t.Fatalf("sampled requests logged as %v", lines)
+	}
+}
+
+func TestAccessLogTraceID(t *testing.T) {
+	tel, err := telemetry.New(context.Background(), telemetry.Config{
+		Service:  "apigateway",
+		Exporter: telemetry.ExporterFile,
+		Endpoint: filepath.Join(t.TempDir(), "telemetry.json"),
+		Sample:   1,
+	}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tel.Shutdown(context.Background())
+	InitTracer(tel.Tracer)
+	defer InitTracer(nil)
+
+	lines := accessLines(t, 1, time.Hour, "/api/test/200")
+	if len(lines) != 1 || lines[0]["trace_id"] == nil || lines[0]["span_id"] == nil {
+		t.Fatalf("no trace ids in %v", lines)
+	}
+}
@@ -3,8 +3,16 @@ package apigateway
 import "github.com/gin-gonic/gin"
 
 func Register(router *gin.Engine) {
-	r := router.Group("/api")
+	router.Use(Metrics(), RequestID(), Trace())
+
+	r := router.Group("/api", Canary())
 	RegisterFeed(r)
 	RegisterProfile(r)
 	RegisterTopic(r)
+	RegisterSearch(r)
+	RegisterMedia(r)
+
+	admin := router.Group("/admin")
+	RegisterSplit(admin)
+	RegisterModeration(admin)
 }
@@ -0,0 +1,45 @@
+package apigateway
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/sd/lb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpStatus maps the gRPC code of a service error to the HTTP status of
+// the gateway: the errors of the request are 4xx, the others 500. The
+// balanced clients fail with the last error of their retries.
+func httpStatus(err error) int {
+	if e, ok := err.(lb.RetryError); ok {
+		err = e.Final
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+// fail answers c with the HTTP status of a service error.
+func fail(c *gin.Context, err error) {
+	code := httpStatus(err)
+	if code == http.StatusInternalServerError {
+		c.String(code, err.Error())
+		return
+	}
+	if e, ok := err.(lb.RetryError); ok {
+		err = e.Final
+	}
+	c.String(code, status.Convert(err).Message())
+}
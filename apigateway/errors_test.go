@@ -0,0 +1,32 @@
+package apigateway
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/kit/sd/lb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	for err, want := range map[error]int{
+		status.Error(codes.InvalidArgument, ""):  http.StatusBadRequest,
+		status.Error(codes.NotFound, ""):         http.StatusNotFound,
+		status.Error(codes.AlreadyExists, ""):    http.StatusConflict,
+		status.Error(codes.Aborted, ""):          http.StatusConflict,
+		status.Error(codes.PermissionDenied, ""): http.StatusForbidden,
+		status.Error(codes.Unavailable, ""):      http.StatusInternalServerError,
+		errors.New("breaker open"):               http.StatusInternalServerError,
+	} {
+		if got := httpStatus(err); got != want {
+			t.Errorf("%v: got %d, want %d", err, got, want)
+		}
+	}
+	// the balanced clients fail with the errors of their retries.
+	retried := lb.RetryError{Final: status.Error(codes.NotFound, "")}
+	if got := httpStatus(retried); got != http.StatusNotFound {
+		t.Errorf("%v: got %d, want the status of the last error", retried, got)
+	}
+}
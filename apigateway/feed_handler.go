@@ -4,7 +4,6 @@ import (
 	feed_client "github.com/buptmiao/microservice-app/client/feed"
 	"github.com/buptmiao/microservice-app/proto/feed"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/net/context"
 	"net/http"
 	"strconv"
 )
@@ -13,6 +12,13 @@ func RegisterFeed(router *gin.RouterGroup) {
 	r := router.Group("/feed")
 	r.GET("/get_feeds", GetFeeds)
 	r.PUT("create_feed", CreateFeed)
+	r.POST("/update_feed", UpdateFeed)
+	r.DELETE("/delete_feed", DeleteFeed)
+	r.GET("/feed_history", GetFeedHistory)
+	r.PUT("/like", LikeFeed)
+	r.DELETE("/like", UnlikeFeed)
+	r.PUT("/create_comment", CreateComment)
+	r.GET("/get_comments", GetComments)
 }
 
 func GetFeeds(c *gin.Context) {
@@ -27,9 +33,12 @@ func GetFeeds(c *gin.Context) {
 		return
 	}
 	req := &feed.GetFeedsRequest{userID, size}
-	resp, err := feed_client.GetClient().GetFeeds(context.Background(), req)
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().GetFeeds(c.Request.Context(), req)
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		fail(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, resp)
@@ -41,10 +50,186 @@ func CreateFeed(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
-	resp, err := feed_client.GetClient().CreateFeed(context.Background(), req)
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().CreateFeed(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if resp.Held {
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func UpdateFeed(c *gin.Context) {
+	req := &feed.UpdateFeedRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().UpdateFeed(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if resp.Held {
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func DeleteFeed(c *gin.Context) {
+	req := &feed.DeleteFeedRequest{}
+	var err error
+	if req.Id, err = strconv.ParseInt(c.Query("id"), 10, 64); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.UserId, err = strconv.ParseInt(c.Query("user_id"), 10, 64); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := c.Query("expected_version"); v != "" {
+		if req.ExpectedVersion, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().DeleteFeed(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func GetFeedHistory(c *gin.Context) {
+	req := &feed.GetFeedHistoryRequest{}
+	var err error
+	if req.Id, err = strconv.ParseInt(c.Query("id"), 10, 64); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	// owner_id routes the request to the instance holding the feed, the
+	// validation requires it.
+	if v := c.Query("owner_id"); v != "" {
+		if req.OwnerId, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().GetFeedHistory(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
+
+func parseLikeRequest(c *gin.Context) (*feed.LikeRequest, error) {
+	req := &feed.LikeRequest{}
+	var err error
+	if req.FeedId, err = strconv.ParseInt(c.Query("feed_id"), 10, 64); err != nil {
+		return nil, err
+	}
+	if req.UserId, err = strconv.ParseInt(c.Query("user_id"), 10, 64); err != nil {
+		return nil, err
+	}
+	if v := c.Query("owner_id"); v != "" {
+		if req.OwnerId, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func LikeFeed(c *gin.Context) {
+	req, err := parseLikeRequest(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().LikeFeed(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func UnlikeFeed(c *gin.Context) {
+	req, err := parseLikeRequest(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().UnlikeFeed(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func CreateComment(c *gin.Context) {
+	req := &feed.Comment{}
+	if err := c.BindJSON(req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().CreateComment(c.Request.Context(), req)
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		fail(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+func GetComments(c *gin.Context) {
+	req := &feed.GetCommentsRequest{}
+	var err error
+	if req.FeedId, err = strconv.ParseInt(c.Query("feed_id"), 10, 64); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	for name, v := range map[string]*int64{"parent_id": &req.ParentId, "cursor": &req.Cursor, "size": &req.Size, "owner_id": &req.OwnerId} {
+		if q := c.Query(name); q != "" {
+			if *v, err = strconv.ParseInt(q, 10, 64); err != nil {
+				c.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().GetComments(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
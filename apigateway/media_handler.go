@@ -0,0 +1,150 @@
+package apigateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/buptmiao/microservice-app/media"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/gin-gonic/gin"
+)
+
+// allowedMediaTypes are the accepted upload types, as sniffed from the content.
+var allowedMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"video/mp4":  true,
+}
+
+var (
+	mediaStore   media.BlobStore
+	mediaMaxSize int64
+)
+
+// InitMedia sets the blob store of uploaded files and the maximum upload size in bytes.
+func InitMedia(store media.BlobStore, maxSize int64) {
+	mediaStore = store
+	mediaMaxSize = maxSize
+}
+
+func RegisterMedia(router *gin.RouterGroup) {
+	r := router.Group("/media")
+	r.POST("/upload", UploadMedia)
+	r.GET("/:key", GetMedia)
+}
+
+// UploadMedia stores the multipart "file" field and returns the Attachment to
+// reference it from a feed. Identical files are stored once.
+func UploadMedia(c *gin.Context) {
+	if mediaStore == nil {
+		c.String(http.StatusServiceUnavailable, "media store is not configured")
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, mediaMaxSize+1<<20)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if fh.Size > mediaMaxSize {
+		c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf("file larger than %d bytes", mediaMaxSize))
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(f, mediaMaxSize+1))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if int64(len(data)) > mediaMaxSize {
+		c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf("file larger than %d bytes", mediaMaxSize))
+		return
+	}
+	// the declared type is not trusted, the content decides.
+	contentType := http.DetectContentType(data)
+	if !allowedMediaTypes[contentType] {
+		c.String(http.StatusUnsupportedMediaType, "unsupported media type "+contentType)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	attachment := &feed.Attachment{
+		Id:          key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Url:         feed.MediaURL + key,
+	}
+	// the thumbnail is made first, so that an image which cannot be decoded
+	// is refused before anything is stored.
+	var thumb *bytes.Buffer
+	if contentType != "video/mp4" {
+		thumb = &bytes.Buffer{}
+		if err := media.Thumbnail(thumb, bytes.NewReader(data)); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		attachment.ThumbnailUrl = feed.MediaURL + key + media.ThumbnailSuffix
+	}
+
+	ctx := c.Request.Context()
+	exists, err := mediaStore.Exists(ctx, key)
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !exists {
+		if err := mediaStore.Put(ctx, key, bytes.NewReader(data)); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if thumb != nil {
+		if err := mediaStore.Put(ctx, key+media.ThumbnailSuffix, thumb); err != nil {
+			if !exists {
+				// do not leave a blob without its thumbnail.
+				mediaStore.Delete(ctx, key)
+			}
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	c.JSON(http.StatusOK, attachment)
+}
+
+func GetMedia(c *gin.Context) {
+	if mediaStore == nil {
+		c.String(http.StatusServiceUnavailable, "media store is not configured")
+		return
+	}
+	rc, err := mediaStore.Get(c.Request.Context(), c.Param("key"))
+	switch err {
+	case nil:
+	case media.ErrBlobNotFound, media.ErrInvalidKey:
+		c.String(http.StatusNotFound, err.Error())
+		return
+	default:
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer rc.Close()
+	// blobs are content addressed and never change.
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Status(http.StatusOK)
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(rc, head)
+	c.Header("Content-Type", http.DetectContentType(head[:n]))
+	c.Writer.Write(head[:n])
+	io.Copy(c.Writer, rc)
+}
@@ -0,0 +1,149 @@
+package apigateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/buptmiao/microservice-app/media"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/context"
+)
+
+// failingThumbs is a BlobStore failing to store the thumbnails.
+type failingThumbs struct {
+	media.BlobStore
+}
+
+func (s failingThumbs) Put(ctx context.Context, key string, r io.Reader) error {
+	if strings.HasSuffix(key, media.ThumbnailSuffix) {
+		return errors.New("disk full")
+	}
+	return s.BlobStore.Put(ctx, key, r)
+}
+
+func newMediaRouter(t *testing.T) (*gin.Engine, media.BlobStore, func()) {
+	dir, err := ioutil.TempDir("", "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := media.NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitMedia(store, 1<<20)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterMedia(router.Group("/api"))
+	RegisterFeed(router.Group("/api"))
+	return router, store, func() { os.RemoveAll(dir) }
+}
+
+func upload(router http.Handler, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "upload")
+	fw.Write(data)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/media/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadMedia(t *testing.T) {
+	router, store, cleanup := newMediaRouter(t)
+	defer cleanup()
+	var img bytes.Buffer
+	png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 300, 300)))
+
+	w := upload(router, img.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", w.Code, w.Body)
+	}
+	a := &feed.Attachment{}
+	if err := json.Unmarshal(w.Body.Bytes(), a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Url != feed.MediaURL+a.Id || a.ThumbnailUrl != a.Url+media.ThumbnailSuffix || a.ContentType != "image/png" {
+		t.Errorf("unexpected attachment %+v", a)
+	}
+	for _, url := range []string{a.Url, a.ThumbnailUrl} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: got %d", url, w.Code)
+		}
+	}
+
+	if w := upload(router, []byte("plain text")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text upload got %d", w.Code)
+	}
+
+	// an image claiming 4 gigapixels is refused, and nothing is stored.
+	var bomb bytes.Buffer
+	gif.Encode(&bomb, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9), nil)
+	copy(bomb.Bytes()[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+	if w := upload(router, bomb.Bytes()); w.Code != http.StatusBadRequest {
+		t.Errorf("bomb upload got %d", w.Code)
+	}
+	sum := sha256.Sum256(bomb.Bytes())
+	if ok, _ := store.Exists(context.Background(), hex.EncodeToString(sum[:])); ok {
+		t.Error("the refused image was stored")
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/media/not-a-key", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("invalid key got %d", w.Code)
+	}
+}
+
+func TestUploadMediaCleansUp(t *testing.T) {
+	router, store, cleanup := newMediaRouter(t)
+	defer cleanup()
+	InitMedia(failingThumbs{store}, 1<<20)
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 10, 10)))
+
+	if w := upload(router, img.Bytes()); w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d, want 500", w.Code)
+	}
+	// the blob stored before the thumbnail failed was removed.
+	sum := sha256.Sum256(img.Bytes())
+	if ok, _ := store.Exists(context.Background(), hex.EncodeToString(sum[:])); ok {
+		t.Error("the blob without a thumbnail was kept")
+	}
+}
+
+func TestFeedAttachmentURL(t *testing.T) {
+	router, _, cleanup := newMediaRouter(t)
+	defer cleanup()
+	id := strings.Repeat("ab", 32)
+	for url, want := range map[string]int{
+		"http://evil.example/x.png": http.StatusBadRequest,
+		feed.MediaURL + "other":     http.StatusBadRequest,
+	} {
+		body := `{"user_id":1,"content":"hi","attachments":[{"id":"` + id + `","url":"` + url + `"}]}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/feed/create_feed", strings.NewReader(body)))
+		if w.Code != want {
+			t.Errorf("%s: got %d, want %d", url, w.Code, want)
+		}
+	}
+}
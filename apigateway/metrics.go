@@ -0,0 +1,52 @@
+package apigateway
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/buptmiao/microservice-app/slo"
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// routeUnmatched labels the requests which matched no route, so that
+// scanners can not blow up the number of series.
+const routeUnmatched = "unmatched"
+
+var (
+	httpRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "apigateway",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration metrics.Histogram = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "apigateway",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request duration in seconds, by route.",
+		Buckets:   util.LatencyBuckets,
+	}, []string{"method", "route"})
+)
+
+// Metrics records the rate, errors and duration of the requests of each
+// route. Routes are labelled by their pattern, e.g. /api/feed/:id. The
+// requests are also counted against the objectives declared for "GET
+// /api/feed/get_feeds" style routes, failing when answered with a 5xx.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		begin := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = routeUnmatched
+		}
+		took := time.Since(begin)
+		httpRequests.With("method", c.Request.Method, "route", route, "status", strconv.Itoa(c.Writer.Status())).Add(1)
+		httpDuration.With("method", c.Request.Method, "route", route).Observe(took.Seconds())
+		slo.Record(c.Request.Method+" "+route, c.Writer.Status() >= 500, took)
+	}
+}
@@ -0,0 +1,63 @@
+package apigateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// requestsCounted returns the count of apigateway_http_requests_total with
+// the given route and status.
+func requestsCounted(t *testing.T, route, status string) float64 {
+	families, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "apigateway_http_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["route"] == route && labels["status"] == status {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetricsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics())
+	router.GET("/api/test/:id", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	for _, c := range []struct {
+		path, route, status string
+	}{
+		// requests are labelled by the pattern of their route, not the path.
+		{"/api/test/1", "/api/test/:id", "418"},
+		{"/api/test/2", "/api/test/:id", "418"},
+		// and by a single label for the paths no route matches.
+		{"/wp-login.php", routeUnmatched, "404"},
+		{"/.env", routeUnmatched, "404"},
+	} {
+		before := requestsCounted(t, c.route, c.status)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, c.path, nil))
+		if got := requestsCounted(t, c.route, c.status) - before; got != 1 {
+			t.Errorf("%s: counted %v requests of route %s, want 1", c.path, got, c.route)
+		}
+	}
+	if n := requestsCounted(t, "/api/test/1", "418"); n != 0 {
+		t.Errorf("the path of a request was used as its route")
+	}
+}
@@ -0,0 +1,96 @@
+package apigateway
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	feed_client "github.com/buptmiao/microservice-app/client/feed"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/gin-gonic/gin"
+)
+
+// Moderator headers: the token of the moderators, "Authorization: Bearer
+// <token>" is accepted as well, and the name of the moderator, recorded
+// with each review.
+const (
+	HeaderModeratorToken = "X-Moderator-Token"
+	HeaderModerator      = "X-Moderator"
+)
+
+var moderatorToken string
+
+// InitModeration sets the token of the moderators, the review queue is
+// disabled when it is empty.
+func InitModeration(token string) {
+	moderatorToken = token
+}
+
+func RegisterModeration(router *gin.RouterGroup) {
+	r := router.Group("/moderation", Moderator())
+	r.GET("/held", ListHeldFeeds)
+	r.POST("/review", ReviewFeed)
+}
+
+// Moderator only lets the requests carrying the moderator token through.
+func Moderator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if moderatorToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "the review queue is disabled, set -moderation.token"})
+			return
+		}
+		given := c.GetHeader(HeaderModeratorToken)
+		if given == "" {
+			given = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(moderatorToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid moderator token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ListHeldFeeds returns the feeds waiting for review, oldest first.
+func ListHeldFeeds(c *gin.Context) {
+	req := &feed.ListHeldFeedsRequest{}
+	if s := c.Query("size"); s != "" {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Size = size
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().ListHeldFeeds(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, resp)
+}
+
+// ReviewFeed publishes or drops a held feed, e.g.
+// {"id": 1, "owner_id": 123, "approve": true, "note": "quoting the news"}.
+// The moderator is named by the X-Moderator header.
+func ReviewFeed(c *gin.Context) {
+	req := &feed.ReviewFeedRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Moderator = c.GetHeader(HeaderModerator)
+	if !valid(c, req) {
+		return
+	}
+	resp, err := feed_client.GetClient().ReviewFeed(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
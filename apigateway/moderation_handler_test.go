@@ -0,0 +1,139 @@
+package apigateway
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	feed_client "github.com/buptmiao/microservice-app/client/feed"
+	feed_service "github.com/buptmiao/microservice-app/feed"
+	"github.com/buptmiao/microservice-app/moderation"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/log"
+	"github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc"
+)
+
+// newModerationRouter serves the feed and moderation routes in front of a
+// feed service holding the posts about a giveaway and rejecting the casinos.
+func newModerationRouter(t *testing.T, addr string) (*gin.Engine, func()) {
+	blocklist, err := moderation.NewBlocklist([]moderation.BlockRule{
+		{Keyword: "casino", Verdict: moderation.Reject},
+		{Keyword: "giveaway", Verdict: moderation.Hold},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := feed_service.NewFeedService(nil, moderation.NewChain(blocklist), log.NewNopLogger())
+	s := grpc.NewServer()
+	feed.RegisterFeedServer(s, feed_service.MakeGRPCServer(service, opentracing.NoopTracer{}, log.NewNopLogger()))
+	go s.Serve(ln)
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed_client.Init(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterFeed(router.Group("/api"))
+	RegisterModeration(router.Group("/admin"))
+	return router, func() {
+		conn.Close()
+		s.GracefulStop()
+	}
+}
+
+func moderatorRequest(router http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set(HeaderModeratorToken, token)
+	}
+	req.Header.Set(HeaderModerator, "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestModeratorToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterModeration(router.Group("/admin"))
+	defer InitModeration("")
+
+	InitModeration("")
+	if w := moderatorRequest(router, http.MethodGet, "/admin/moderation/held", "secret", ""); w.Code != http.StatusForbidden {
+		t.Errorf("disabled review queue got %d, want 403", w.Code)
+	}
+	InitModeration("secret")
+	if w := moderatorRequest(router, http.MethodGet, "/admin/moderation/held", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous request got %d, want 401", w.Code)
+	}
+	if w := moderatorRequest(router, http.MethodPost, "/admin/moderation/review", "guess", `{"id": 1, "owner_id": 1, "approve": true}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token got %d, want 401", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/moderation/review", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bearer token with an empty review got %d, want 400", w.Code)
+	}
+}
+
+func TestModeration(t *testing.T) {
+	router, cleanup := newModerationRouter(t, ":8020")
+	defer cleanup()
+	InitModeration("secret")
+	defer InitModeration("")
+
+	for _, tc := range []struct {
+		name         string
+		method, path string
+		token, body  string
+		want         int
+	}{
+		{"rejected feed", http.MethodPut, "/api/feed/create_feed", "", `{"id": 1001, "user_id": 1002, "content": "casino night"}`, http.StatusForbidden},
+		{"held feed", http.MethodPut, "/api/feed/create_feed", "", `{"id": 1001, "user_id": 1002, "content": "giveaway tonight"}`, http.StatusAccepted},
+		{"review without owner", http.MethodPost, "/admin/moderation/review", "secret", `{"id": 1001, "approve": true}`, http.StatusBadRequest},
+		{"review of an unknown feed", http.MethodPost, "/admin/moderation/review", "secret", `{"id": 1003, "owner_id": 1002, "approve": true}`, http.StatusNotFound},
+		{"approved feed", http.MethodPost, "/admin/moderation/review", "secret", `{"id": 1001, "owner_id": 1002, "approve": true}`, http.StatusOK},
+		{"second review", http.MethodPost, "/admin/moderation/review", "secret", `{"id": 1001, "owner_id": 1002, "approve": true}`, http.StatusNotFound},
+		{"rejected edit", http.MethodPost, "/api/feed/update_feed", "", `{"id": 1001, "user_id": 1002, "content": "giveaway at the casino"}`, http.StatusForbidden},
+		{"held edit", http.MethodPost, "/api/feed/update_feed", "", `{"id": 1001, "user_id": 1002, "content": "giveaway, round two"}`, http.StatusAccepted},
+		{"allowed edit", http.MethodPost, "/api/feed/update_feed", "", `{"id": 1001, "user_id": 1002, "content": "see you tonight"}`, http.StatusOK},
+	} {
+		if w := moderatorRequest(router, tc.method, tc.path, tc.token, tc.body); w.Code != tc.want {
+			t.Errorf("%s: got %d, want %d: %s", tc.name, w.Code, tc.want, w.Body)
+		}
+	}
+
+	// the held edit is listed, and approving it after another edit conflicts.
+	w := moderatorRequest(router, http.MethodGet, "/admin/moderation/held?size=5", "secret", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list got %d, want 200", w.Code)
+	}
+	var held feed.ListHeldFeedsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &held); err != nil {
+		t.Fatal(err)
+	}
+	if len(held.Feeds) != 1 || !held.Feeds[0].Edit || held.Feeds[0].Feed.Content != "giveaway, round two" {
+		t.Fatalf("unexpected review queue %s", w.Body)
+	}
+	if w := moderatorRequest(router, http.MethodPost, "/admin/moderation/review", "secret", `{"id": 1001, "owner_id": 1002, "approve": true}`); w.Code != http.StatusConflict {
+		t.Errorf("approving a stale edit got %d, want 409", w.Code)
+	}
+	if w := moderatorRequest(router, http.MethodGet, "/admin/moderation/held?size=x", "secret", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid size got %d, want 400", w.Code)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	profile_client "github.com/buptmiao/microservice-app/client/profile"
 	"github.com/buptmiao/microservice-app/proto/profile"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/net/context"
 	"net/http"
 	"strconv"
 )
@@ -22,9 +21,12 @@ func GetProfile(c *gin.Context) {
 	}
 
 	req := &profile.GetProfileRequest{userID}
-	resp, err := profile_client.GetClient().GetProfile(context.Background(), req)
+	if !valid(c, req) {
+		return
+	}
+	resp, err := profile_client.GetClient().GetProfile(c.Request.Context(), req)
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		fail(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, resp)
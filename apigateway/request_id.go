@@ -0,0 +1,26 @@
+package apigateway
+
+import (
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID carries the id of a request, see RequestID.
+const HeaderRequestID = "X-Request-Id"
+
+// RequestID gives each request an id, unless its client did, and attaches it
+// to the request context along with the identity of the caller, the user_id
+// parameter. The services log both with every line about the request. The
+// id is sent back in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderRequestID)
+		if id == "" {
+			id = logging.NewRequestID()
+		}
+		c.Header(HeaderRequestID, id)
+		fields := logging.Fields{RequestID: id, Identity: c.Query("user_id")}
+		c.Request = c.Request.WithContext(logging.WithFields(c.Request.Context(), fields))
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package apigateway
+
+import (
+	"net/http"
+	"strconv"
+
+	feed_client "github.com/buptmiao/microservice-app/client/feed"
+	topic_client "github.com/buptmiao/microservice-app/client/topic"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/buptmiao/microservice-app/proto/topic"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSearchSize = 10
+
+func RegisterSearch(router *gin.RouterGroup) {
+	router.GET("/search", Search)
+}
+
+type searchResponse struct {
+	Feeds  []*feed.FeedRecord        `json:"feeds"`
+	Topics []*topic.GetTopicResponse `json:"topics"`
+}
+
+// Search queries the feed and topic indexes concurrently and returns both
+// result lists, each ordered by relevance.
+func Search(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		c.String(http.StatusBadRequest, "missing query")
+		return
+	}
+	size := int64(defaultSearchSize)
+	if s := c.Query("size"); s != "" {
+		var err error
+		if size, err = strconv.ParseInt(s, 10, 64); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	feedsReq := &feed.SearchFeedsRequest{Query: q, Size: size}
+	topicsReq := &topic.SearchTopicsRequest{Query: q, Size: size}
+	if !valid(c, feedsReq) || !valid(c, topicsReq) {
+		return
+	}
+
+	var (
+		feedsResp  *feed.GetFeedsResponse
+		topicsResp *topic.SearchTopicsResponse
+		feedsErr   = make(chan error, 1)
+	)
+	go func() {
+		var err error
+		feedsResp, err = feed_client.GetClient().SearchFeeds(c.Request.Context(), feedsReq)
+		feedsErr <- err
+	}()
+	topicsResp, err := topic_client.GetClient().SearchTopics(c.Request.Context(), topicsReq)
+	if ferr := <-feedsErr; ferr != nil {
+		err = ferr
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, &searchResponse{
+		Feeds:  feedsResp.GetFeeds(),
+		Topics: topicsResp.GetTopics(),
+	})
+}
@@ -0,0 +1,96 @@
+package apigateway
+
+import (
+	"net/http"
+
+	"github.com/buptmiao/microservice-app/admin"
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/gin-gonic/gin"
+)
+
+// Routing override headers, see Canary.
+const (
+	HeaderCanary  = "X-Canary"
+	HeaderVersion = "X-Version"
+)
+
+var (
+	// splitters holds the traffic Split of each service, by service name.
+	splitters map[string]*balancer.Splitter
+	// adminToken guards the Split, see admin.Authorized.
+	adminToken string
+)
+
+// InitSplit sets the splitters the clients were built with, so that their
+// Split can be changed at runtime by the holders of the admin token. The
+// Split is not served when token is empty.
+func InitSplit(s map[string]*balancer.Splitter, token string) {
+	splitters = s
+	adminToken = token
+}
+
+// Canary attaches the routing overrides of a request to its context:
+// "X-Canary: true" sends it to the canary version of every service,
+// "X-Version" to a given version, and the user_id parameter pins it to the
+// version of the user.
+func Canary() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hint := balancer.Hint{
+			Canary:  c.GetHeader(HeaderCanary) == "true",
+			Version: c.GetHeader(HeaderVersion),
+			User:    c.Query("user_id"),
+		}
+		c.Request = c.Request.WithContext(balancer.WithHint(c.Request.Context(), hint))
+		c.Next()
+	}
+}
+
+func RegisterSplit(router *gin.RouterGroup) {
+	r := router.Group("/split", Admin())
+	r.GET("/:service", GetSplit)
+	r.PUT("/:service", SetSplit)
+}
+
+// Admin only lets the requests carrying the admin token through.
+func Admin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin actions are disabled, set -admin.token"})
+			return
+		}
+		if !admin.Authorized(adminToken, c.Request) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func GetSplit(c *gin.Context) {
+	s, ok := splitters[c.Param("service")]
+	if !ok {
+		c.String(http.StatusNotFound, "unknown service")
+		return
+	}
+	c.IndentedJSON(http.StatusOK, s.Get())
+}
+
+// SetSplit replaces the Split of a service, e.g.
+// {"weights": {"1.0": 90, "1.1": 10}, "canary": "1.1"}.
+func SetSplit(c *gin.Context) {
+	s, ok := splitters[c.Param("service")]
+	if !ok {
+		c.String(http.StatusNotFound, "unknown service")
+		return
+	}
+	split := balancer.Split{}
+	if err := c.BindJSON(&split); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.Set(split); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	c.IndentedJSON(http.StatusOK, split)
+}
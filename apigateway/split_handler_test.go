@@ -0,0 +1,42 @@
+package apigateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/buptmiao/microservice-app/admin"
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSplitRequiresAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterSplit(router.Group("/admin"))
+	put := func(token string) int {
+		req := httptest.NewRequest(http.MethodPut, "/admin/split/feed", strings.NewReader(`{"weights": {"1.0": 90, "1.1": 10}}`))
+		if token != "" {
+			req.Header.Set(admin.HeaderToken, token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	InitSplit(map[string]*balancer.Splitter{"feed": balancer.NewSplitter()}, "")
+	if code := put("secret"); code != http.StatusForbidden {
+		t.Errorf("without an admin token got %d, want 403", code)
+	}
+	InitSplit(map[string]*balancer.Splitter{"feed": balancer.NewSplitter()}, "secret")
+	if code := put(""); code != http.StatusUnauthorized {
+		t.Errorf("anonymous request got %d, want 401", code)
+	}
+	if code := put("guess"); code != http.StatusUnauthorized {
+		t.Errorf("invalid token got %d, want 401", code)
+	}
+	if code := put("secret"); code != http.StatusOK {
+		t.Errorf("admin request got %d, want 200", code)
+	}
+}
@@ -1,10 +1,11 @@
 package apigateway
 
 import (
+	feed_client "github.com/buptmiao/microservice-app/client/feed"
 	topic_client "github.com/buptmiao/microservice-app/client/topic"
+	"github.com/buptmiao/microservice-app/proto/feed"
 	"github.com/buptmiao/microservice-app/proto/topic"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/net/context"
 	"net/http"
 	"strconv"
 )
@@ -12,6 +13,13 @@ import (
 func RegisterTopic(router *gin.RouterGroup) {
 	r := router.Group("/topic")
 	r.GET("/view", view)
+	r.PUT("/create", create)
+}
+
+// topicView is a topic together with the posts discussing it.
+type topicView struct {
+	*topic.GetTopicResponse
+	Feeds []*feed.FeedRecord `json:"feeds"`
 }
 
 func view(c *gin.Context) {
@@ -20,12 +28,45 @@ func view(c *gin.Context) {
 		c.String(http.StatusBadRequest, err.Error())
 		return
 	}
+	size := int64(10)
+	if s := c.Query("size"); s != "" {
+		if size, err = strconv.ParseInt(s, 10, 64); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	req := &topic.GetTopicRequest{TopicId: topicID}
+	feedsReq := &feed.GetFeedsByTopicRequest{TopicId: topicID, Size: size}
+	if !valid(c, req) || !valid(c, feedsReq) {
+		return
+	}
+	resp, err := topic_client.GetClient().GetTopic(c.Request.Context(), req)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	feeds, err := feed_client.GetClient().GetFeedsByTopic(c.Request.Context(), feedsReq)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, &topicView{GetTopicResponse: resp, Feeds: feeds.GetFeeds()})
+}
 
-	req := &topic.GetTopicRequest{topicID}
-	resp, err := topic_client.GetClient().GetTopic(context.Background(), req)
+func create(c *gin.Context) {
+	req := &topic.CreateTopicRequest{}
+	if err := c.BindJSON(req); err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if !valid(c, req) {
+		return
+	}
+	resp, err := topic_client.GetClient().CreateTopic(c.Request.Context(), req)
 	if err != nil {
-		c.String(http.StatusInternalServerError, err.Error())
+		fail(c, err)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,50 @@
+package apigateway
+
+import (
+	"github.com/gin-gonic/gin"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// tracer starts the spans of the inbound requests, see Trace. The global
+// tracer is used until InitTracer, looked up on each request as it may be
+// set after this package is initialized.
+var tracer stdopentracing.Tracer
+
+// InitTracer sets the tracer the clients were built with, so that their
+// spans are children of the span of the inbound request.
+func InitTracer(t stdopentracing.Tracer) {
+	tracer = t
+}
+
+// Trace starts the span of each request, named after its route. It is the
+// root span of the trace, unless the caller sent its own trace context, e.g.
+// in a W3C traceparent header.
+func Trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tracer := tracer
+		if tracer == nil {
+			tracer = stdopentracing.GlobalTracer()
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = routeUnmatched
+		}
+		opts := []stdopentracing.StartSpanOption{ext.SpanKindRPCServer}
+		if parent, err := tracer.Extract(stdopentracing.HTTPHeaders, stdopentracing.HTTPHeadersCarrier(c.Request.Header)); err == nil {
+			opts = append(opts, stdopentracing.ChildOf(parent))
+		}
+		span := tracer.StartSpan(c.Request.Method+" "+route, opts...)
+		defer span.Finish()
+		ext.HTTPMethod.Set(span, c.Request.Method)
+		ext.HTTPUrl.Set(span, c.Request.URL.String())
+
+		c.Request = c.Request.WithContext(stdopentracing.ContextWithSpan(c.Request.Context(), span))
+		c.Next()
+
+		ext.HTTPStatusCode.Set(span, uint16(c.Writer.Status()))
+		if c.Writer.Status() >= 500 {
+			ext.Error.Set(span, true)
+		}
+	}
+}
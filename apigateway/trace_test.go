@@ -0,0 +1,50 @@
+package apigateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestTrace(t *testing.T) {
+	mock := mocktracer.New()
+	InitTracer(mock)
+	defer InitTracer(nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Trace())
+	var inner stdopentracing.Span
+	router.GET("/api/test/:id", func(c *gin.Context) {
+		inner = stdopentracing.SpanFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	// the caller's trace context is continued.
+	parent := mock.StartSpan("caller")
+	req := httptest.NewRequest(http.MethodGet, "/api/test/1", nil)
+	mock.Inject(parent.Context(), stdopentracing.HTTPHeaders, stdopentracing.HTTPHeadersCarrier(req.Header))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := mock.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.OperationName != "GET /api/test/:id" {
+		t.Errorf("span named %q, want the route", span.OperationName)
+	}
+	if span.ParentID != parent.Context().(mocktracer.MockSpanContext).SpanID {
+		t.Error("span is not a child of the caller's span")
+	}
+	if span.Tag("http.status_code") != uint16(500) || span.Tag("error") != true {
+		t.Errorf("unexpected tags %v", span.Tags())
+	}
+	if inner != stdopentracing.Span(span) {
+		t.Error("the span is not in the context of the handlers")
+	}
+}
@@ -0,0 +1,30 @@
+package apigateway
+
+import (
+	"net/http"
+
+	"github.com/buptmiao/microservice-app/validate"
+	"github.com/gin-gonic/gin"
+)
+
+// invalidResponse lists the fields of a request breaking its rules.
+type invalidResponse struct {
+	Error      string               `json:"error"`
+	Violations []validate.Violation `json:"violations"`
+}
+
+// valid checks req against the rules the service enforces, so that an
+// invalid request is answered without a round trip. It writes a 400 with
+// the violations and returns false when req breaks any.
+func valid(c *gin.Context, req interface{}) bool {
+	err := validate.Request(req)
+	if err == nil {
+		return true
+	}
+	resp := invalidResponse{Error: err.Error()}
+	if e, ok := err.(*validate.Error); ok {
+		resp.Violations = e.Violations
+	}
+	c.JSON(http.StatusBadRequest, resp)
+	return false
+}
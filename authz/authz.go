@@ -0,0 +1,117 @@
+// Package authz decides which callers may call which methods of a service.
+// The callers are identified by their certificate with mutual TLS, see
+// mtls, and the Policy is enforced by a gRPC server interceptor in front of
+// the endpoints of every service.
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/buptmiao/microservice-app/mtls"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Any matches any method in the rules of a service, and any caller,
+// authenticated or not, in the callers of a method.
+const Any = "*"
+
+// Anonymous labels the callers without a certificate in the metrics.
+const Anonymous = "anonymous"
+
+// Decisions, as labelled in the metrics.
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+var decisions metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+	Namespace: "authz",
+	Name:      "decisions_total",
+	Help:      "Number of authorization decisions by service, method, caller and decision.",
+}, []string{"service", "method", "caller", "decision"})
+
+// Policy lists the callers allowed for each method of each service, see
+// authz/policy.json. Everything not allowed is denied, the methods of a
+// service missing from the policy included.
+type Policy struct {
+	// DryRun logs and counts the denials without enforcing them, to try a
+	// new policy.
+	DryRun bool `json:"dry_run"`
+	// Services maps a service, then a method or Any, to the identities of
+	// the callers allowed or Any.
+	Services map[string]map[string][]string `json:"services"`
+}
+
+// Load reads the Policy of a JSON file.
+func Load(path string) (*Policy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Allowed tells whether caller may call method of service, caller being
+// empty when not authenticated. The rules of the method take precedence
+// over those of Any.
+func (p *Policy) Allowed(service, method, caller string) bool {
+	rules := p.Services[service]
+	callers, ok := rules[method]
+	if !ok {
+		callers = rules[Any]
+	}
+	for _, c := range callers {
+		if c == Any || (caller != "" && c == caller) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor enforces p on the requests to service, the caller
+// being identified by mtls.PeerIdentity. The denied requests fail with
+// Unauthenticated without an identity, PermissionDenied otherwise. Every
+// decision is counted, the denials are logged.
+func UnaryServerInterceptor(p *Policy, service string, logger log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+		caller, _ := mtls.PeerIdentity(ctx)
+		label := caller
+		if label == "" {
+			label = Anonymous
+		}
+		if p.Allowed(service, method, caller) {
+			decisions.With("service", service, "method", method, "caller", label, "decision", DecisionAllow).Add(1)
+			return handler(ctx, req)
+		}
+
+		decisions.With("service", service, "method", method, "caller", label, "decision", DecisionDeny).Add(1)
+		md, _ := metadata.FromIncomingContext(ctx)
+		logger := logging.With(logging.GRPCToContext(ctx, md), logger)
+		level.Warn(logger).Log("authz", DecisionDeny, "method", method, "caller", label, "dry_run", p.DryRun)
+		if p.DryRun {
+			return handler(ctx, req)
+		}
+		if caller == "" {
+			return nil, status.Errorf(codes.Unauthenticated, "%s requires an authenticated caller", method)
+		}
+		return nil, status.Errorf(codes.PermissionDenied, "%s may not call %s", caller, method)
+	}
+}
@@ -0,0 +1,55 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAllowed(t *testing.T) {
+	p, err := Load("policy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		service, method, caller string
+		allowed                 bool
+	}{
+		{"feed", "CreateFeed", "apigateway", true},
+		{"feed", "CreateFeed", "topic", false},
+		{"feed", "CreateFeed", "", false},
+		{"topic", "ResolveTags", "feed", true},
+		{"topic", "CreateTopic", "feed", false},
+		{"media", "Upload", "apigateway", false},
+	} {
+		if got := p.Allowed(c.service, c.method, c.caller); got != c.allowed {
+			t.Errorf("%s calling %s.%s allowed %v", c.caller, c.service, c.method, got)
+		}
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	p := &Policy{Services: map[string]map[string][]string{
+		"feed": {"GetFeeds": {Any}, Any: {"apigateway"}},
+	}}
+	handler := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+	call := func(method string) error {
+		_, err := UnaryServerInterceptor(p, "feed", log.NewNopLogger())(context.Background(), nil,
+			&grpc.UnaryServerInfo{FullMethod: "/feed.Feed/" + method}, handler)
+		return err
+	}
+	if err := call("GetFeeds"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("CreateFeed"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("anonymous CreateFeed returned %v", err)
+	}
+	p.DryRun = true
+	if err := call("CreateFeed"); err != nil {
+		t.Fatalf("dry run returned %v", err)
+	}
+}
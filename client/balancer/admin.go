@@ -0,0 +1,181 @@
+package balancer
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The caches and the hedgers of every client of the process, for the admin
+// endpoints. Clients are built once at startup, they are never dropped.
+var (
+	registryMu sync.Mutex
+	caches     []*cache
+	hedgers    []*hedger
+
+	// service to the addresses of its drained instances, replaced on change.
+	drained atomic.Value
+)
+
+func init() {
+	drained.Store(map[string]map[string]bool{})
+}
+
+func registerCache(c *cache) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	caches = append(caches, c)
+}
+
+func registerHedger(h *hedger) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	hedgers = append(hedgers, h)
+}
+
+// withoutDrained returns the nodes of service which are not drained.
+func withoutDrained(service string, nodes []*node) []*node {
+	addrs := drained.Load().(map[string]map[string]bool)[service]
+	if len(addrs) == 0 {
+		return nodes
+	}
+	res := make([]*node, 0, len(nodes))
+	for _, n := range nodes {
+		if !addrs[n.Addr] {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// Drain takes the instance of service at addr out of the rotation, or puts
+// it back when drain is false: the requests in flight complete, no new one
+// is sent to it. With ConsistentHash, its keys move to the other instances.
+// It returns false when the clients do not know the instance.
+func Drain(service, addr string, drain bool) bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	var matched []*cache
+	for _, c := range caches {
+		if c.options.service != service {
+			continue
+		}
+		for _, n := range c.snapshot() {
+			if n.Addr == addr {
+				matched = append(matched, c)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return false
+	}
+
+	old := drained.Load().(map[string]map[string]bool)
+	next := make(map[string]map[string]bool, len(old)+1)
+	for s, addrs := range old {
+		next[s] = addrs
+	}
+	addrs := make(map[string]bool, len(old[service])+1)
+	for a := range old[service] {
+		addrs[a] = true
+	}
+	if drain {
+		addrs[addr] = true
+	} else {
+		delete(addrs, addr)
+	}
+	next[service] = addrs
+	drained.Store(next)
+
+	for _, c := range matched {
+		c.refresh()
+	}
+	return true
+}
+
+// InstanceState is what the admin endpoints show of an instance, summed
+// over the endpoints of every method.
+type InstanceState struct {
+	Addr     string `json:"addr"`
+	Version  string `json:"version,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+	Weight   int    `json:"weight"`
+	Inflight int64  `json:"inflight"`
+	Failures int64  `json:"failures"`
+	Ejected  bool   `json:"ejected"`
+	Drained  bool   `json:"drained"`
+}
+
+// HedgeState is the budget and the delay of the hedged method of a client.
+type HedgeState struct {
+	Method string  `json:"method"`
+	Tokens float64 `json:"tokens"`
+	Delay  string  `json:"delay"`
+}
+
+// ClientState is what the admin endpoints show of the client of a service.
+type ClientState struct {
+	Instances []InstanceState `json:"instances"`
+	Hedges    []HedgeState    `json:"hedges,omitempty"`
+}
+
+// Clients returns the state of the clients of the process by service, the
+// shadow instances being the service suffixed with -shadow.
+func Clients() map[string]*ClientState {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	now := time.Now().UnixNano()
+	all := drained.Load().(map[string]map[string]bool)
+	res := make(map[string]*ClientState)
+	client := func(service string) *ClientState {
+		cs, ok := res[service]
+		if !ok {
+			cs = &ClientState{Instances: []InstanceState{}}
+			res[service] = cs
+		}
+		return cs
+	}
+
+	for _, c := range caches {
+		cs := client(c.options.service)
+		for _, n := range c.snapshot() {
+			i := 0
+			for i < len(cs.Instances) && cs.Instances[i].Addr != n.Addr {
+				i++
+			}
+			if i == len(cs.Instances) {
+				cs.Instances = append(cs.Instances, InstanceState{
+					Addr:    n.Addr,
+					Version: n.Version,
+					Zone:    n.Zone,
+					Weight:  n.Weight,
+					Drained: all[n.service][n.Addr],
+				})
+			}
+			s := &cs.Instances[i]
+			s.Inflight += atomic.LoadInt64(&n.inflight)
+			if f := atomic.LoadInt64(&n.failures); f > s.Failures {
+				s.Failures = f
+			}
+			s.Ejected = s.Ejected || !n.healthy(now)
+		}
+	}
+	for _, cs := range res {
+		sort.Slice(cs.Instances, func(i, j int) bool { return cs.Instances[i].Addr < cs.Instances[j].Addr })
+	}
+
+	for _, h := range hedgers {
+		h.mu.Lock()
+		tokens := h.tokens
+		h.mu.Unlock()
+		cs := client(h.options.service)
+		cs.Hedges = append(cs.Hedges, HedgeState{
+			Method: h.options.mirror,
+			Tokens: tokens,
+			Delay:  time.Duration(atomic.LoadInt64(&h.delay)).String(),
+		})
+	}
+	return res
+}
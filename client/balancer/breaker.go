@@ -0,0 +1,164 @@
+package balancer
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/circuitbreaker"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/sony/gobreaker"
+	"golang.org/x/net/context"
+)
+
+type breakerKey struct {
+	service, instance, method string
+}
+
+// breaker is the circuit breaker of a method on one instance, shared by
+// every endpoint of the process calling it.
+type breaker struct {
+	breakerKey
+	cb *gobreaker.CircuitBreaker
+	// 1 while forced open by ForceOpen.
+	forced int32
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[breakerKey]*breaker)
+)
+
+// breakerFor returns the breaker of method on one instance of service,
+// reporting its state in the metrics.
+func breakerFor(service, instance, method string) *breaker {
+	key := breakerKey{service, instance, method}
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if b, ok := breakers[key]; ok {
+		return b
+	}
+	state := breakerState.With("service", service, "instance", instance, "method", method)
+	state.Set(float64(gobreaker.StateClosed))
+	b := &breaker{breakerKey: key}
+	b.cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:    method,
+		Timeout: 5 * time.Second,
+		OnStateChange: func(_ string, _, to gobreaker.State) {
+			if atomic.LoadInt32(&b.forced) == 0 {
+				state.Set(float64(to))
+			}
+		},
+	})
+	breakers[key] = b
+	return b
+}
+
+// Breaker returns the circuit breaker middleware of method on one instance
+// of service. While forced open, see ForceOpen, the requests fail right away
+// with gobreaker.ErrOpenState. The errors of the requests themselves, see
+// requestFault, do not count as failures of the instance.
+func Breaker(service, instance, method string) endpoint.Middleware {
+	b := breakerFor(service, instance, method)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		broken := circuitbreaker.Gobreaker(b.cb)(func(ctx context.Context, request interface{}) (interface{}, error) {
+			response, err := next(ctx, request)
+			if requestFault(err) {
+				return requestError{err}, nil
+			}
+			return response, err
+		})
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if atomic.LoadInt32(&b.forced) == 1 {
+				return nil, gobreaker.ErrOpenState
+			}
+			response, err := broken(ctx, request)
+			if e, ok := response.(requestError); ok {
+				return nil, e.err
+			}
+			return response, err
+		}
+	}
+}
+
+// requestError carries a request fault through the circuit breaker as a
+// success.
+type requestError struct {
+	err error
+}
+
+// BreakerState is what the admin endpoints show of a circuit breaker.
+type BreakerState struct {
+	Service  string `json:"service"`
+	Instance string `json:"instance"`
+	Method   string `json:"method"`
+	State    string `json:"state"`
+	Forced   bool   `json:"forced"`
+}
+
+func (b *breaker) state() BreakerState {
+	s := BreakerState{
+		Service:  b.service,
+		Instance: b.instance,
+		Method:   b.method,
+		State:    b.cb.State().String(),
+		Forced:   atomic.LoadInt32(&b.forced) == 1,
+	}
+	if s.Forced {
+		s.State = gobreaker.StateOpen.String()
+	}
+	return s
+}
+
+// Breakers returns the state of every circuit breaker of the process, by
+// service, instance and method.
+func Breakers() []BreakerState {
+	breakersMu.Lock()
+	res := make([]BreakerState, 0, len(breakers))
+	for _, b := range breakers {
+		res = append(res, b.state())
+	}
+	breakersMu.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Service != res[j].Service {
+			return res[i].Service < res[j].Service
+		}
+		if res[i].Instance != res[j].Instance {
+			return res[i].Instance < res[j].Instance
+		}
+		return res[i].Method < res[j].Method
+	})
+	return res
+}
+
+// ForceOpen forces the breakers of an instance of service open, or lets
+// them follow the requests again when open is false. An empty method
+// matches every method. It returns the breakers matched.
+func ForceOpen(service, instance, method string, open bool) []BreakerState {
+	forced := int32(0)
+	if open {
+		forced = 1
+	}
+	breakersMu.Lock()
+	var matched []*breaker
+	for key, b := range breakers {
+		if key.service == service && key.instance == instance && (method == "" || key.method == method) {
+			matched = append(matched, b)
+		}
+	}
+	breakersMu.Unlock()
+
+	res := []BreakerState{}
+	for _, b := range matched {
+		atomic.StoreInt32(&b.forced, forced)
+		state := breakerState.With("service", b.service, "instance", b.instance, "method", b.method)
+		if open {
+			state.Set(float64(gobreaker.StateOpen))
+		} else {
+			state.Set(float64(b.cb.State()))
+		}
+		res = append(res, b.state())
+	}
+	return res
+}
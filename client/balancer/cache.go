@@ -0,0 +1,210 @@
+package balancer
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"golang.org/x/net/context"
+)
+
+// node is a discovered instance and the endpoint built for it.
+type node struct {
+	// the registration value, and the metadata decoded from it.
+	instance string
+	discovery.Instance
+	endpoint endpoint.Endpoint
+	closer   io.Closer
+
+	// the service of the client, and whether the instance is in its zone.
+	service string
+	local   bool
+
+	// requests in flight, see leastOutstanding.
+	inflight int64
+	// current weight of the smooth weighted round robin, see weighted.
+	current int
+	// consecutive failures, and the end of the ejection in UnixNano, see zone.go.
+	failures     int64
+	ejectedUntil int64
+}
+
+// call forwards the request to the instance, counting it while in flight
+// and tracking the health of the instance.
+func (n *node) call(ctx context.Context, request interface{}) (interface{}, error) {
+	atomic.AddInt64(&n.inflight, 1)
+	defer atomic.AddInt64(&n.inflight, -1)
+	if t, ok := ctx.Value(triedKey{}).(*tried); ok {
+		t.add(n)
+	}
+	zoneRequests.With("service", n.service, "zone", n.Zone, "local", strconv.FormatBool(n.local)).Add(1)
+	begin := time.Now()
+	response, err := n.endpoint(ctx, request)
+	took := time.Since(begin).Seconds()
+	versionDuration.With("service", n.service, "version", n.Version, "success", strconv.FormatBool(err == nil)).Observe(took)
+	instanceDuration.With("service", n.service, "instance", n.Addr, "code", util.Code(err)).Observe(took)
+	n.observe(err)
+	return response, err
+}
+
+// cache keeps a node for every instance of the instancer accepted by the
+// filters, in the order they were discovered. It is an sd.Endpointer of the
+// candidates of each request, so the go-kit balancers work on it.
+type cache struct {
+	factory  sd.Factory
+	options  options
+	logger   log.Logger
+	onUpdate func(nodes []*node)
+
+	// serializes the updates and the refreshes, so that onUpdate sees the
+	// nodes in order and a refresh never puts back older ones.
+	updateMu sync.Mutex
+
+	mu    sync.RWMutex
+	nodes []*node
+}
+
+// newCache subscribes to the instancer. onUpdate, if not nil, is called with
+// the new nodes on every change, before they are visible to the readers. The
+// instancers send their state on subscription, newCache waits for it a
+// little so that the cache is usable right away.
+func newCache(instancer sd.Instancer, factory sd.Factory, o options, logger log.Logger, onUpdate func(nodes []*node)) *cache {
+	c := &cache{
+		factory:  factory,
+		options:  o,
+		logger:   logger,
+		onUpdate: onUpdate,
+	}
+	ch := make(chan sd.Event)
+	ready := make(chan struct{})
+	go func() {
+		first := true
+		for event := range ch {
+			c.update(event)
+			if first {
+				close(ready)
+				first = false
+			}
+		}
+	}()
+	instancer.Register(ch)
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+	}
+	registerCache(c)
+	return c
+}
+
+func (c *cache) update(event sd.Event) {
+	if event.Err != nil {
+		// keep using the last known instances.
+		c.logger.Log("err", event.Err)
+		return
+	}
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.mu.RLock()
+	old := make(map[string]*node, len(c.nodes))
+	for _, n := range c.nodes {
+		old[n.instance] = n
+	}
+	c.mu.RUnlock()
+
+	nodes := []*node{}
+	for _, instance := range event.Instances {
+		if n, ok := old[instance]; ok {
+			nodes = append(nodes, n)
+			delete(old, instance)
+			continue
+		}
+		inst, err := discovery.Parse(instance)
+		if err != nil {
+			c.logger.Log("instance", instance, "err", err)
+			continue
+		}
+		if !c.accept(inst) {
+			continue
+		}
+		e, closer, err := c.factory(inst.Addr)
+		if err != nil {
+			c.logger.Log("instance", instance, "err", err)
+			continue
+		}
+		nodes = append(nodes, &node{
+			instance: instance,
+			Instance: inst,
+			endpoint: e,
+			closer:   closer,
+			service:  c.options.service,
+			local:    c.options.zone != "" && inst.Zone == c.options.zone,
+		})
+	}
+	if c.onUpdate != nil {
+		c.onUpdate(nodes)
+	}
+
+	c.mu.Lock()
+	c.nodes = nodes
+	c.mu.Unlock()
+
+	for _, n := range old {
+		if n.closer != nil {
+			n.closer.Close()
+		}
+	}
+}
+
+// refresh calls onUpdate again with the current nodes, after a drain.
+func (c *cache) refresh() {
+	if c.onUpdate == nil {
+		return
+	}
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	c.onUpdate(c.snapshot())
+}
+
+func (c *cache) accept(inst discovery.Instance) bool {
+	for _, f := range c.options.filters {
+		if !f(inst) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasVersion tells if a healthy node runs version.
+func (c *cache) hasVersion(version string) bool {
+	now := time.Now().UnixNano()
+	for _, n := range withoutDrained(c.options.service, c.snapshot()) {
+		if n.Version == version && n.healthy(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot returns the current nodes, the slice must not be modified.
+func (c *cache) snapshot() []*node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nodes
+}
+
+// Endpoints implements sd.Endpointer.
+func (c *cache) Endpoints() ([]endpoint.Endpoint, error) {
+	nodes := c.candidates()
+	res := make([]endpoint.Endpoint, 0, len(nodes))
+	for _, n := range nodes {
+		res = append(res, n.call)
+	}
+	return res, nil
+}
@@ -0,0 +1,234 @@
+package balancer
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	// latencies kept to estimate the hedging delay, and the number of new
+	// ones after which it is estimated again.
+	hedgeWindow  = 512
+	hedgeRefresh = 32
+	// at most this many hedges may be sent in a burst, whatever the budget.
+	maxHedgeTokens = 10
+)
+
+// Results of a hedge.
+const (
+	hedgeSent      = "sent"
+	hedgeWon       = "won"
+	hedgeThrottled = "throttled"
+)
+
+var hedgeRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+	Namespace: "client",
+	Subsystem: "hedge",
+	Name:      "requests_total",
+	Help:      "Number of hedged requests sent, won by the hedge, or not sent for lack of budget.",
+}, []string{"service", "method", "result"})
+
+// Hedge sends a second copy of a slow read to another instance and returns
+// whichever answers first, the other one is canceled. It trades a little
+// more traffic for a shorter tail latency.
+type Hedge struct {
+	// Percentile of the latency of the method after which a request is
+	// hedged, between 0 and 1, e.g. 0.95.
+	Percentile float64
+	// Budget is the fraction of the requests which may be hedged, e.g. 0.05.
+	Budget float64
+	// Delay, when set, is the fixed delay after which a request is hedged,
+	// instead of the one estimated from the Percentile.
+	Delay time.Duration
+}
+
+// WithHedge hedges the requests of the Mirror methods. It does not apply to
+// ConsistentHash, whose requests must stay on one instance.
+func WithHedge(h Hedge) Option {
+	return func(o *options) { o.hedge = &h }
+}
+
+// tried records the nodes a request was sent to, so that its hedge goes to
+// another one.
+type tried struct {
+	mu    sync.Mutex
+	nodes map[*node]bool
+}
+
+type triedKey struct{}
+
+func (t *tried) add(n *node) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nodes[n] = true
+}
+
+func (t *tried) has(n *node) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.nodes[n]
+}
+
+// hedger estimates the hedging delay of a method and enforces its budget.
+type hedger struct {
+	cache   *cache
+	options options
+
+	mu        sync.Mutex
+	latencies []float64
+	next      int
+	fresh     int
+	tokens    float64
+	// the current delay in nanoseconds, 0 until enough latencies are known.
+	delay int64
+}
+
+// hedged sends the requests through primary and, when one has not
+// answered after the Percentile latency and the budget allows it, a hedge to
+// the least loaded candidate of c it was not sent to yet.
+func hedged(primary endpoint.Endpoint, c *cache, o options) endpoint.Endpoint {
+	h := &hedger{cache: c, options: o, latencies: make([]float64, 0, hedgeWindow), delay: int64(o.hedge.Delay)}
+	registerHedger(h)
+	type result struct {
+		response interface{}
+		err      error
+		hedge    bool
+	}
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		h.deposit()
+		begin := time.Now()
+		delay := time.Duration(atomic.LoadInt64(&h.delay))
+		if delay <= 0 {
+			response, err := primary(ctx, request)
+			if err == nil {
+				h.observe(time.Since(begin))
+			}
+			return response, err
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		// cancels the request which lost the race.
+		defer cancel()
+		t := &tried{nodes: make(map[*node]bool)}
+		ctx = context.WithValue(ctx, triedKey{}, t)
+
+		results := make(chan result, 2)
+		go func() {
+			response, err := primary(ctx, request)
+			results <- result{response, err, false}
+		}()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		pending := 1
+		var firstErr error
+		for {
+			select {
+			case r := <-results:
+				pending--
+				if r.err == nil {
+					h.observe(time.Since(begin))
+					if r.hedge {
+						hedgeRequests.With("service", o.service, "method", o.mirror, "result", hedgeWon).Add(1)
+					}
+					return r.response, nil
+				}
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				if pending == 0 {
+					return nil, firstErr
+				}
+			case <-timer.C:
+				n := h.pick(t)
+				if n == nil {
+					continue
+				}
+				if !h.withdraw() {
+					hedgeRequests.With("service", o.service, "method", o.mirror, "result", hedgeThrottled).Add(1)
+					continue
+				}
+				hedgeRequests.With("service", o.service, "method", o.mirror, "result", hedgeSent).Add(1)
+				pending++
+				go func() {
+					hctx, hcancel := context.WithTimeout(ctx, o.timeout)
+					defer hcancel()
+					response, err := n.call(hctx, request)
+					results <- result{response, err, true}
+				}()
+			}
+		}
+	}
+}
+
+// pick returns the candidate with the fewest requests in flight among those
+// the request was not sent to, nil if there is none.
+func (h *hedger) pick(t *tried) *node {
+	var best *node
+	for _, n := range h.cache.candidates() {
+		if t.has(n) {
+			continue
+		}
+		if best == nil || atomic.LoadInt64(&n.inflight) < atomic.LoadInt64(&best.inflight) {
+			best = n
+		}
+	}
+	return best
+}
+
+// deposit earns the budget of one request.
+func (h *hedger) deposit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens += h.options.hedge.Budget
+	if h.tokens > maxHedgeTokens {
+		h.tokens = maxHedgeTokens
+	}
+}
+
+// withdraw spends the budget of one hedge, it fails when there is not enough.
+func (h *hedger) withdraw() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		return false
+	}
+	h.tokens--
+	return true
+}
+
+// observe records the latency of a successful request and estimates the
+// delay again every hedgeRefresh of them, unless the delay is fixed.
+func (h *hedger) observe(d time.Duration) {
+	if h.options.hedge.Delay > 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeWindow {
+		h.latencies = append(h.latencies, d.Seconds())
+	} else {
+		h.latencies[h.next] = d.Seconds()
+		h.next = (h.next + 1) % hedgeWindow
+	}
+	h.fresh++
+	if h.fresh < hedgeRefresh {
+		return
+	}
+	h.fresh = 0
+	sorted := append([]float64(nil), h.latencies...)
+	sort.Float64s(sorted)
+	i := int(h.options.hedge.Percentile * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	atomic.StoreInt64(&h.delay, int64(sorted[i]*float64(time.Second)))
+}
@@ -0,0 +1,23 @@
+package balancer
+
+import (
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	instanceDuration metrics.Histogram = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "client",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the requests to each instance of a service, by gRPC code.",
+		Buckets:   util.LatencyBuckets,
+	}, []string{"service", "instance", "code"})
+	breakerState metrics.Gauge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "client",
+		Subsystem: "breaker",
+		Name:      "state",
+		Help:      "State of the circuit breaker of each method and instance: 0 closed, 1 half-open, 2 open.",
+	}, []string{"service", "instance", "method"})
+)
@@ -0,0 +1,208 @@
+package balancer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	"github.com/go-kit/kit/sd"
+	"github.com/golang/protobuf/proto"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/status"
+)
+
+// maxShadowInflight bounds the mirrored requests in flight, beyond which
+// they are dropped rather than piling up behind a slow shadow.
+const maxShadowInflight = 100
+
+// A shadow which disagrees usually does on every request: the mismatches of
+// a method are logged at most once per diffLogEvery, the metrics count them
+// all. At most maxDiffFields differing fields are named.
+const (
+	diffLogEvery  = time.Second
+	maxDiffFields = 10
+)
+
+// Results of a mirrored request.
+const (
+	shadowMatch   = "match"
+	shadowDiff    = "diff"
+	shadowDropped = "dropped"
+)
+
+var shadowRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+	Namespace: "client",
+	Subsystem: "shadow",
+	Name:      "requests_total",
+	Help:      "Number of requests mirrored to the shadow instances, by result.",
+}, []string{"service", "method", "result"})
+
+// Shadow is a secondary set of instances receiving a copy of the read
+// requests, to validate them against real traffic. Their responses are only
+// compared to the primary ones, never returned.
+type Shadow struct {
+	Instancer sd.Instancer
+	// Sample is the fraction of the requests mirrored, between 0 and 1.
+	Sample float64
+	// Timeout bounds each mirrored request.
+	Timeout time.Duration
+}
+
+// WithShadow mirrors the requests of the Mirror methods to the shadow.
+func WithShadow(s Shadow) Option {
+	return func(o *options) { o.shadow = &s }
+}
+
+// Mirror marks a method as safe to mirror to the shadow, to hedge and to
+// retry: it does not write anything. method names it in the metrics and logs.
+func Mirror(method string) Option {
+	return func(o *options) { o.mirror = method }
+}
+
+// shadowOptions returns the options of the shadow side of o: the same
+// strategy, without split, zone, hedge nor shadow of its own.
+func shadowOptions(o options) options {
+	o.shadow, o.mirror, o.split, o.zone, o.hedge = nil, "", nil, "", nil
+	o.service += "-shadow"
+	return o
+}
+
+// mirrored sends every request to primary and a sample of them to shadow in
+// the background, recording whether both agree.
+func mirrored(primary, shadow endpoint.Endpoint, logger log.Logger, o options) endpoint.Endpoint {
+	sem := make(chan struct{}, maxShadowInflight)
+	sample, timeout := o.shadow.Sample, o.shadow.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	logger = log.With(logger, "component", "shadow", "service", o.service, "method", o.mirror)
+	diffLogs := rate.NewLimiter(rate.Every(diffLogEvery), 1)
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := primary(ctx, request)
+		if rand.Float64() >= sample {
+			return response, err
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			shadowRequests.With("service", o.service, "method", o.mirror, "result", shadowDropped).Add(1)
+			return response, err
+		}
+		go func() {
+			defer func() { <-sem }()
+			// the request is over, the mirror must not be canceled with it.
+			sctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			sresponse, serr := shadow(sctx, request)
+			result := shadowMatch
+			if diff := compare(response, err, sresponse, serr); diff != "" {
+				result = shadowDiff
+				if diffLogs.Allow() {
+					logger.Log("diff", diff)
+				}
+			}
+			shadowRequests.With("service", o.service, "method", o.mirror, "result", result).Add(1)
+		}()
+		return response, err
+	}
+}
+
+// compare describes how the shadow response differs from the primary one,
+// "" if they agree. The payloads may hold personal data, only the status
+// codes, the names of the differing fields and digests of the redacted
+// payloads, see logging.Payload, are described.
+func compare(response interface{}, err error, sresponse interface{}, serr error) string {
+	switch {
+	case err != nil || serr != nil:
+		if status.Code(err) != status.Code(serr) {
+			return fmt.Sprintf("primary %s, shadow %s", status.Code(err), status.Code(serr))
+		}
+		return ""
+	case equal(response, sresponse):
+		return ""
+	}
+	var fields []string
+	diffFields("", tree(response), tree(sresponse), &fields)
+	return fmt.Sprintf("fields %s differ, primary %s, shadow %s", strings.Join(fields, ","), digest(response), digest(sresponse))
+}
+
+// tree returns the JSON form of v as maps and slices.
+func tree(v interface{}) interface{} {
+	var res interface{}
+	if raw, err := json.Marshal(v); err == nil {
+		json.Unmarshal(raw, &res)
+	}
+	return res
+}
+
+// diffFields appends to fields the paths of the values differing between
+// the JSON trees a and b, up to maxDiffFields.
+func diffFields(path string, a, b interface{}, fields *[]string) {
+	if len(*fields) >= maxDiffFields {
+		return
+	}
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if okA && okB {
+		keys := make([]string, 0, len(ma)+len(mb))
+		for k := range ma {
+			keys = append(keys, k)
+		}
+		for k := range mb {
+			if _, ok := ma[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffFields(strings.TrimPrefix(path+"."+k, "."), ma[k], mb[k], fields)
+		}
+		return
+	}
+	la, okA := a.([]interface{})
+	lb, okB := b.([]interface{})
+	if okA && okB && len(la) == len(lb) {
+		for i := range la {
+			diffFields(path+"["+strconv.Itoa(i)+"]", la[i], lb[i], fields)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "."
+		}
+		*fields = append(*fields, path)
+	}
+}
+
+// digest tells the payloads apart without showing them.
+func digest(v interface{}) string {
+	raw, err := json.Marshal(logging.Payload(v))
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+func equal(a, b interface{}) bool {
+	pa, ok := a.(proto.Message)
+	if pb, ok2 := b.(proto.Message); ok && ok2 {
+		return proto.Equal(pa, pb)
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,50 @@
+// Package balancer holds the load balancing strategies shared by the clients,
+// beyond the round robin of go-kit.
+package balancer
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// DefaultReplicas is the number of virtual nodes per instance on a Ring.
+const DefaultReplicas = 100
+
+// Ring is a consistent hash ring. Adding or removing one of n nodes only
+// moves about 1/n of the keys. A Ring is immutable, build a new one when the
+// nodes change.
+type Ring struct {
+	hashes []uint32
+	owners map[uint32]string
+}
+
+// NewRing places replicas virtual nodes for each node on the ring.
+func NewRing(replicas int, nodes ...string) *Ring {
+	r := &Ring{owners: make(map[uint32]string, replicas*len(nodes))}
+	for _, node := range nodes {
+		for i := 0; i < replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(node + "#" + strconv.Itoa(i)))
+			if _, ok := r.owners[h]; ok {
+				continue
+			}
+			r.owners[h] = node
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// Get returns the node owning key, "" if the ring is empty.
+func (r *Ring) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
@@ -0,0 +1,34 @@
+package balancer_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/buptmiao/microservice-app/client/balancer"
+)
+
+func TestRing(t *testing.T) {
+	r := balancer.NewRing(balancer.DefaultReplicas, "a:8001", "b:8001", "c:8001")
+	grown := balancer.NewRing(balancer.DefaultReplicas, "a:8001", "b:8001", "c:8001", "d:8001")
+
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := strconv.Itoa(i)
+		if r.Get(key) != r.Get(key) {
+			t.Fatal("the owner of a key must be stable")
+		}
+		if owner := grown.Get(key); owner != r.Get(key) {
+			if owner != "d:8001" {
+				t.Fatalf("key %s moved between old nodes", key)
+			}
+			moved++
+		}
+	}
+	// about a quarter of the keys go to the new node.
+	if moved < 1500 || moved > 3500 {
+		t.Fatalf("%d keys moved, expected about 2500", moved)
+	}
+	if balancer.NewRing(balancer.DefaultReplicas).Get("1") != "" {
+		t.Fatal("an empty ring owns nothing")
+	}
+}
@@ -0,0 +1,239 @@
+package balancer
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrNoEndpoints = errors.New("no endpoints available")
+
+// KeyFunc extracts the routing key of a request.
+type KeyFunc func(request interface{}) string
+
+// Router keeps one endpoint per discovered instance and places the instances
+// on a consistent hash Ring, so that every request can be routed to the
+// instance owning its key. With a Splitter, each version has its own Ring.
+type Router struct {
+	cache   *cache
+	split   *Splitter
+	logger  log.Logger
+	options options
+	// the router of the shadow instances, see Mirror.
+	shadow *Router
+
+	mu sync.RWMutex
+	// rings by version, "" holds every instance.
+	rings map[string]*Ring
+	owner map[string]*node
+}
+
+// NewRouter subscribes to the instancer and builds an endpoint for every
+// instance with the factory. The strategy and the zone of opts do not apply,
+// a key always goes to its owner.
+func NewRouter(instancer sd.Instancer, factory sd.Factory, logger log.Logger, opts ...Option) *Router {
+	return newRouter(instancer, factory, logger, newOptions(opts))
+}
+
+func newRouter(instancer sd.Instancer, factory sd.Factory, logger log.Logger, o options) *Router {
+	r := &Router{
+		split:   o.split,
+		logger:  logger,
+		options: o,
+		rings:   map[string]*Ring{"": NewRing(DefaultReplicas)},
+		owner:   make(map[string]*node),
+	}
+	r.cache = newCache(instancer, factory, o, logger, r.update)
+	if o.shadow != nil && o.mirror != "" {
+		r.shadow = newRouter(o.shadow.Instancer, factory, logger, shadowOptions(o))
+	}
+	return r
+}
+
+// update places the nodes which are not drained on new rings, by address so
+// that a change of weight does not move any key.
+func (r *Router) update(nodes []*node) {
+	addrs := map[string][]string{"": {}}
+	owner := make(map[string]*node, len(nodes))
+	for _, n := range withoutDrained(r.options.service, nodes) {
+		addrs[""] = append(addrs[""], n.Addr)
+		if n.Version != "" {
+			addrs[n.Version] = append(addrs[n.Version], n.Addr)
+		}
+		owner[n.Addr] = n
+	}
+	rings := make(map[string]*Ring, len(addrs))
+	for version, list := range addrs {
+		rings[version] = NewRing(DefaultReplicas, list...)
+	}
+
+	r.mu.Lock()
+	r.rings, r.owner = rings, owner
+	r.mu.Unlock()
+}
+
+// Owner returns the address of the instance owning key and its endpoint.
+func (r *Router) Owner(key string) (string, endpoint.Endpoint, error) {
+	return r.ownerIn("", key)
+}
+
+// ownerIn returns the owner of key among the instances of version, or
+// among all the instances if version has none left: the rings may have
+// changed since the version was picked.
+func (r *Router) ownerIn(version, key string) (string, endpoint.Endpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ring, ok := r.rings[version]
+	if !ok {
+		ring = r.rings[""]
+	}
+	addr := ring.Get(key)
+	if addr == "" {
+		return "", nil, ErrNoEndpoints
+	}
+	return addr, r.owner[addr].call, nil
+}
+
+func (r *Router) hasVersion(version string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.rings[version]
+	return ok
+}
+
+// Endpoints returns the endpoints of all the instances which are not
+// drained, healthy or not.
+func (r *Router) Endpoints() []endpoint.Endpoint {
+	nodes := withoutDrained(r.options.service, r.cache.snapshot())
+	res := make([]endpoint.Endpoint, 0, len(nodes))
+	for _, n := range nodes {
+		res = append(res, n.call)
+	}
+	return res
+}
+
+// Endpoint returns an endpoint forwarding each request to the owner of its
+// key, among the instances of the version picked by the Splitter if any. The
+// key stands for the user of the Split, whoever makes the request, so that
+// the requests of a key keep going to the same instance as long as the Split
+// does not change.
+func (r *Router) Endpoint(key KeyFunc) endpoint.Endpoint {
+	if r.shadow != nil {
+		return mirrored(r.endpoint(key), r.shadow.endpoint(key), r.logger, r.options)
+	}
+	return r.endpoint(key)
+}
+
+func (r *Router) endpoint(key KeyFunc) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		k, version := key(request), ""
+		if r.split != nil {
+			hint := HintFrom(ctx)
+			hint.User = k
+			version = r.split.version(WithHint(ctx, hint), k, r.hasVersion)
+		}
+		_, e, err := r.ownerIn(version, k)
+		if err != nil {
+			return nil, err
+		}
+		return e(ctx, request)
+	}
+}
+
+// Scatter sends the request to every instance concurrently and returns the
+// successful responses. It fails only if every instance fails.
+func (r *Router) Scatter(ctx context.Context, request interface{}) ([]interface{}, error) {
+	endpoints := r.Endpoints()
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	type result struct {
+		response interface{}
+		err      error
+	}
+	results := make(chan result, len(endpoints))
+	for _, e := range endpoints {
+		go func(e endpoint.Endpoint) {
+			response, err := e(ctx, request)
+			results <- result{response, err}
+		}(e)
+	}
+	var (
+		responses []interface{}
+		lastErr   error
+	)
+	for range endpoints {
+		res := <-results
+		if res.err != nil {
+			lastErr = res.err
+			continue
+		}
+		responses = append(responses, res.response)
+	}
+	if len(responses) == 0 {
+		return nil, lastErr
+	}
+	return responses, nil
+}
+
+// ScatterEndpoint returns an endpoint scattering each request and merging
+// the responses.
+func (r *Router) ScatterEndpoint(merge func(request interface{}, responses []interface{}) interface{}) endpoint.Endpoint {
+	if r.shadow != nil {
+		return mirrored(r.scatterEndpoint(merge), r.shadow.scatterEndpoint(merge), r.logger, r.options)
+	}
+	return r.scatterEndpoint(merge)
+}
+
+func (r *Router) scatterEndpoint(merge func(request interface{}, responses []interface{}) interface{}) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		responses, err := r.Scatter(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return merge(request, responses), nil
+	}
+}
+
+// Retry retries the requests of ep, an endpoint of r, as set by WithRetry.
+// The errors caused by the request, see requestFault, are returned at once.
+// Only the idempotent methods should be retried.
+func (r *Router) Retry(ep endpoint.Endpoint) endpoint.Endpoint {
+	retries := r.options.retries
+	retry := lb.RetryWithCallback(r.options.timeout, fixed(ep), func(n int, err error) (bool, error) {
+		return n < retries && !requestFault(err), nil
+	})
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := retry(ctx, request)
+		if e, ok := err.(lb.RetryError); ok {
+			// the callers look at the status of the last error.
+			err = e.Final
+		}
+		return response, err
+	}
+}
+
+// requestFault tells the errors caused by the request, e.g. an invalid
+// argument or a post rejected by moderation. They say nothing of the health
+// of the instance and retrying does not fix them.
+func requestFault(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied, codes.FailedPrecondition, codes.OutOfRange:
+		return true
+	}
+	return false
+}
+
+// fixed is the Balancer of a single endpoint.
+type fixed endpoint.Endpoint
+
+func (f fixed) Endpoint() (endpoint.Endpoint, error) {
+	return endpoint.Endpoint(f), nil
+}
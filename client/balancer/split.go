@@ -0,0 +1,208 @@
+package balancer
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"golang.org/x/net/context"
+)
+
+// Split divides the traffic of a service between the versions of its
+// instances, e.g. {"weights": {"1.0": 95, "1.1": 5}, "canary": "1.1"}.
+type Split struct {
+	// Weights is the percentage of the requests going to each version, they
+	// add up to 100. Without weights every instance gets traffic.
+	Weights map[string]int `json:"weights,omitempty"`
+	// Canary is the version of the requests flagged as canary.
+	Canary string `json:"canary,omitempty"`
+	// Users pins user ids to a version.
+	Users map[string]string `json:"users,omitempty"`
+}
+
+// Validate checks that the weights add up to 100.
+func (s Split) Validate() error {
+	if len(s.Weights) == 0 {
+		return nil
+	}
+	total := 0
+	for version, w := range s.Weights {
+		if w < 0 {
+			return fmt.Errorf("negative weight for version %q", version)
+		}
+		total += w
+	}
+	if total != 100 {
+		return fmt.Errorf("weights add up to %d, want 100", total)
+	}
+	return nil
+}
+
+// Splitter holds the Split of a service. It is safe for concurrent use and
+// the Split can be changed at any time.
+type Splitter struct {
+	current atomic.Value
+}
+
+// compiled is a Split with its versions sorted, so that a bucket always
+// falls in the same version.
+type compiled struct {
+	Split
+	versions []string
+}
+
+func NewSplitter() *Splitter {
+	s := &Splitter{}
+	s.current.Store(compiled{})
+	return s
+}
+
+// Get returns the current Split.
+func (s *Splitter) Get() Split {
+	return s.current.Load().(compiled).Split
+}
+
+// Set replaces the Split, the next requests use the new one.
+func (s *Splitter) Set(split Split) error {
+	if err := split.Validate(); err != nil {
+		return err
+	}
+	c := compiled{Split: split}
+	for version := range split.Weights {
+		c.versions = append(c.versions, version)
+	}
+	sort.Strings(c.versions)
+	s.current.Store(c)
+	return nil
+}
+
+// version returns the version a request goes to, "" for any. In order, the
+// version explicitly asked for, the canary version of a canary request, the
+// version pinned for the user, and the version weighted by the Split. The
+// requests of one user, or else of one key, stick to a version. A version
+// without instances is ignored.
+func (s *Splitter) version(ctx context.Context, key string, available func(version string) bool) string {
+	split := s.current.Load().(compiled)
+	hint := HintFrom(ctx)
+	for _, version := range []string{hint.Version, canary(hint, split), split.Users[hint.User]} {
+		if version != "" && available(version) {
+			return version
+		}
+	}
+	if len(split.versions) == 0 {
+		return ""
+	}
+
+	var bucket int
+	switch {
+	case hint.User != "":
+		bucket = int(crc32.ChecksumIEEE([]byte(hint.User)) % 100)
+	case key != "":
+		bucket = int(crc32.ChecksumIEEE([]byte(key)) % 100)
+	default:
+		bucket = rand.Intn(100)
+	}
+	for _, version := range split.versions {
+		if bucket -= split.Weights[version]; bucket < 0 {
+			if available(version) {
+				return version
+			}
+			break
+		}
+	}
+	return ""
+}
+
+func canary(hint Hint, split compiled) string {
+	if hint.Canary {
+		return split.Canary
+	}
+	return ""
+}
+
+// Hint carries the routing overrides of a request, see WithHint.
+type Hint struct {
+	// Canary sends the request to the canary version.
+	Canary bool
+	// Version sends the request to this version.
+	Version string
+	// User is the user making the request, for the pinned users and to
+	// stick to a version.
+	User string
+}
+
+type hintKey struct{}
+
+// WithHint attaches the routing overrides of a request to its context, the
+// clients with a Splitter honor them.
+func WithHint(ctx context.Context, hint Hint) context.Context {
+	return context.WithValue(ctx, hintKey{}, hint)
+}
+
+// HintFrom returns the routing overrides attached to ctx.
+func HintFrom(ctx context.Context) Hint {
+	hint, _ := ctx.Value(hintKey{}).(Hint)
+	return hint
+}
+
+// versioned balances the requests across the instances of the version picked
+// by the Splitter, with one balancer per version built on first use.
+type versioned struct {
+	instancer sd.Instancer
+	factory   sd.Factory
+	key       KeyFunc
+	logger    log.Logger
+	options   options
+
+	all         *cache
+	allEndpoint endpoint.Endpoint
+
+	mu        sync.Mutex
+	byVersion map[string]endpoint.Endpoint
+}
+
+func newVersioned(instancer sd.Instancer, factory sd.Factory, key KeyFunc, logger log.Logger, o options) endpoint.Endpoint {
+	v := &versioned{
+		instancer: instancer,
+		factory:   factory,
+		key:       key,
+		logger:    logger,
+		options:   o,
+		all:       newCache(instancer, factory, o, logger, nil),
+		byVersion: make(map[string]endpoint.Endpoint),
+	}
+	v.allEndpoint = balance(v.all, o)
+	return v.endpoint
+}
+
+func (v *versioned) endpoint(ctx context.Context, request interface{}) (interface{}, error) {
+	key := ""
+	if v.key != nil {
+		key = v.key(request)
+	}
+	version := v.options.split.version(ctx, key, v.all.hasVersion)
+	if version == "" {
+		return v.allEndpoint(ctx, request)
+	}
+	return v.forVersion(version)(ctx, request)
+}
+
+func (v *versioned) forVersion(version string) endpoint.Endpoint {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if e, ok := v.byVersion[version]; ok {
+		return e
+	}
+	o := v.options
+	o.filters = append(append([]discovery.Filter(nil), o.filters...), discovery.Version(version))
+	e := balance(newCache(v.instancer, v.factory, o, v.logger, nil), o)
+	v.byVersion[version] = e
+	return e
+}
@@ -0,0 +1,219 @@
+package balancer
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+)
+
+// Names of the strategies, as accepted by Parse.
+const (
+	StrategyRoundRobin       = "roundrobin"
+	StrategyConsistentHash   = "hash"
+	StrategyLeastOutstanding = "p2c"
+	StrategyWeighted         = "weighted"
+)
+
+type options struct {
+	strategy string
+	retries  int
+	timeout  time.Duration
+	filters  []discovery.Filter
+	service  string
+	zone     string
+	split    *Splitter
+	shadow   *Shadow
+	mirror   string
+	hedge    *Hedge
+}
+
+func newOptions(opts []Option) options {
+	o := options{strategy: StrategyRoundRobin, retries: 3, timeout: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Option configures the endpoints built by NewEndpoint.
+type Option func(*options)
+
+// RoundRobin sends the requests to the instances in turn. It is the default.
+func RoundRobin() Option {
+	return func(o *options) { o.strategy = StrategyRoundRobin }
+}
+
+// ConsistentHash sends the requests with the same key to the same instance,
+// so that its caches stay warm. Requests are not retried, as they would go to
+// the same instance again.
+func ConsistentHash() Option {
+	return func(o *options) { o.strategy = StrategyConsistentHash }
+}
+
+// LeastOutstanding picks two instances at random and sends the request to the
+// one with fewer requests in flight.
+func LeastOutstanding() Option {
+	return func(o *options) { o.strategy = StrategyLeastOutstanding }
+}
+
+// Weighted sends the requests to the instances in proportion to the weights
+// they registered with.
+func Weighted() Option {
+	return func(o *options) { o.strategy = StrategyWeighted }
+}
+
+// WithRetry sets the attempts and the overall timeout of each request, the
+// default is 3 attempts within a second. Only the Mirror methods make more
+// than one attempt.
+func WithRetry(max int, timeout time.Duration) Option {
+	return func(o *options) { o.retries, o.timeout = max, timeout }
+}
+
+// WithFilter only uses the instances accepted by every filter, e.g.
+// discovery.Version("1.2.0").
+func WithFilter(filters ...discovery.Filter) Option {
+	return func(o *options) { o.filters = append(o.filters, filters...) }
+}
+
+// WithService names the service of the client in the metrics.
+func WithService(name string) Option {
+	return func(o *options) { o.service = name }
+}
+
+// PreferZone keeps the requests in the zone of the caller while its instances
+// are healthy and not overloaded, see zone.go. It does not apply to
+// ConsistentHash.
+func PreferZone(zone string) Option {
+	return func(o *options) { o.zone = zone }
+}
+
+// WithSplit divides the requests between the versions of the service as the
+// Splitter says.
+func WithSplit(s *Splitter) Option {
+	return func(o *options) { o.split = s }
+}
+
+// Parse returns the Option selecting the strategy called name, for flags.
+func Parse(name string) (Option, error) {
+	switch name {
+	case StrategyRoundRobin, "":
+		return RoundRobin(), nil
+	case StrategyConsistentHash:
+		return ConsistentHash(), nil
+	case StrategyLeastOutstanding:
+		return LeastOutstanding(), nil
+	case StrategyWeighted:
+		return Weighted(), nil
+	}
+	return nil, fmt.Errorf("unknown balancer %q", name)
+}
+
+// NewEndpoint builds the endpoint of one method of a client, spreading the
+// requests across the instances of the service as selected by opts. key
+// extracts the affinity key of a request for ConsistentHash.
+func NewEndpoint(instancer sd.Instancer, factory sd.Factory, key KeyFunc, logger log.Logger, opts ...Option) endpoint.Endpoint {
+	o := newOptions(opts)
+	if o.shadow != nil && o.mirror != "" {
+		primary := newEndpoint(instancer, factory, key, logger, o)
+		shadow := newEndpoint(o.shadow.Instancer, factory, key, logger, shadowOptions(o))
+		return mirrored(primary, shadow, logger, o)
+	}
+	return newEndpoint(instancer, factory, key, logger, o)
+}
+
+func newEndpoint(instancer sd.Instancer, factory sd.Factory, key KeyFunc, logger log.Logger, o options) endpoint.Endpoint {
+	switch {
+	case o.strategy == StrategyConsistentHash:
+		return newRouter(instancer, factory, logger, o).Endpoint(key)
+	case o.split != nil:
+		return newVersioned(instancer, factory, key, logger, o)
+	}
+	return balance(newCache(instancer, factory, o, logger, nil), o)
+}
+
+// balance spreads the requests across the nodes of the cache with the
+// strategy of o. The failures of the Mirror methods are retried, except
+// the request faults; the other methods may write, so they are sent once.
+func balance(c *cache, o options) endpoint.Endpoint {
+	var balancer lb.Balancer
+	switch o.strategy {
+	case StrategyLeastOutstanding:
+		balancer = &leastOutstanding{cache: c}
+	case StrategyWeighted:
+		balancer = &weighted{cache: c}
+	default:
+		balancer = lb.NewRoundRobin(c)
+	}
+	retries := o.retries
+	if o.mirror == "" {
+		retries = 1
+	}
+	ep := lb.RetryWithCallback(o.timeout, balancer, func(n int, err error) (bool, error) {
+		return n < retries && !requestFault(err), nil
+	})
+	if o.hedge != nil && o.mirror != "" {
+		ep = hedged(ep, c, o)
+	}
+	return ep
+}
+
+// leastOutstanding implements the power of two choices: it is close to
+// picking the least loaded instance, without herding every client on it.
+type leastOutstanding struct {
+	cache *cache
+}
+
+func (b *leastOutstanding) Endpoint() (endpoint.Endpoint, error) {
+	nodes := b.cache.candidates()
+	switch len(nodes) {
+	case 0:
+		return nil, ErrNoEndpoints
+	case 1:
+		return nodes[0].call, nil
+	}
+	i := rand.Intn(len(nodes))
+	j := rand.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+	n := nodes[i]
+	if atomic.LoadInt64(&nodes[j].inflight) < atomic.LoadInt64(&n.inflight) {
+		n = nodes[j]
+	}
+	return n.call, nil
+}
+
+// weighted is the smooth weighted round robin of nginx: an instance of weight
+// 3 next to one of weight 1 gets a, a, b, a rather than a, a, a, b.
+type weighted struct {
+	cache *cache
+	mu    sync.Mutex
+}
+
+func (b *weighted) Endpoint() (endpoint.Endpoint, error) {
+	nodes := b.cache.candidates()
+	if len(nodes) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total := 0
+	var best *node
+	for _, n := range nodes {
+		n.current += n.Weight
+		total += n.Weight
+		if best == nil || n.current > best.current {
+			best = n
+		}
+	}
+	best.current -= total
+	return best.call, nil
+}
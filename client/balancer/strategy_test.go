@@ -0,0 +1,543 @@
+package balancer_test
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/sony/gobreaker"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// echoFactory builds endpoints answering with the address they were built for.
+func echoFactory(addr string) (endpoint.Endpoint, io.Closer, error) {
+	return func(context.Context, interface{}) (interface{}, error) {
+		return addr, nil
+	}, nil, nil
+}
+
+func TestStrategies(t *testing.T) {
+	instancer := sd.FixedInstancer{
+		discovery.Instance{Addr: "a:8001", Version: "2", Weight: 3}.Encode(),
+		discovery.Instance{Addr: "b:8001", Version: "2", Weight: 1}.Encode(),
+		discovery.Instance{Addr: "c:8001", Version: "1", Weight: 1}.Encode(),
+	}
+	v2 := balancer.WithFilter(discovery.Version("2"))
+	key := func(request interface{}) string { return request.(string) }
+	ctx := context.Background()
+
+	weighted := balancer.NewEndpoint(instancer, echoFactory, key, log.NewNopLogger(), balancer.Weighted(), v2)
+	hash := balancer.NewEndpoint(instancer, echoFactory, key, log.NewNopLogger(), balancer.ConsistentHash(), v2)
+	p2c := balancer.NewEndpoint(instancer, echoFactory, key, log.NewNopLogger(), balancer.LeastOutstanding(), v2)
+
+	count := map[interface{}]int{}
+	for i := 0; i < 400; i++ {
+		addr, err := weighted(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		count[addr]++
+	}
+	if count["a:8001"] != 300 || count["b:8001"] != 100 {
+		t.Fatalf("weighted balancer sent %v", count)
+	}
+
+	for _, k := range []string{"1", "2", "3", "42"} {
+		first, err := hash(ctx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			if addr, _ := hash(ctx, k); addr != first {
+				t.Fatalf("key %s went to %v then %v", k, first, addr)
+			}
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		addr, err := p2c(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr == "c:8001" {
+			t.Fatal("the instance of version 1 must be filtered out")
+		}
+	}
+
+	// with two instances p2c compares both, so the requests avoid the one
+	// busy with a request.
+	started, release := make(chan string, 1), make(chan struct{})
+	blocking := func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		return func(_ context.Context, request interface{}) (interface{}, error) {
+			if request == "block" {
+				started <- addr
+				<-release
+			}
+			return addr, nil
+		}, nil, nil
+	}
+	p2c = balancer.NewEndpoint(instancer, blocking, key, log.NewNopLogger(), balancer.LeastOutstanding(), v2)
+	go p2c(ctx, "block")
+	busy := <-started
+	defer close(release)
+	for i := 0; i < 20; i++ {
+		if addr, _ := p2c(ctx, ""); addr == busy {
+			t.Fatalf("p2c sent request %d to %s, which has one in flight", i, busy)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int32
+	fail := func(code codes.Code) sd.Factory {
+		return func(string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				atomic.AddInt32(&attempts, 1)
+				return nil, status.Error(code, code.String())
+			}, nil, nil
+		}
+	}
+	instancer := sd.FixedInstancer{"a:8001", "b:8001"}
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name    string
+		factory sd.Factory
+		opts    []balancer.Option
+		want    int32
+	}{
+		{"write", fail(codes.Unavailable), nil, 1},
+		{"read", fail(codes.Unavailable), []balancer.Option{balancer.Mirror("Get")}, 3},
+		{"request fault", fail(codes.PermissionDenied), []balancer.Option{balancer.Mirror("Get")}, 1},
+	} {
+		opts := append([]balancer.Option{balancer.WithService("retry-" + tc.name)}, tc.opts...)
+		e := balancer.NewEndpoint(instancer, tc.factory, nil, log.NewNopLogger(), opts...)
+		atomic.StoreInt32(&attempts, 0)
+		if _, err := e(ctx, nil); err == nil {
+			t.Fatalf("%s: got no error", tc.name)
+		}
+		if n := atomic.LoadInt32(&attempts); n != tc.want {
+			t.Errorf("%s: made %d attempts, want %d", tc.name, n, tc.want)
+		}
+	}
+}
+
+func TestPreferZone(t *testing.T) {
+	instancer := sd.FixedInstancer{
+		discovery.Instance{Addr: "a:8001", Zone: "east"}.Encode(),
+		discovery.Instance{Addr: "b:8001", Zone: "west"}.Encode(),
+	}
+	down := int32(0)
+	factory := func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		return func(context.Context, interface{}) (interface{}, error) {
+			if addr == "a:8001" && atomic.LoadInt32(&down) == 1 {
+				return nil, status.Error(codes.Unavailable, "down")
+			}
+			return addr, nil
+		}, nil, nil
+	}
+	e := balancer.NewEndpoint(instancer, factory, nil, log.NewNopLogger(),
+		balancer.PreferZone("east"), balancer.WithRetry(1, time.Second))
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		if addr, err := e(ctx, nil); err != nil || addr != "a:8001" {
+			t.Fatalf("got %v, %v, want the local instance", addr, err)
+		}
+	}
+
+	// the local instance goes down: it is ejected and the traffic fails over.
+	atomic.StoreInt32(&down, 1)
+	for i := 0; i < 5; i++ {
+		e(ctx, nil)
+	}
+	for i := 0; i < 20; i++ {
+		if addr, err := e(ctx, nil); err != nil || addr != "b:8001" {
+			t.Fatalf("got %v, %v, want the remote instance", addr, err)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	instancer := sd.FixedInstancer{
+		discovery.Instance{Addr: "stable:8001", Version: "1.0"}.Encode(),
+		discovery.Instance{Addr: "canary:8001", Version: "1.1"}.Encode(),
+	}
+	splitter := balancer.NewSplitter()
+	if err := splitter.Set(balancer.Split{Weights: map[string]int{"1.0": 60, "1.1": 60}}); err == nil {
+		t.Fatal("weights adding up to 120 must be rejected")
+	}
+	if err := splitter.Set(balancer.Split{Weights: map[string]int{"1.0": 100, "1.1": 0}, Canary: "1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	e := balancer.NewEndpoint(instancer, echoFactory, nil, log.NewNopLogger(), balancer.WithSplit(splitter))
+	ctx := context.Background()
+	canary := balancer.WithHint(ctx, balancer.Hint{Canary: true})
+	for i := 0; i < 10; i++ {
+		if addr, _ := e(ctx, nil); addr != "stable:8001" {
+			t.Fatalf("got %v, want the stable version", addr)
+		}
+		if addr, _ := e(canary, nil); addr != "canary:8001" {
+			t.Fatalf("got %v, want the canary version", addr)
+		}
+	}
+}
+
+// pushInstancer is an sd.Instancer sending the events pushed to it.
+type pushInstancer struct {
+	mu sync.Mutex
+	ch chan<- sd.Event
+}
+
+func (p *pushInstancer) Register(ch chan<- sd.Event) {
+	p.mu.Lock()
+	p.ch = ch
+	p.mu.Unlock()
+}
+
+func (p *pushInstancer) Deregister(chan<- sd.Event) {}
+
+func (p *pushInstancer) Stop() {}
+
+// push sends the instances, and sends them again so that it returns once the
+// first event is handled.
+func (p *pushInstancer) push(instances ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ch <- sd.Event{Instances: instances}
+	p.ch <- sd.Event{Instances: instances}
+}
+
+func TestRouterVersionGone(t *testing.T) {
+	stable := discovery.Instance{Addr: "stable:8001", Version: "1.0"}.Encode()
+	canaryInstance := discovery.Instance{Addr: "canary:8001", Version: "1.1"}.Encode()
+	instancer := &pushInstancer{}
+	splitter := balancer.NewSplitter()
+	if err := splitter.Set(balancer.Split{Weights: map[string]int{"1.0": 100}, Canary: "1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	key := func(request interface{}) string { return request.(string) }
+	router := balancer.NewRouter(instancer, echoFactory, log.NewNopLogger(), balancer.WithService("gone"), balancer.WithSplit(splitter))
+	e := router.Endpoint(key)
+	canary := balancer.WithHint(context.Background(), balancer.Hint{Canary: true})
+
+	instancer.push(stable, canaryInstance)
+	if addr, err := e(canary, "1"); err != nil || addr != "canary:8001" {
+		t.Fatalf("got %v, %v, want the canary version", addr, err)
+	}
+	// the canary comes and goes while the requests keep coming: a version
+	// picked before it left is routed to the other instances.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := e(canary, strconv.Itoa(i)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		instancer.push(stable)
+		instancer.push(stable, canaryInstance)
+	}
+	instancer.push(stable)
+	close(stop)
+	wg.Wait()
+	if addr, err := e(canary, "1"); err != nil || addr != "stable:8001" {
+		t.Fatalf("got %v, %v, want the stable version once the canary is gone", addr, err)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	mirrored := make(chan interface{}, 10)
+	factory := func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		return func(_ context.Context, request interface{}) (interface{}, error) {
+			if addr == "shadow:8001" {
+				mirrored <- request
+			}
+			return addr, nil
+		}, nil, nil
+	}
+	shadow := balancer.Shadow{Instancer: sd.FixedInstancer{"shadow:8001"}, Sample: 1}
+	primary := sd.FixedInstancer{"primary:8001"}
+
+	read := balancer.NewEndpoint(primary, factory, nil, log.NewNopLogger(), balancer.WithShadow(shadow), balancer.Mirror("Get"))
+	write := balancer.NewEndpoint(primary, factory, nil, log.NewNopLogger(), balancer.WithShadow(shadow))
+	ctx := context.Background()
+
+	if addr, _ := read(ctx, "read"); addr != "primary:8001" {
+		t.Fatalf("got %v, the shadow response must be discarded", addr)
+	}
+	select {
+	case request := <-mirrored:
+		if request != "read" {
+			t.Fatalf("mirrored %v", request)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the read was not mirrored")
+	}
+
+	write(ctx, "write")
+	select {
+	case request := <-mirrored:
+		t.Fatalf("%v must not be mirrored", request)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHedge(t *testing.T) {
+	slow := int32(0)
+	factory := func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		return func(ctx context.Context, _ interface{}) (interface{}, error) {
+			if addr == "a:8001" && atomic.LoadInt32(&slow) == 1 {
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+			return addr, nil
+		}, nil, nil
+	}
+	instancer := sd.FixedInstancer{"a:8001", "b:8001"}
+	ctx := context.Background()
+	check := func(read endpoint.Endpoint) {
+		for i := 0; i < 10; i++ {
+			begin := time.Now()
+			addr, err := read(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if addr != "b:8001" || time.Since(begin) > 500*time.Millisecond {
+				t.Fatalf("got %v after %v, the hedge to b must answer first", addr, time.Since(begin))
+			}
+		}
+	}
+
+	// a budget of 1 lets every request be hedged, so none is throttled.
+	atomic.StoreInt32(&slow, 1)
+	fixed := balancer.WithHedge(balancer.Hedge{Budget: 1, Delay: 20 * time.Millisecond})
+	check(balancer.NewEndpoint(instancer, factory, nil, log.NewNopLogger(), balancer.WithService("fixed-hedge"), fixed, balancer.Mirror("Get")))
+
+	// the delay is learnt from the latency of the method first. Fast
+	// requests may be hedged meanwhile, the budget covers them too.
+	atomic.StoreInt32(&slow, 0)
+	learnt := balancer.WithHedge(balancer.Hedge{Percentile: 0.9, Budget: 1})
+	read := balancer.NewEndpoint(instancer, factory, nil, log.NewNopLogger(), balancer.WithService("learnt-hedge"), learnt, balancer.Mirror("Get"))
+	for i := 0; i < 100; i++ {
+		if _, err := read(ctx, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	atomic.StoreInt32(&slow, 1)
+	check(read)
+}
+
+func TestDrain(t *testing.T) {
+	instancer := sd.FixedInstancer{
+		discovery.Instance{Addr: "a:8001"}.Encode(),
+		discovery.Instance{Addr: "b:8001"}.Encode(),
+	}
+	key := func(request interface{}) string { return request.(string) }
+	service := balancer.WithService("drained")
+	rr := balancer.NewEndpoint(instancer, echoFactory, key, log.NewNopLogger(), service)
+	hash := balancer.NewEndpoint(instancer, echoFactory, key, log.NewNopLogger(), service, balancer.ConsistentHash())
+	ctx := context.Background()
+
+	if balancer.Drain("drained", "c:8001", true) {
+		t.Fatal("unknown instance drained")
+	}
+	if !balancer.Drain("drained", "a:8001", true) {
+		t.Fatal("a:8001 not drained")
+	}
+	for i := 0; i < 10; i++ {
+		for _, e := range []endpoint.Endpoint{rr, hash} {
+			if addr, err := e(ctx, strconv.Itoa(i)); err != nil || addr != "b:8001" {
+				t.Fatalf("request %d went to %v, %v", i, addr, err)
+			}
+		}
+	}
+	if s := balancer.Clients()["drained"]; len(s.Instances) != 2 || !s.Instances[0].Drained {
+		t.Fatalf("state %+v", s)
+	}
+
+	balancer.Drain("drained", "a:8001", false)
+	count := map[interface{}]int{}
+	for i := 0; i < 10; i++ {
+		addr, _ := rr(ctx, "")
+		count[addr]++
+	}
+	if count["a:8001"] != 5 {
+		t.Fatalf("a:8001 is back with %v", count)
+	}
+}
+
+func TestDrainDuringUpdates(t *testing.T) {
+	a := discovery.Instance{Addr: "a:8001"}.Encode()
+	b := discovery.Instance{Addr: "b:8001"}.Encode()
+	instancer := &pushInstancer{}
+	key := func(request interface{}) string { return request.(string) }
+	hash := balancer.NewEndpoint(instancer, echoFactory, key, log.NewNopLogger(), balancer.WithService("reordered"), balancer.ConsistentHash())
+	ctx := context.Background()
+
+	// the refreshes after the drains race with the instancer, the last
+	// event must win.
+	instancer.push(a, b)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			balancer.Drain("reordered", "b:8001", false)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		instancer.push(a, b)
+		instancer.push(b)
+	}
+	<-done
+	for i := 0; i < 20; i++ {
+		if addr, err := hash(ctx, strconv.Itoa(i)); err != nil || addr != "b:8001" {
+			t.Fatalf("key %d went to %v, %v after a:8001 left", i, addr, err)
+		}
+	}
+}
+
+func TestForceOpen(t *testing.T) {
+	e := balancer.Breaker("forced", "a:8001", "Get")(func(context.Context, interface{}) (interface{}, error) {
+		return "a:8001", nil
+	})
+	ctx := context.Background()
+	if matched := balancer.ForceOpen("forced", "a:8001", "", true); len(matched) != 1 || matched[0].State != "open" {
+		t.Fatalf("matched %+v", matched)
+	}
+	if _, err := e(ctx, nil); err != gobreaker.ErrOpenState {
+		t.Fatalf("forced open breaker returned %v", err)
+	}
+	balancer.ForceOpen("forced", "a:8001", "Get", false)
+	if _, err := e(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBreakerRequestFaults(t *testing.T) {
+	var fail error
+	// a distinct instance per run, the breakers outlive the test.
+	instance := "a:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	e := balancer.Breaker("faults", instance, "Create")(func(context.Context, interface{}) (interface{}, error) {
+		return nil, fail
+	})
+	ctx := context.Background()
+	call := func(code codes.Code, n int) {
+		fail = status.Error(code, code.String())
+		for i := 0; i < n; i++ {
+			if _, err := e(ctx, nil); err != fail {
+				t.Fatalf("%v request %d returned %v", code, i, err)
+			}
+		}
+	}
+
+	// the request faults are returned as they are, and count as successes
+	// which reset the consecutive failures.
+	call(codes.PermissionDenied, 10)
+	call(codes.Unavailable, 5)
+	call(codes.InvalidArgument, 1)
+	call(codes.Unavailable, 5)
+	call(codes.NotFound, 1)
+	if s := breakerState(t, instance); s != "closed" {
+		t.Fatalf("breaker is %s after request faults, want closed", s)
+	}
+	call(codes.Unavailable, 6)
+	if _, err := e(ctx, nil); err != gobreaker.ErrOpenState {
+		t.Fatalf("breaker of a failing instance returned %v", err)
+	}
+}
+
+// breakerState returns the state of the breaker of instance, as shown by the
+// admin endpoints.
+func breakerState(t *testing.T, instance string) string {
+	for _, b := range balancer.Breakers() {
+		if b.Instance == instance {
+			return b.State
+		}
+	}
+	t.Fatalf("no breaker for %s", instance)
+	return ""
+}
+
+// syncBuffer is a bytes.Buffer safe for the background shadow logs.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestShadowDiffLog(t *testing.T) {
+	type account struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+		Friends  []int  `json:"friends"`
+	}
+	mirrored := make(chan struct{}, 10)
+	factory := func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		return func(context.Context, interface{}) (interface{}, error) {
+			if addr == "shadow:8001" {
+				defer func() { mirrored <- struct{}{} }()
+				return account{Name: "bob", Password: "hunter2", Friends: []int{1, 3}}, nil
+			}
+			return account{Name: "alice", Password: "swordfish", Friends: []int{1, 2}}, nil
+		}, nil, nil
+	}
+	shadow := balancer.Shadow{Instancer: sd.FixedInstancer{"shadow:8001"}, Sample: 1}
+	var logs syncBuffer
+	read := balancer.NewEndpoint(sd.FixedInstancer{"primary:8001"}, factory, nil, log.NewLogfmtLogger(&logs), balancer.WithShadow(shadow), balancer.Mirror("Get"))
+	for i := 0; i < 5; i++ {
+		read(context.Background(), nil)
+		<-mirrored
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	out := logs.String()
+	if n := strings.Count(out, "diff="); n != 1 {
+		t.Fatalf("logged %d mismatches, want 1 per second:\n%s", n, out)
+	}
+	if !strings.Contains(out, "fields friends[1],name,password differ") {
+		t.Errorf("differing fields not logged: %s", out)
+	}
+	for _, value := range []string{"alice", "bob", "hunter2", "swordfish"} {
+		if strings.Contains(out, value) {
+			t.Errorf("payload value %q logged: %s", value, out)
+		}
+	}
+}
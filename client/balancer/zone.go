@@ -0,0 +1,137 @@
+package balancer
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// an instance failing ejectAfter requests in a row is left out for ejectFor.
+	ejectAfter = 5
+	ejectFor   = 10 * time.Second
+
+	// overprovisioning lets a zone with 1/overprovisioning of its instances
+	// healthy still serve all of its traffic, as in Envoy.
+	overprovisioning = 1.4
+	// overloadInflight is the mean number of requests in flight per local
+	// instance beyond which the other zones share the traffic.
+	overloadInflight = 100
+)
+
+// Spillover reasons.
+const (
+	spillFailover   = "failover"
+	spillUnhealthy  = "unhealthy"
+	spillOverloaded = "overloaded"
+)
+
+var (
+	zoneRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "client",
+		Subsystem: "zone",
+		Name:      "requests_total",
+		Help:      "Number of requests sent to each zone, local or not to the caller.",
+	}, []string{"service", "zone", "local"})
+	zoneSpillover metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "client",
+		Subsystem: "zone",
+		Name:      "spillover_total",
+		Help:      "Number of requests allowed out of the zone of the caller, by reason.",
+	}, []string{"service", "reason"})
+	versionDuration metrics.Histogram = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "client",
+		Subsystem: "version",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the requests to each version of a service, to compare a canary with the stable version.",
+		Buckets:   util.LatencyBuckets,
+	}, []string{"service", "version", "success"})
+)
+
+// unavailable tells the errors of the instance itself, as opposed to the
+// errors of the requests, which prove the instance alive.
+func unavailable(err error) bool {
+	if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// observe ejects the node after ejectAfter consecutive failures.
+func (n *node) observe(err error) {
+	if err == nil || !unavailable(err) {
+		atomic.StoreInt64(&n.failures, 0)
+		return
+	}
+	if atomic.AddInt64(&n.failures, 1) >= ejectAfter {
+		atomic.StoreInt64(&n.failures, 0)
+		atomic.StoreInt64(&n.ejectedUntil, time.Now().Add(ejectFor).UnixNano())
+	}
+}
+
+func (n *node) healthy(now int64) bool {
+	return now >= atomic.LoadInt64(&n.ejectedUntil)
+}
+
+// candidates returns the nodes the next request may go to. Drained nodes are
+// left out, and so are ejected nodes, unless they all are. With a preferred zone, the healthy local
+// nodes are returned and the other zones are added when:
+//   - no local node is healthy,
+//   - too few are healthy: a share of the requests proportional to the
+//     missing capacity goes to the other zones,
+//   - the local nodes are overloaded.
+func (c *cache) candidates() []*node {
+	nodes := withoutDrained(c.options.service, c.snapshot())
+	now := time.Now().UnixNano()
+	var local, remote []*node
+	total := 0
+	for _, n := range nodes {
+		if n.local {
+			total++
+		}
+		switch {
+		case !n.healthy(now):
+		case n.local:
+			local = append(local, n)
+		default:
+			remote = append(remote, n)
+		}
+	}
+	switch {
+	case len(local)+len(remote) == 0:
+		// panic mode: better try the ejected nodes than nothing.
+		return nodes
+	case c.options.zone == "" || len(remote) == 0:
+		return append(local, remote...)
+	case len(local) == 0:
+		zoneSpillover.With("service", c.options.service, "reason", spillFailover).Add(1)
+		return remote
+	case rand.Float64() >= float64(len(local))/float64(total)*overprovisioning:
+		zoneSpillover.With("service", c.options.service, "reason", spillUnhealthy).Add(1)
+		return remote
+	case meanInflight(local) >= overloadInflight && meanInflight(remote) < meanInflight(local):
+		zoneSpillover.With("service", c.options.service, "reason", spillOverloaded).Add(1)
+		return append(local, remote...)
+	}
+	return local
+}
+
+func meanInflight(nodes []*node) float64 {
+	sum := int64(0)
+	for _, n := range nodes {
+		sum += atomic.LoadInt64(&n.inflight)
+	}
+	return float64(sum) / float64(len(nodes))
+}
@@ -2,37 +2,43 @@ package feed
 
 import (
 	"io"
+	"sort"
+	"strconv"
 	"time"
 
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/buptmiao/microservice-app/mtls"
 	"github.com/buptmiao/microservice-app/proto/feed"
 	"github.com/buptmiao/microservice-app/util"
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/sd"
-	"github.com/go-kit/kit/sd/etcd"
-	"github.com/go-kit/kit/sd/lb"
 	"github.com/go-kit/kit/tracing/opentracing"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	stdopentracing "github.com/opentracing/opentracing-go"
-	"github.com/sony/gobreaker"
 	"golang.org/x/net/context"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 )
 
 var feedCli feed.FeedClient
-var feedInstancer *etcd.Instancer
+var feedInstancer sd.Instancer
 
 func Init(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger log.Logger) {
 	feedCli = NewFeedClient(conn, tracer, logger)
 }
 
-func InitWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer, logger log.Logger) {
-	feedCli = NewFeedClientWithSD(sdClient, tracer, logger)
-	feedInstancer, _ = etcd.NewInstancer(sdClient, "feedSD", logger)
-
+// InitWithSD watches the feed instances in the registry.
+func InitWithSD(registry discovery.Registry, tracer stdopentracing.Tracer, logger log.Logger, opts ...balancer.Option) error {
+	var err error
+	if feedInstancer, err = registry.Instancer("feed"); err != nil {
+		return err
+	}
+	feedCli = NewFeedClientWithSD(feedInstancer, tracer, logger, opts...)
+	return nil
 }
 
 func GetClient() feed.FeedClient {
@@ -43,8 +49,19 @@ func GetClient() feed.FeedClient {
 }
 
 type FeedClient struct {
-	GetFeedsEndpoint   endpoint.Endpoint
-	CreateFeedEndpoint endpoint.Endpoint
+	GetFeedsEndpoint        endpoint.Endpoint
+	CreateFeedEndpoint      endpoint.Endpoint
+	SearchFeedsEndpoint     endpoint.Endpoint
+	GetFeedsByTopicEndpoint endpoint.Endpoint
+	UpdateFeedEndpoint      endpoint.Endpoint
+	DeleteFeedEndpoint      endpoint.Endpoint
+	GetFeedHistoryEndpoint  endpoint.Endpoint
+	LikeFeedEndpoint        endpoint.Endpoint
+	UnlikeFeedEndpoint      endpoint.Endpoint
+	CreateCommentEndpoint   endpoint.Endpoint
+	GetCommentsEndpoint     endpoint.Endpoint
+	ListHeldFeedsEndpoint   endpoint.Endpoint
+	ReviewFeedEndpoint      endpoint.Endpoint
 }
 
 func (f *FeedClient) GetFeeds(ctx context.Context, in *feed.GetFeedsRequest, opts ...grpc.CallOption) (*feed.GetFeedsResponse, error) {
@@ -63,6 +80,94 @@ func (f *FeedClient) CreateFeed(ctx context.Context, in *feed.FeedRecord, opts .
 	return resp.(*feed.OkResponse), nil
 }
 
+func (f *FeedClient) SearchFeeds(ctx context.Context, in *feed.SearchFeedsRequest, opts ...grpc.CallOption) (*feed.GetFeedsResponse, error) {
+	resp, err := f.SearchFeedsEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.GetFeedsResponse), nil
+}
+
+func (f *FeedClient) GetFeedsByTopic(ctx context.Context, in *feed.GetFeedsByTopicRequest, opts ...grpc.CallOption) (*feed.GetFeedsResponse, error) {
+	resp, err := f.GetFeedsByTopicEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.GetFeedsResponse), nil
+}
+
+func (f *FeedClient) UpdateFeed(ctx context.Context, in *feed.UpdateFeedRequest, opts ...grpc.CallOption) (*feed.FeedRecord, error) {
+	resp, err := f.UpdateFeedEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.FeedRecord), nil
+}
+
+func (f *FeedClient) DeleteFeed(ctx context.Context, in *feed.DeleteFeedRequest, opts ...grpc.CallOption) (*feed.OkResponse, error) {
+	resp, err := f.DeleteFeedEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.OkResponse), nil
+}
+
+func (f *FeedClient) GetFeedHistory(ctx context.Context, in *feed.GetFeedHistoryRequest, opts ...grpc.CallOption) (*feed.GetFeedHistoryResponse, error) {
+	resp, err := f.GetFeedHistoryEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.GetFeedHistoryResponse), nil
+}
+
+func (f *FeedClient) LikeFeed(ctx context.Context, in *feed.LikeRequest, opts ...grpc.CallOption) (*feed.LikeResponse, error) {
+	resp, err := f.LikeFeedEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.LikeResponse), nil
+}
+
+func (f *FeedClient) UnlikeFeed(ctx context.Context, in *feed.LikeRequest, opts ...grpc.CallOption) (*feed.LikeResponse, error) {
+	resp, err := f.UnlikeFeedEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.LikeResponse), nil
+}
+
+func (f *FeedClient) CreateComment(ctx context.Context, in *feed.Comment, opts ...grpc.CallOption) (*feed.Comment, error) {
+	resp, err := f.CreateCommentEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.Comment), nil
+}
+
+func (f *FeedClient) GetComments(ctx context.Context, in *feed.GetCommentsRequest, opts ...grpc.CallOption) (*feed.GetCommentsResponse, error) {
+	resp, err := f.GetCommentsEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.GetCommentsResponse), nil
+}
+
+func (f *FeedClient) ListHeldFeeds(ctx context.Context, in *feed.ListHeldFeedsRequest, opts ...grpc.CallOption) (*feed.ListHeldFeedsResponse, error) {
+	resp, err := f.ListHeldFeedsEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.ListHeldFeedsResponse), nil
+}
+
+func (f *FeedClient) ReviewFeed(ctx context.Context, in *feed.ReviewFeedRequest, opts ...grpc.CallOption) (*feed.OkResponse, error) {
+	resp, err := f.ReviewFeedEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*feed.OkResponse), nil
+}
+
 func NewFeedClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger log.Logger) feed.FeedClient {
 
 	limiter := ratelimit.NewDelayingLimiter(rate.NewLimiter(rate.Every(time.Second), 1000))
@@ -76,14 +181,11 @@ func NewFeedClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger l
 			util.DummyEncode,
 			util.DummyDecode,
 			feed.GetFeedsResponse{},
-			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger)),
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
 		).Endpoint()
 		getFeedsEndpoint = opentracing.TraceClient(tracer, "GetFeeds")(getFeedsEndpoint)
 		getFeedsEndpoint = limiter(getFeedsEndpoint)
-		getFeedsEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "GetFeeds",
-			Timeout: 5 * time.Second,
-		}))(getFeedsEndpoint)
+		getFeedsEndpoint = balancer.Breaker("feed", conn.Target(), "GetFeeds")(getFeedsEndpoint)
 	}
 
 	var createFeedEndpoint endpoint.Endpoint
@@ -95,19 +197,203 @@ func NewFeedClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger l
 			util.DummyEncode,
 			util.DummyDecode,
 			feed.OkResponse{},
-			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger)),
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
 		).Endpoint()
 		createFeedEndpoint = opentracing.TraceClient(tracer, "CreateFeed")(createFeedEndpoint)
 		createFeedEndpoint = limiter(createFeedEndpoint)
-		createFeedEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "CreateFeed",
-			Timeout: 5 * time.Second,
-		}))(createFeedEndpoint)
+		createFeedEndpoint = balancer.Breaker("feed", conn.Target(), "CreateFeed")(createFeedEndpoint)
+	}
+
+	var searchFeedsEndpoint endpoint.Endpoint
+	{
+		searchFeedsEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"SearchFeeds",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.GetFeedsResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		searchFeedsEndpoint = opentracing.TraceClient(tracer, "SearchFeeds")(searchFeedsEndpoint)
+		searchFeedsEndpoint = limiter(searchFeedsEndpoint)
+		searchFeedsEndpoint = balancer.Breaker("feed", conn.Target(), "SearchFeeds")(searchFeedsEndpoint)
+	}
+
+	var getFeedsByTopicEndpoint endpoint.Endpoint
+	{
+		getFeedsByTopicEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"GetFeedsByTopic",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.GetFeedsResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		getFeedsByTopicEndpoint = opentracing.TraceClient(tracer, "GetFeedsByTopic")(getFeedsByTopicEndpoint)
+		getFeedsByTopicEndpoint = limiter(getFeedsByTopicEndpoint)
+		getFeedsByTopicEndpoint = balancer.Breaker("feed", conn.Target(), "GetFeedsByTopic")(getFeedsByTopicEndpoint)
+	}
+
+	var updateFeedEndpoint endpoint.Endpoint
+	{
+		updateFeedEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"UpdateFeed",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.FeedRecord{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		updateFeedEndpoint = opentracing.TraceClient(tracer, "UpdateFeed")(updateFeedEndpoint)
+		updateFeedEndpoint = limiter(updateFeedEndpoint)
+		updateFeedEndpoint = balancer.Breaker("feed", conn.Target(), "UpdateFeed")(updateFeedEndpoint)
+	}
+
+	var deleteFeedEndpoint endpoint.Endpoint
+	{
+		deleteFeedEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"DeleteFeed",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.OkResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		deleteFeedEndpoint = opentracing.TraceClient(tracer, "DeleteFeed")(deleteFeedEndpoint)
+		deleteFeedEndpoint = limiter(deleteFeedEndpoint)
+		deleteFeedEndpoint = balancer.Breaker("feed", conn.Target(), "DeleteFeed")(deleteFeedEndpoint)
+	}
+
+	var getFeedHistoryEndpoint endpoint.Endpoint
+	{
+		getFeedHistoryEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"GetFeedHistory",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.GetFeedHistoryResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		getFeedHistoryEndpoint = opentracing.TraceClient(tracer, "GetFeedHistory")(getFeedHistoryEndpoint)
+		getFeedHistoryEndpoint = limiter(getFeedHistoryEndpoint)
+		getFeedHistoryEndpoint = balancer.Breaker("feed", conn.Target(), "GetFeedHistory")(getFeedHistoryEndpoint)
+	}
+
+	var likeFeedEndpoint endpoint.Endpoint
+	{
+		likeFeedEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"LikeFeed",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.LikeResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		likeFeedEndpoint = opentracing.TraceClient(tracer, "LikeFeed")(likeFeedEndpoint)
+		likeFeedEndpoint = limiter(likeFeedEndpoint)
+		likeFeedEndpoint = balancer.Breaker("feed", conn.Target(), "LikeFeed")(likeFeedEndpoint)
+	}
+
+	var unlikeFeedEndpoint endpoint.Endpoint
+	{
+		unlikeFeedEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"UnlikeFeed",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.LikeResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		unlikeFeedEndpoint = opentracing.TraceClient(tracer, "UnlikeFeed")(unlikeFeedEndpoint)
+		unlikeFeedEndpoint = limiter(unlikeFeedEndpoint)
+		unlikeFeedEndpoint = balancer.Breaker("feed", conn.Target(), "UnlikeFeed")(unlikeFeedEndpoint)
+	}
+
+	var createCommentEndpoint endpoint.Endpoint
+	{
+		createCommentEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"CreateComment",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.Comment{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		createCommentEndpoint = opentracing.TraceClient(tracer, "CreateComment")(createCommentEndpoint)
+		createCommentEndpoint = limiter(createCommentEndpoint)
+		createCommentEndpoint = balancer.Breaker("feed", conn.Target(), "CreateComment")(createCommentEndpoint)
+	}
+
+	var getCommentsEndpoint endpoint.Endpoint
+	{
+		getCommentsEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"GetComments",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.GetCommentsResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		getCommentsEndpoint = opentracing.TraceClient(tracer, "GetComments")(getCommentsEndpoint)
+		getCommentsEndpoint = limiter(getCommentsEndpoint)
+		getCommentsEndpoint = balancer.Breaker("feed", conn.Target(), "GetComments")(getCommentsEndpoint)
+	}
+
+	var listHeldFeedsEndpoint endpoint.Endpoint
+	{
+		listHeldFeedsEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"ListHeldFeeds",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.ListHeldFeedsResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		listHeldFeedsEndpoint = opentracing.TraceClient(tracer, "ListHeldFeeds")(listHeldFeedsEndpoint)
+		listHeldFeedsEndpoint = limiter(listHeldFeedsEndpoint)
+		listHeldFeedsEndpoint = balancer.Breaker("feed", conn.Target(), "ListHeldFeeds")(listHeldFeedsEndpoint)
+	}
+
+	var reviewFeedEndpoint endpoint.Endpoint
+	{
+		reviewFeedEndpoint = grpctransport.NewClient(
+			conn,
+			"feed.Feed",
+			"ReviewFeed",
+			util.DummyEncode,
+			util.DummyDecode,
+			feed.OkResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		reviewFeedEndpoint = opentracing.TraceClient(tracer, "ReviewFeed")(reviewFeedEndpoint)
+		reviewFeedEndpoint = limiter(reviewFeedEndpoint)
+		reviewFeedEndpoint = balancer.Breaker("feed", conn.Target(), "ReviewFeed")(reviewFeedEndpoint)
 	}
 
 	return &FeedClient{
-		GetFeedsEndpoint:   getFeedsEndpoint,
-		CreateFeedEndpoint: createFeedEndpoint,
+		GetFeedsEndpoint:        getFeedsEndpoint,
+		CreateFeedEndpoint:      createFeedEndpoint,
+		SearchFeedsEndpoint:     searchFeedsEndpoint,
+		GetFeedsByTopicEndpoint: getFeedsByTopicEndpoint,
+		UpdateFeedEndpoint:      updateFeedEndpoint,
+		DeleteFeedEndpoint:      deleteFeedEndpoint,
+		GetFeedHistoryEndpoint:  getFeedHistoryEndpoint,
+		LikeFeedEndpoint:        likeFeedEndpoint,
+		UnlikeFeedEndpoint:      unlikeFeedEndpoint,
+		CreateCommentEndpoint:   createCommentEndpoint,
+		GetCommentsEndpoint:     getCommentsEndpoint,
+		ListHeldFeedsEndpoint:   listHeldFeedsEndpoint,
+		ReviewFeedEndpoint:      reviewFeedEndpoint,
 	}
 }
 
@@ -119,28 +405,172 @@ func MakeCreateFeedEndpoint(f feed.FeedClient) endpoint.Endpoint {
 	return f.(*FeedClient).CreateFeedEndpoint
 }
 
-func NewFeedClientWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer, logger log.Logger) feed.FeedClient {
+func MakeSearchFeedsEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).SearchFeedsEndpoint
+}
+
+func MakeGetFeedsByTopicEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).GetFeedsByTopicEndpoint
+}
+
+func MakeUpdateFeedEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).UpdateFeedEndpoint
+}
+
+func MakeDeleteFeedEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).DeleteFeedEndpoint
+}
+
+func MakeGetFeedHistoryEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).GetFeedHistoryEndpoint
+}
+
+func MakeLikeFeedEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).LikeFeedEndpoint
+}
+
+func MakeUnlikeFeedEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).UnlikeFeedEndpoint
+}
+
+func MakeCreateCommentEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).CreateCommentEndpoint
+}
+
+func MakeGetCommentsEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).GetCommentsEndpoint
+}
+
+func MakeListHeldFeedsEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).ListHeldFeedsEndpoint
+}
+
+func MakeReviewFeedEndpoint(f feed.FeedClient) endpoint.Endpoint {
+	return f.(*FeedClient).ReviewFeedEndpoint
+}
+
+// NewFeedClientWithSD routes every request to the feed instance owning its
+// data. Feeds are sharded by user id across the instances on a consistent
+// hash ring: requests on a feed go to the instance of its owner, named by
+// the user_id or the owner_id of the request, searches, topic listings and
+// the review queue are gathered from every instance. The
+// strategy and the zone of opts do not apply. The read methods are mirrored
+// to the shadow instances of opts, if any.
+func NewFeedClientWithSD(instancer sd.Instancer, tracer stdopentracing.Tracer, logger log.Logger, opts ...balancer.Option) feed.FeedClient {
 	res := &FeedClient{}
+	opts = append([]balancer.Option{balancer.WithService("feed")}, opts...)
 
-	factory := FeedFactory(MakeGetFeedsEndpoint, tracer, logger)
-	endpointer := sd.NewEndpointer(feedInstancer, factory, logger)
-	balancer := lb.NewRoundRobin(endpointer)
-	retry := lb.Retry(3, time.Second, balancer)
-	res.GetFeedsEndpoint = retry
+	route := func(makeEndpoint func(f feed.FeedClient) endpoint.Endpoint, extra ...balancer.Option) *balancer.Router {
+		all := append(append([]balancer.Option(nil), opts...), extra...)
+		return balancer.NewRouter(instancer, FeedFactory(makeEndpoint, tracer, logger), logger, all...)
+	}
+	// the reads are idempotent, they are retried.
+	getFeeds := route(MakeGetFeedsEndpoint, balancer.Mirror("GetFeeds"))
+	res.GetFeedsEndpoint = getFeeds.Retry(getFeeds.Endpoint(userKey))
+	res.CreateFeedEndpoint = route(MakeCreateFeedEndpoint).Endpoint(userKey)
+	res.UpdateFeedEndpoint = route(MakeUpdateFeedEndpoint).Endpoint(userKey)
+	res.DeleteFeedEndpoint = route(MakeDeleteFeedEndpoint).Endpoint(userKey)
+
+	searchFeeds := route(MakeSearchFeedsEndpoint, balancer.Mirror("SearchFeeds"))
+	res.SearchFeedsEndpoint = searchFeeds.Retry(searchFeeds.ScatterEndpoint(mergeFeeds(byScore)))
+	getFeedsByTopic := route(MakeGetFeedsByTopicEndpoint, balancer.Mirror("GetFeedsByTopic"))
+	res.GetFeedsByTopicEndpoint = getFeedsByTopic.Retry(getFeedsByTopic.ScatterEndpoint(mergeFeeds(newestFirst)))
 
-	factory = FeedFactory(MakeCreateFeedEndpoint, tracer, logger)
-	endpointer = sd.NewEndpointer(feedInstancer, factory, logger)
-	balancer = lb.NewRoundRobin(endpointer)
-	retry = lb.Retry(3, time.Second, balancer)
-	res.CreateFeedEndpoint = retry
+	getFeedHistory := route(MakeGetFeedHistoryEndpoint, balancer.Mirror("GetFeedHistory"))
+	res.GetFeedHistoryEndpoint = getFeedHistory.Retry(getFeedHistory.Endpoint(ownerKey))
+	res.LikeFeedEndpoint = route(MakeLikeFeedEndpoint).Endpoint(ownerKey)
+	res.UnlikeFeedEndpoint = route(MakeUnlikeFeedEndpoint).Endpoint(ownerKey)
+	res.CreateCommentEndpoint = route(MakeCreateCommentEndpoint).Endpoint(ownerKey)
+	getComments := route(MakeGetCommentsEndpoint, balancer.Mirror("GetComments"))
+	res.GetCommentsEndpoint = getComments.Retry(getComments.Endpoint(ownerKey))
+
+	listHeldFeeds := route(MakeListHeldFeedsEndpoint)
+	res.ListHeldFeedsEndpoint = listHeldFeeds.Retry(listHeldFeeds.ScatterEndpoint(mergeHeldFeeds))
+	res.ReviewFeedEndpoint = route(MakeReviewFeedEndpoint).Endpoint(ownerKey)
 
 	return res
 }
 
+func userKey(request interface{}) string {
+	return strconv.FormatInt(request.(interface {
+		GetUserId() int64
+	}).GetUserId(), 10)
+}
+
+func ownerKey(request interface{}) string {
+	return strconv.FormatInt(request.(interface {
+		GetOwnerId() int64
+	}).GetOwnerId(), 10)
+}
+
+// mergeFeeds sorts the feeds of every instance by before, and keeps up to
+// the size of the request, or feed.DefaultPageSize when it is unset.
+func mergeFeeds(before func(a, b *feed.FeedRecord) bool) func(request interface{}, responses []interface{}) interface{} {
+	return func(request interface{}, responses []interface{}) interface{} {
+		feeds := []*feed.FeedRecord{}
+		for _, resp := range responses {
+			feeds = append(feeds, resp.(*feed.GetFeedsResponse).GetFeeds()...)
+		}
+		sort.SliceStable(feeds, func(i, j int) bool {
+			return before(feeds[i], feeds[j])
+		})
+		size := int(request.(interface {
+			GetSize() int64
+		}).GetSize())
+		if size <= 0 {
+			size = feed.DefaultPageSize
+		}
+		if len(feeds) > size {
+			feeds = feeds[:size]
+		}
+		return &feed.GetFeedsResponse{Feeds: feeds}
+	}
+}
+
+// byScore puts the best matches of a search first. Each instance scores on
+// its own index, which is close enough as the feeds are spread evenly.
+func byScore(a, b *feed.FeedRecord) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Id > b.Id
+}
+
+func newestFirst(a, b *feed.FeedRecord) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt > b.CreatedAt
+	}
+	return a.Id > b.Id
+}
+
+// mergeHeldFeeds keeps the oldest held feeds of every instance, up to the
+// size of the request or feed.DefaultHeldSize when it is unset, as each
+// instance does.
+func mergeHeldFeeds(request interface{}, responses []interface{}) interface{} {
+	feeds := []*feed.HeldFeed{}
+	for _, resp := range responses {
+		feeds = append(feeds, resp.(*feed.ListHeldFeedsResponse).GetFeeds()...)
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		if feeds[i].GetHeldAt() != feeds[j].GetHeldAt() {
+			return feeds[i].GetHeldAt() < feeds[j].GetHeldAt()
+		}
+		return feeds[i].GetFeed().GetId() < feeds[j].GetFeed().GetId()
+	})
+	size := int(request.(*feed.ListHeldFeedsRequest).GetSize())
+	if size <= 0 {
+		size = feed.DefaultHeldSize
+	}
+	if len(feeds) > size {
+		feeds = feeds[:size]
+	}
+	return &feed.ListHeldFeedsResponse{Feeds: feeds}
+}
+
 // Todo: use connect pool, and reference counting to one connection.
 func FeedFactory(makeEndpoint func(f feed.FeedClient) endpoint.Endpoint, tracer stdopentracing.Tracer, logger log.Logger) sd.Factory {
 	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
-		conn, err := grpc.Dial(instance, grpc.WithInsecure())
+		conn, err := grpc.Dial(instance, mtls.DialOption("feed"))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -2,20 +2,36 @@ package feed_test
 
 import (
 	client "github.com/buptmiao/microservice-app/client/feed"
+	topic_client "github.com/buptmiao/microservice-app/client/topic"
+	"github.com/buptmiao/microservice-app/discovery"
 	"github.com/buptmiao/microservice-app/feed"
+	"github.com/buptmiao/microservice-app/moderation"
 	p_feed "github.com/buptmiao/microservice-app/proto/feed"
+	p_topic "github.com/buptmiao/microservice-app/proto/topic"
+	"github.com/buptmiao/microservice-app/topic"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
 	"github.com/opentracing/opentracing-go"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"net"
+	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 func runFeedServer(addr string) *grpc.Server {
-	service := feed.NewFeedService()
+	return runFeedServerWithTopics(addr, nil)
+}
+
+func runFeedServerWithTopics(addr string, topics p_topic.TopicClient) *grpc.Server {
+	return serveFeed(addr, feed.NewFeedService(topics, nil, log.NewNopLogger()))
+}
 
+func serveFeed(addr string, service p_feed.FeedServer) *grpc.Server {
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		panic(err)
@@ -61,3 +77,363 @@ func TestNewFeedClient(t *testing.T) {
 		panic(resp)
 	}
 }
+
+func TestGetFeedsByTopic(t *testing.T) {
+	ln, err := net.Listen("tcp", ":8011")
+	if err != nil {
+		panic(err)
+	}
+	ts := grpc.NewServer()
+	p_topic.RegisterTopicServer(ts, topic.MakeGRPCServer(context.Background(), topic.NewTopicService(), opentracing.NoopTracer{}, log.NewNopLogger()))
+	go ts.Serve(ln)
+	defer ts.GracefulStop()
+	tconn, err := grpc.Dial(":8011", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer tconn.Close()
+	topics := topic_client.NewTopicClient(tconn, opentracing.NoopTracer{}, log.NewNopLogger())
+	_, err = topics.CreateTopic(context.Background(), &p_topic.CreateTopicRequest{TopicId: 7, Subject: "Go Kit"})
+	if err != nil {
+		panic(err)
+	}
+
+	s := runFeedServerWithTopics(":8012", topics)
+	defer s.GracefulStop()
+	conn, err := grpc.Dial(":8012", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	service := client.NewFeedClient(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+	_, err = service.CreateFeed(context.Background(), &p_feed.FeedRecord{
+		Id:      200,
+		UserId:  456,
+		Content: "trying #GoKit with @alice, and #unknown",
+	})
+	if err != nil {
+		panic(err)
+	}
+	resp, err := service.GetFeedsByTopic(context.Background(), &p_feed.GetFeedsByTopicRequest{TopicId: 7, Size: 5})
+	if err != nil {
+		panic(err)
+	}
+	if len(resp.GetFeeds()) != 1 {
+		t.Fatalf("got %v, want feed 200", resp.GetFeeds())
+	}
+	f := resp.GetFeeds()[0]
+	if len(f.Hashtags) != 2 || len(f.Mentions) != 1 || f.Mentions[0] != "alice" {
+		t.Fatalf("unexpected tags %v, mentions %v", f.Hashtags, f.Mentions)
+	}
+}
+
+func TestUpdateAndDeleteFeed(t *testing.T) {
+	s := runFeedServer(":8015")
+	defer s.GracefulStop()
+	conn, err := grpc.Dial(":8015", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	service := client.NewFeedClient(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+	ctx := context.Background()
+	if _, err = service.CreateFeed(ctx, &p_feed.FeedRecord{Id: 300, UserId: 789, Content: "first"}); err != nil {
+		panic(err)
+	}
+	if _, err = service.UpdateFeed(ctx, &p_feed.UpdateFeedRequest{Id: 300, UserId: 1, Content: "hijack"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("update by another user got %v, want PermissionDenied", err)
+	}
+	f, err := service.UpdateFeed(ctx, &p_feed.UpdateFeedRequest{Id: 300, UserId: 789, Content: "second", ExpectedVersion: 1})
+	if err != nil {
+		panic(err)
+	}
+	if f.Version != 2 {
+		t.Fatalf("got version %d, want 2", f.Version)
+	}
+	if _, err = service.UpdateFeed(ctx, &p_feed.UpdateFeedRequest{Id: 300, UserId: 789, Content: "stale", ExpectedVersion: 1}); status.Code(err) != codes.Aborted {
+		t.Fatalf("update of a stale version got %v, want Aborted", err)
+	}
+	h, err := service.GetFeedHistory(ctx, &p_feed.GetFeedHistoryRequest{Id: 300, OwnerId: 789})
+	if err != nil {
+		panic(err)
+	}
+	if n := len(h.GetRevisions()); n != 2 || h.GetRevisions()[1].Content != "second" {
+		t.Fatalf("unexpected history %v", h.GetRevisions())
+	}
+	if _, err = service.GetFeedHistory(ctx, &p_feed.GetFeedHistoryRequest{Id: 300, OwnerId: 1}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("history for another owner got %v, want PermissionDenied", err)
+	}
+	if _, err = service.DeleteFeed(ctx, &p_feed.DeleteFeedRequest{Id: 300, UserId: 789}); err != nil {
+		panic(err)
+	}
+	resp, err := service.GetFeeds(ctx, &p_feed.GetFeedsRequest{UserId: 789, Size: 5})
+	if err != nil {
+		panic(err)
+	}
+	if len(resp.GetFeeds()) != 0 {
+		t.Fatalf("deleted feed returned: %v", resp.GetFeeds())
+	}
+	if _, err = service.GetFeedHistory(ctx, &p_feed.GetFeedHistoryRequest{Id: 300, OwnerId: 789}); status.Code(err) != codes.NotFound {
+		t.Fatalf("history of a deleted feed got %v, want NotFound", err)
+	}
+}
+
+func TestLikesAndComments(t *testing.T) {
+	s := runFeedServer(":8016")
+	defer s.GracefulStop()
+	conn, err := grpc.Dial(":8016", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	service := client.NewFeedClient(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+	ctx := context.Background()
+	if _, err = service.CreateFeed(ctx, &p_feed.FeedRecord{Id: 400, UserId: 900, Content: "like me"}); err != nil {
+		panic(err)
+	}
+	for _, user := range []int64{1, 2, 2} {
+		if _, err = service.LikeFeed(ctx, &p_feed.LikeRequest{FeedId: 400, UserId: user, OwnerId: 900}); err != nil {
+			panic(err)
+		}
+	}
+	// feed ids are only unique on one instance, the owner names the feed.
+	if _, err = service.LikeFeed(ctx, &p_feed.LikeRequest{FeedId: 400, UserId: 3}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("like without an owner got %v, want InvalidArgument", err)
+	}
+	if _, err = service.LikeFeed(ctx, &p_feed.LikeRequest{FeedId: 400, UserId: 3, OwnerId: 901}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("like naming another owner got %v, want PermissionDenied", err)
+	}
+	if _, err = service.GetComments(ctx, &p_feed.GetCommentsRequest{FeedId: 400, OwnerId: 901}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("comments naming another owner got %v, want PermissionDenied", err)
+	}
+	top, err := service.CreateComment(ctx, &p_feed.Comment{FeedId: 400, UserId: 1, OwnerId: 900, Content: "nice"})
+	if err != nil {
+		panic(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = service.CreateComment(ctx, &p_feed.Comment{FeedId: 400, UserId: 2, OwnerId: 900, ParentId: top.Id, Content: "reply"}); err != nil {
+			panic(err)
+		}
+	}
+	page, err := service.GetComments(ctx, &p_feed.GetCommentsRequest{FeedId: 400, OwnerId: 900, ParentId: top.Id, Size: 2})
+	if err != nil {
+		panic(err)
+	}
+	if len(page.Comments) != 2 || page.NextCursor == 0 {
+		t.Fatalf("unexpected first page %v", page)
+	}
+	page, err = service.GetComments(ctx, &p_feed.GetCommentsRequest{FeedId: 400, OwnerId: 900, ParentId: top.Id, Cursor: page.NextCursor, Size: 2})
+	if err != nil {
+		panic(err)
+	}
+	if len(page.Comments) != 1 || page.NextCursor != 0 {
+		t.Fatalf("unexpected last page %v", page)
+	}
+	resp, err := service.GetFeeds(ctx, &p_feed.GetFeedsRequest{UserId: 900, Size: 1})
+	if err != nil {
+		panic(err)
+	}
+	if f := resp.GetFeeds()[0]; f.LikeCount != 2 || f.CommentCount != 4 {
+		t.Fatalf("got %d likes and %d comments, want 2 and 4", f.LikeCount, f.CommentCount)
+	}
+}
+
+func TestModeration(t *testing.T) {
+	blocklist, err := moderation.NewBlocklist([]moderation.BlockRule{
+		{Keyword: "casino", Verdict: moderation.Reject},
+		{Keyword: "giveaway", Verdict: moderation.Hold},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := serveFeed(":8017", feed.NewFeedService(nil, moderation.NewChain(blocklist), log.NewNopLogger()))
+	defer s.GracefulStop()
+	conn, err := grpc.Dial(":8017", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	service := client.NewFeedClient(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+	ctx := context.Background()
+
+	_, err = service.CreateFeed(ctx, &p_feed.FeedRecord{Id: 900, UserId: 901, Content: "best Casino in town"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got %v, want PermissionDenied", err)
+	}
+	ok, err := service.CreateFeed(ctx, &p_feed.FeedRecord{Id: 902, UserId: 901, Content: "giveaway tonight"})
+	if err != nil {
+		panic(err)
+	}
+	if !ok.Held {
+		t.Fatal("feed should be held")
+	}
+	if _, err = service.GetFeeds(ctx, &p_feed.GetFeedsRequest{UserId: 901, Size: 5}); err == nil {
+		t.Fatal("held feed should not be visible")
+	}
+	held, err := service.ListHeldFeeds(ctx, &p_feed.ListHeldFeedsRequest{})
+	if err != nil {
+		panic(err)
+	}
+	if len(held.Feeds) != 1 || held.Feeds[0].Feed.Id != 902 || held.Feeds[0].Stage != "blocklist" {
+		t.Fatalf("unexpected review queue %v", held.Feeds)
+	}
+
+	if _, err = service.ReviewFeed(ctx, &p_feed.ReviewFeedRequest{Id: 902, OwnerId: 903, Approve: true, Moderator: "alice"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("review of another owner got %v, want NotFound", err)
+	}
+	review := &p_feed.ReviewFeedRequest{Id: 902, OwnerId: 901, Approve: true, Moderator: "alice"}
+	if _, err = service.ReviewFeed(ctx, review); err != nil {
+		panic(err)
+	}
+	if _, err = service.ReviewFeed(ctx, review); status.Code(err) != codes.NotFound {
+		t.Fatalf("second review got %v, want NotFound", err)
+	}
+	resp, err := service.GetFeeds(ctx, &p_feed.GetFeedsRequest{UserId: 901, Size: 5})
+	if err != nil {
+		panic(err)
+	}
+	if len(resp.Feeds) != 1 || resp.Feeds[0].Id != 902 {
+		t.Fatalf("approved feed not published: %v", resp.Feeds)
+	}
+
+	// the edits are moderated as well.
+	_, err = service.UpdateFeed(ctx, &p_feed.UpdateFeedRequest{Id: 902, UserId: 901, Content: "giveaway at the casino"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("edit got %v, want PermissionDenied", err)
+	}
+	edited, err := service.UpdateFeed(ctx, &p_feed.UpdateFeedRequest{Id: 902, UserId: 901, Content: "giveaway, round two"})
+	if err != nil {
+		panic(err)
+	}
+	if !edited.Held || edited.Content != "giveaway tonight" || edited.Version != 1 {
+		t.Fatalf("edit should be held, got %v", edited)
+	}
+	held, err = service.ListHeldFeeds(ctx, &p_feed.ListHeldFeedsRequest{})
+	if err != nil {
+		panic(err)
+	}
+	if len(held.Feeds) != 1 || !held.Feeds[0].Edit || held.Feeds[0].Feed.Content != "giveaway, round two" {
+		t.Fatalf("unexpected review queue %v", held.Feeds)
+	}
+	// a rejected edit leaves the feed, and its id, as they are.
+	if _, err = service.ReviewFeed(ctx, &p_feed.ReviewFeedRequest{Id: 902, OwnerId: 901, Moderator: "alice"}); err != nil {
+		panic(err)
+	}
+	if _, err = service.CreateFeed(ctx, &p_feed.FeedRecord{Id: 902, UserId: 904, Content: "hello"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("create after a rejected edit got %v, want AlreadyExists", err)
+	}
+	if _, err = service.UpdateFeed(ctx, &p_feed.UpdateFeedRequest{Id: 902, UserId: 901, Content: "giveaway, round three"}); err != nil {
+		panic(err)
+	}
+	if _, err = service.ReviewFeed(ctx, review); err != nil {
+		panic(err)
+	}
+	resp, err = service.GetFeeds(ctx, &p_feed.GetFeedsRequest{UserId: 901, Size: 5})
+	if err != nil {
+		panic(err)
+	}
+	if f := resp.Feeds[0]; f.Content != "giveaway, round three" || f.Version != 2 {
+		t.Fatalf("approved edit not applied: %v", f)
+	}
+}
+
+// shardStub answers the reads of one instance with fixed feeds and review
+// queue, and fails the first history requests.
+type shardStub struct {
+	p_feed.FeedServer
+	feeds    []*p_feed.FeedRecord
+	held     []*p_feed.HeldFeed
+	failures int32
+}
+
+// heldFeeds returns n held feeds, held in the order of their ids from first.
+func heldFeeds(first int64, n int) []*p_feed.HeldFeed {
+	res := []*p_feed.HeldFeed{}
+	for id := first; id < first+int64(n); id++ {
+		res = append(res, &p_feed.HeldFeed{Feed: &p_feed.FeedRecord{Id: id}, HeldAt: id})
+	}
+	return res
+}
+
+func (s *shardStub) SearchFeeds(context.Context, *p_feed.SearchFeedsRequest) (*p_feed.GetFeedsResponse, error) {
+	return &p_feed.GetFeedsResponse{Feeds: s.feeds}, nil
+}
+
+func (s *shardStub) GetFeedsByTopic(context.Context, *p_feed.GetFeedsByTopicRequest) (*p_feed.GetFeedsResponse, error) {
+	return &p_feed.GetFeedsResponse{Feeds: s.feeds}, nil
+}
+
+func (s *shardStub) GetFeedHistory(context.Context, *p_feed.GetFeedHistoryRequest) (*p_feed.GetFeedHistoryResponse, error) {
+	if atomic.AddInt32(&s.failures, -1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "restarting")
+	}
+	return &p_feed.GetFeedHistoryResponse{Revisions: []*p_feed.FeedRevision{{Version: 1}}}, nil
+}
+
+func (s *shardStub) ListHeldFeeds(context.Context, *p_feed.ListHeldFeedsRequest) (*p_feed.ListHeldFeedsResponse, error) {
+	return &p_feed.ListHeldFeedsResponse{Feeds: s.held}, nil
+}
+
+func TestShardedReads(t *testing.T) {
+	a := serveFeed(":8018", &shardStub{feeds: []*p_feed.FeedRecord{
+		{Id: 1, Score: 3, CreatedAt: 40},
+		{Id: 2, Score: 1, CreatedAt: 10},
+	}, held: heldFeeds(1, p_feed.DefaultHeldSize), failures: 1})
+	defer a.GracefulStop()
+	b := serveFeed(":8019", &shardStub{feeds: []*p_feed.FeedRecord{
+		{Id: 3, Score: 2, CreatedAt: 30},
+		{Id: 4, Score: 0.5, CreatedAt: 20},
+	}, held: heldFeeds(0, p_feed.DefaultHeldSize), failures: 1})
+	defer b.GracefulStop()
+	instancer := sd.FixedInstancer{
+		discovery.Instance{Addr: "localhost:8018"}.Encode(),
+		discovery.Instance{Addr: "localhost:8019"}.Encode(),
+	}
+	service := client.NewFeedClientWithSD(instancer, opentracing.NoopTracer{}, log.NewNopLogger())
+	ctx := context.Background()
+
+	ids := func(resp *p_feed.GetFeedsResponse) []int64 {
+		res := []int64{}
+		for _, f := range resp.GetFeeds() {
+			res = append(res, f.Id)
+		}
+		return res
+	}
+	found, err := service.SearchFeeds(ctx, &p_feed.SearchFeedsRequest{Query: "go", Size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(found); !reflect.DeepEqual(got, []int64{1, 3, 2}) {
+		t.Errorf("search got %v, want the best scores first", got)
+	}
+	listed, err := service.GetFeedsByTopic(ctx, &p_feed.GetFeedsByTopicRequest{TopicId: 1, Size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(listed); !reflect.DeepEqual(got, []int64{1, 3, 4}) {
+		t.Errorf("topic got %v, want the newest first", got)
+	}
+
+	// the owner fails once and the read is retried.
+	history, err := service.GetFeedHistory(ctx, &p_feed.GetFeedHistoryRequest{Id: 1, OwnerId: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Revisions) != 1 {
+		t.Errorf("unexpected history %v", history.Revisions)
+	}
+
+	// the review queue is capped at the default size, as on each instance.
+	for _, tc := range []struct {
+		size int64
+		want int
+	}{{0, p_feed.DefaultHeldSize}, {5, 5}, {100, 2 * p_feed.DefaultHeldSize}} {
+		held, err := service.ListHeldFeeds(ctx, &p_feed.ListHeldFeedsRequest{Size: tc.size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(held.Feeds) != tc.want || held.Feeds[0].Feed.Id != 0 || held.Feeds[1].Feed.Id != 1 {
+			t.Errorf("size %d: got %d held feeds from %v, want %d from the oldest", tc.size, len(held.Feeds), held.Feeds[0].Feed, tc.want)
+		}
+	}
+}
@@ -2,36 +2,42 @@ package profile
 
 import (
 	"io"
+	"strconv"
 	"time"
 
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/buptmiao/microservice-app/mtls"
 	"github.com/buptmiao/microservice-app/proto/profile"
 	"github.com/buptmiao/microservice-app/util"
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/sd"
-	"github.com/go-kit/kit/sd/etcd"
-	"github.com/go-kit/kit/sd/lb"
 	"github.com/go-kit/kit/tracing/opentracing"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	stdopentracing "github.com/opentracing/opentracing-go"
-	"github.com/sony/gobreaker"
 	"golang.org/x/net/context"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 )
 
 var profileCli profile.ProfileClient
-var profileInstancer *etcd.Instancer
+var profileInstancer sd.Instancer
 
 func Init(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger log.Logger) {
 	profileCli = NewProfileClient(conn, tracer, logger)
 }
 
-func InitWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer, logger log.Logger) {
-	profileCli = NewProfileClientWithSD(sdClient, tracer, logger)
-	profileInstancer, _ = etcd.NewInstancer(sdClient, "prifileSD", logger)
+// InitWithSD watches the profile instances in the registry.
+func InitWithSD(registry discovery.Registry, tracer stdopentracing.Tracer, logger log.Logger, opts ...balancer.Option) error {
+	var err error
+	if profileInstancer, err = registry.Instancer("profile"); err != nil {
+		return err
+	}
+	profileCli = NewProfileClientWithSD(profileInstancer, tracer, logger, opts...)
+	return nil
 }
 
 func GetClient() profile.ProfileClient {
@@ -65,14 +71,11 @@ func NewProfileClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logge
 			util.DummyEncode,
 			util.DummyDecode,
 			profile.GetProfileResponse{},
-			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger)),
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
 		).Endpoint()
 		getProfileEndpoint = opentracing.TraceClient(tracer, "GetProfile")(getProfileEndpoint)
 		getProfileEndpoint = limiter(getProfileEndpoint)
-		getProfileEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "GetProfile",
-			Timeout: 5 * time.Second,
-		}))(getProfileEndpoint)
+		getProfileEndpoint = balancer.Breaker("profile", conn.Target(), "GetProfile")(getProfileEndpoint)
 	}
 
 	return &ProfileClient{
@@ -84,14 +87,18 @@ func MakeGetProfileEndpoint(f profile.ProfileClient) endpoint.Endpoint {
 	return f.(*ProfileClient).GetProfileEndpoint
 }
 
-func NewProfileClientWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer, logger log.Logger) profile.ProfileClient {
+// NewProfileClientWithSD balances the requests across the profile instances
+// with the strategy selected by opts, round robin by default. Requests on one
+// user share their hash key. GetProfile is mirrored to the shadow instances
+// and hedged as set by opts, if at all.
+func NewProfileClientWithSD(instancer sd.Instancer, tracer stdopentracing.Tracer, logger log.Logger, opts ...balancer.Option) profile.ProfileClient {
 	res := &ProfileClient{}
+	opts = append([]balancer.Option{balancer.WithService("profile")}, opts...)
 
 	factory := ProfileFactory(MakeGetProfileEndpoint, tracer, logger)
-	endpointer := sd.NewEndpointer(profileInstancer, factory, logger)
-	balancer := lb.NewRoundRobin(endpointer)
-	retry := lb.Retry(3, time.Second, balancer)
-	res.GetProfileEndpoint = retry
+	res.GetProfileEndpoint = balancer.NewEndpoint(instancer, factory, func(request interface{}) string {
+		return strconv.FormatInt(request.(*profile.GetProfileRequest).GetUserId(), 10)
+	}, logger, append(opts, balancer.Mirror("GetProfile"))...)
 
 	return res
 }
@@ -99,7 +106,7 @@ func NewProfileClientWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer,
 // Todo: use connect pool, and reference counting to one connection.
 func ProfileFactory(makeEndpoint func(f profile.ProfileClient) endpoint.Endpoint, tracer stdopentracing.Tracer, logger log.Logger) sd.Factory {
 	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
-		conn, err := grpc.Dial(instance, grpc.WithInsecure())
+		conn, err := grpc.Dial(instance, mtls.DialOption("profile"))
 		if err != nil {
 			return nil, nil, err
 		}
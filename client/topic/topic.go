@@ -2,37 +2,43 @@ package topic
 
 import (
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/buptmiao/microservice-app/mtls"
 	"github.com/buptmiao/microservice-app/proto/topic"
 	"github.com/buptmiao/microservice-app/util"
-	"github.com/go-kit/kit/circuitbreaker"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/ratelimit"
 	"github.com/go-kit/kit/sd"
-	"github.com/go-kit/kit/sd/etcd"
-	"github.com/go-kit/kit/sd/lb"
 	"github.com/go-kit/kit/tracing/opentracing"
 	grpctransport "github.com/go-kit/kit/transport/grpc"
 	stdopentracing "github.com/opentracing/opentracing-go"
-	"github.com/sony/gobreaker"
 	"golang.org/x/net/context"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 )
 
 var topicCli topic.TopicClient
-var topicInstancer *etcd.Instancer
+var topicInstancer sd.Instancer
 
 func Init(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger log.Logger) {
 	topicCli = NewTopicClient(conn, tracer, logger)
 }
 
-func InitWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer, logger log.Logger) {
-	topicCli = NewTopicClientWithSD(sdClient, tracer, logger)
-	topicInstancer, _ = etcd.NewInstancer(sdClient, "topicSD", logger)
-
+// InitWithSD watches the topic instances in the registry.
+func InitWithSD(registry discovery.Registry, tracer stdopentracing.Tracer, logger log.Logger, opts ...balancer.Option) error {
+	var err error
+	if topicInstancer, err = registry.Instancer("topic"); err != nil {
+		return err
+	}
+	topicCli = NewTopicClientWithSD(topicInstancer, tracer, logger, opts...)
+	return nil
 }
 
 func GetClient() topic.TopicClient {
@@ -43,7 +49,10 @@ func GetClient() topic.TopicClient {
 }
 
 type TopicClient struct {
-	GetTopicEndpoint endpoint.Endpoint
+	GetTopicEndpoint     endpoint.Endpoint
+	CreateTopicEndpoint  endpoint.Endpoint
+	SearchTopicsEndpoint endpoint.Endpoint
+	ResolveTagsEndpoint  endpoint.Endpoint
 }
 
 func (p *TopicClient) GetTopic(ctx context.Context, in *topic.GetTopicRequest, opts ...grpc.CallOption) (*topic.GetTopicResponse, error) {
@@ -54,6 +63,30 @@ func (p *TopicClient) GetTopic(ctx context.Context, in *topic.GetTopicRequest, o
 	return resp.(*topic.GetTopicResponse), nil
 }
 
+func (p *TopicClient) CreateTopic(ctx context.Context, in *topic.CreateTopicRequest, opts ...grpc.CallOption) (*topic.OkResponse, error) {
+	resp, err := p.CreateTopicEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*topic.OkResponse), nil
+}
+
+func (p *TopicClient) SearchTopics(ctx context.Context, in *topic.SearchTopicsRequest, opts ...grpc.CallOption) (*topic.SearchTopicsResponse, error) {
+	resp, err := p.SearchTopicsEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*topic.SearchTopicsResponse), nil
+}
+
+func (p *TopicClient) ResolveTags(ctx context.Context, in *topic.ResolveTagsRequest, opts ...grpc.CallOption) (*topic.ResolveTagsResponse, error) {
+	resp, err := p.ResolveTagsEndpoint(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*topic.ResolveTagsResponse), nil
+}
+
 func NewTopicClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger log.Logger) topic.TopicClient {
 	limiter := ratelimit.NewDelayingLimiter(rate.NewLimiter(rate.Every(time.Second), 1000))
 
@@ -66,18 +99,66 @@ func NewTopicClient(conn *grpc.ClientConn, tracer stdopentracing.Tracer, logger
 			util.DummyEncode,
 			util.DummyDecode,
 			topic.GetTopicResponse{},
-			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger)),
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
 		).Endpoint()
 		getTopicEndpoint = opentracing.TraceClient(tracer, "GetTopic")(getTopicEndpoint)
 		getTopicEndpoint = limiter(getTopicEndpoint)
-		getTopicEndpoint = circuitbreaker.Gobreaker(gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:    "GetTopic",
-			Timeout: 5 * time.Second,
-		}))(getTopicEndpoint)
+		getTopicEndpoint = balancer.Breaker("topic", conn.Target(), "GetTopic")(getTopicEndpoint)
+	}
+
+	var createTopicEndpoint endpoint.Endpoint
+	{
+		createTopicEndpoint = grpctransport.NewClient(
+			conn,
+			"topic.Topic",
+			"CreateTopic",
+			util.DummyEncode,
+			util.DummyDecode,
+			topic.OkResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		createTopicEndpoint = opentracing.TraceClient(tracer, "CreateTopic")(createTopicEndpoint)
+		createTopicEndpoint = limiter(createTopicEndpoint)
+		createTopicEndpoint = balancer.Breaker("topic", conn.Target(), "CreateTopic")(createTopicEndpoint)
+	}
+
+	var searchTopicsEndpoint endpoint.Endpoint
+	{
+		searchTopicsEndpoint = grpctransport.NewClient(
+			conn,
+			"topic.Topic",
+			"SearchTopics",
+			util.DummyEncode,
+			util.DummyDecode,
+			topic.SearchTopicsResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		searchTopicsEndpoint = opentracing.TraceClient(tracer, "SearchTopics")(searchTopicsEndpoint)
+		searchTopicsEndpoint = limiter(searchTopicsEndpoint)
+		searchTopicsEndpoint = balancer.Breaker("topic", conn.Target(), "SearchTopics")(searchTopicsEndpoint)
+	}
+
+	var resolveTagsEndpoint endpoint.Endpoint
+	{
+		resolveTagsEndpoint = grpctransport.NewClient(
+			conn,
+			"topic.Topic",
+			"ResolveTags",
+			util.DummyEncode,
+			util.DummyDecode,
+			topic.ResolveTagsResponse{},
+			grpctransport.ClientBefore(opentracing.ContextToGRPC(tracer, logger), logging.ContextToGRPC),
+		).Endpoint()
+		resolveTagsEndpoint = opentracing.TraceClient(tracer, "ResolveTags")(resolveTagsEndpoint)
+		resolveTagsEndpoint = limiter(resolveTagsEndpoint)
+		resolveTagsEndpoint = balancer.Breaker("topic", conn.Target(), "ResolveTags")(resolveTagsEndpoint)
 	}
 
 	return &TopicClient{
-		GetTopicEndpoint: getTopicEndpoint,
+		GetTopicEndpoint:     getTopicEndpoint,
+		CreateTopicEndpoint:  createTopicEndpoint,
+		SearchTopicsEndpoint: searchTopicsEndpoint,
+		ResolveTagsEndpoint:  resolveTagsEndpoint,
 	}
 }
 
@@ -85,22 +166,59 @@ func MakeGetTopicEndpoint(f topic.TopicClient) endpoint.Endpoint {
 	return f.(*TopicClient).GetTopicEndpoint
 }
 
-func NewTopicClientWithSD(sdClient etcd.Client, tracer stdopentracing.Tracer, logger log.Logger) topic.TopicClient {
+func MakeCreateTopicEndpoint(f topic.TopicClient) endpoint.Endpoint {
+	return f.(*TopicClient).CreateTopicEndpoint
+}
+
+func MakeSearchTopicsEndpoint(f topic.TopicClient) endpoint.Endpoint {
+	return f.(*TopicClient).SearchTopicsEndpoint
+}
+
+func MakeResolveTagsEndpoint(f topic.TopicClient) endpoint.Endpoint {
+	return f.(*TopicClient).ResolveTagsEndpoint
+}
+
+// NewTopicClientWithSD balances the requests across the topic instances
+// with the strategy selected by opts, round robin by default. Requests on one
+// topic share their hash key. The read methods are mirrored to the shadow
+// instances and hedged as set by opts, if at all.
+func NewTopicClientWithSD(instancer sd.Instancer, tracer stdopentracing.Tracer, logger log.Logger, opts ...balancer.Option) topic.TopicClient {
 	res := &TopicClient{}
+	opts = append([]balancer.Option{balancer.WithService("topic")}, opts...)
+
+	read := func(method string) []balancer.Option {
+		return append(append([]balancer.Option(nil), opts...), balancer.Mirror(method))
+	}
 
 	factory := TopicFactory(MakeGetTopicEndpoint, tracer, logger)
-	endpointer := sd.NewEndpointer(topicInstancer, factory, logger)
-	balancer := lb.NewRoundRobin(endpointer)
-	retry := lb.Retry(3, time.Second, balancer)
-	res.GetTopicEndpoint = retry
+	res.GetTopicEndpoint = balancer.NewEndpoint(instancer, factory, topicKey, logger, read("GetTopic")...)
+
+	factory = TopicFactory(MakeCreateTopicEndpoint, tracer, logger)
+	res.CreateTopicEndpoint = balancer.NewEndpoint(instancer, factory, topicKey, logger, opts...)
+
+	factory = TopicFactory(MakeSearchTopicsEndpoint, tracer, logger)
+	res.SearchTopicsEndpoint = balancer.NewEndpoint(instancer, factory, func(request interface{}) string {
+		return request.(*topic.SearchTopicsRequest).GetQuery()
+	}, logger, read("SearchTopics")...)
+
+	factory = TopicFactory(MakeResolveTagsEndpoint, tracer, logger)
+	res.ResolveTagsEndpoint = balancer.NewEndpoint(instancer, factory, func(request interface{}) string {
+		return strings.Join(request.(*topic.ResolveTagsRequest).GetTags(), " ")
+	}, logger, read("ResolveTags")...)
 
 	return res
 }
 
+func topicKey(request interface{}) string {
+	return strconv.FormatInt(request.(interface {
+		GetTopicId() int64
+	}).GetTopicId(), 10)
+}
+
 // Todo: use connect pool, and reference counting to one connection.
 func TopicFactory(makeEndpoint func(f topic.TopicClient) endpoint.Endpoint, tracer stdopentracing.Tracer, logger log.Logger) sd.Factory {
 	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
-		conn, err := grpc.Dial(instance, grpc.WithInsecure())
+		conn, err := grpc.Dial(instance, mtls.DialOption("topic"))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -10,6 +10,8 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"net"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 )
@@ -50,3 +52,73 @@ func TestNewTopicClient(t *testing.T) {
 		fmt.Println(resp, err)
 	}
 }
+
+func TestSearchTopics(t *testing.T) {
+	s := runTopicServer(":8013")
+	defer s.GracefulStop()
+	conn, err := grpc.Dial(":8013", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	service := client.NewTopicClient(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+	topics := []*p_topic.CreateTopicRequest{
+		{TopicId: 1, Subject: "Go microservices", Content: "service discovery with etcd"},
+		{TopicId: 2, Subject: "Cooking", Content: "how to discover new recipes"},
+	}
+	for _, req := range topics {
+		if _, err := service.CreateTopic(context.Background(), req); err != nil {
+			panic(err)
+		}
+	}
+	cases := map[string][]int64{
+		"etcd":                {1},
+		"disc*":               {1, 2},
+		`"service discovery"`: {1},
+		`"discovery service"`: {},
+	}
+	for q, want := range cases {
+		resp, err := service.SearchTopics(context.Background(), &p_topic.SearchTopicsRequest{Query: q, Size: 10})
+		if err != nil {
+			panic(err)
+		}
+		got := []int64{}
+		for _, t := range resp.GetTopics() {
+			got = append(got, t.GetTopicId())
+		}
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("query %s: got ids %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestRenameKeepsTagOfOtherTopic(t *testing.T) {
+	s := runTopicServer(":8014")
+	defer s.GracefulStop()
+	conn, err := grpc.Dial(":8014", grpc.WithInsecure(), grpc.WithTimeout(time.Second))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	service := client.NewTopicClient(conn, opentracing.NoopTracer{}, log.NewNopLogger())
+	ctx := context.Background()
+	for _, req := range []*p_topic.CreateTopicRequest{
+		{TopicId: 21, Subject: "Gardening"},
+		// takes the tag over.
+		{TopicId: 22, Subject: "gardening"},
+		// renaming the first topic must not unlink the second.
+		{TopicId: 21, Subject: "Botany"},
+	} {
+		if _, err := service.CreateTopic(ctx, req); err != nil {
+			panic(err)
+		}
+	}
+	resp, err := service.ResolveTags(ctx, &p_topic.ResolveTagsRequest{Tags: []string{"gardening", "botany"}})
+	if err != nil {
+		panic(err)
+	}
+	if got := resp.GetTopicIds(); got["gardening"] != 22 || got["botany"] != 21 {
+		t.Fatalf("got %v, want gardening:22 botany:21", got)
+	}
+}
@@ -2,20 +2,29 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"net/http"
 	"net/http/pprof"
 	"os"
 	"strings"
+	"time"
 
 	"context"
+	"github.com/buptmiao/microservice-app/admin"
 	"github.com/buptmiao/microservice-app/apigateway"
+	"github.com/buptmiao/microservice-app/client/balancer"
 	"github.com/buptmiao/microservice-app/client/feed"
 	"github.com/buptmiao/microservice-app/client/profile"
 	"github.com/buptmiao/microservice-app/client/topic"
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/buptmiao/microservice-app/media"
+	"github.com/buptmiao/microservice-app/mtls"
+	"github.com/buptmiao/microservice-app/slo"
+	"github.com/buptmiao/microservice-app/telemetry"
 	"github.com/facebookgo/grace/gracehttp"
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/sd/etcd"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	zipkin "github.com/openzipkin/zipkin-go-opentracing"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
@@ -25,23 +34,83 @@ func main() {
 	var (
 		httpAddr   = flag.String("http.addr", ":8080", "HTTP server address")
 		etcdAddr   = flag.String("etcd.addr", "", "etcd registry address")
+		sdBackend  = flag.String("discovery", "etcd", "the service registry: etcd, consul, dns or static")
+		sdAddr     = flag.String("discovery.addr", "", "the registry address, -etcd.addr is used for etcd when empty")
 		zipkinAddr = flag.String("zipkin.addr", "", "tracer server address")
+		otelExp    = flag.String("otel.exporter", "", "export the traces and metrics with OpenTelemetry: otlp, stdout or file, it replaces zipkin")
+		otelAddr   = flag.String("otel.endpoint", "", "the OTLP/HTTP collector address, OTEL_EXPORTER_OTLP_ENDPOINT by default, or the path of the file exporter")
+		otelSample = flag.Float64("otel.sample", 1, "the fraction of the new traces recorded, the others follow the decision of their caller")
+		logLvl     = flag.String("log.level", "info", "the minimum level logged: debug, info, warn or error, it can be changed at /debug/loglevel with the admin token")
+		logRedact  = flag.String("log.redact", "", "comma separated payload fields never logged, on top of password, token, secret, authorization, email and phone")
+		sloConfig  = flag.String("slo.config", "", "the JSON file declaring the service level objectives, e.g. monitor/slo.json")
+		adminToken = flag.String("admin.token", "", "the token of the admin actions on the debug address and of /admin/split, ADMIN_TOKEN by default, they are disabled without one")
+		modToken   = flag.String("moderation.token", "", "the token of the moderators at /admin/moderation, MODERATION_TOKEN by default, the review queue is disabled without one")
+		tlsCA      = flag.String("tls.ca", "", "the CA bundle the services are verified with, mutual TLS is on with -tls.cert and -tls.key")
+		tlsCert    = flag.String("tls.cert", "", "the certificate presented to the services, whose first DNS name is apigateway, it is reloaded on change")
+		tlsKey     = flag.String("tls.key", "", "the private key of -tls.cert")
+		httpCert   = flag.String("http.tls.cert", "", "the certificate of the public listener, HTTPS is served with -http.tls.key, it is reloaded on change")
+		httpKey    = flag.String("http.tls.key", "", "the private key of -http.tls.cert")
+		accessRate = flag.Float64("access.sample", 1, "the fraction of the successful requests written to the access log, failed and slow ones always are")
+		accessSlow = flag.Duration("access.slow", time.Second, "the latency beyond which a request is slow")
+		mediaDir   = flag.String("media.dir", "media_data", "the directory of uploaded media files")
+		mediaMax   = flag.Int64("media.maxsize", 10<<20, "the maximum size in bytes of an uploaded file")
+		profileLB  = flag.String("profile.balancer", "roundrobin", "the profile client balancer: roundrobin, hash, p2c or weighted")
+		topicLB    = flag.String("topic.balancer", "roundrobin", "the topic client balancer: roundrobin, hash, p2c or weighted")
+		feedVer    = flag.String("feed.version", "", "only use the feed instances of this version")
+		profileVer = flag.String("profile.version", "", "only use the profile instances of this version")
+		topicVer   = flag.String("topic.version", "", "only use the topic instances of this version")
+		onlyZone   = flag.String("filter.zone", "", "only use the instances of this zone")
+		zone       = flag.String("zone", "", "the zone of the gateway, requests stay in it while its instances are healthy")
+		feedShadow = flag.String("feed.shadow", "", "the registered name of the shadow feed instances, e.g. feed-shadow")
+		profShadow = flag.String("profile.shadow", "", "the registered name of the shadow profile instances")
+		topShadow  = flag.String("topic.shadow", "", "the registered name of the shadow topic instances")
+		shadowRate = flag.Float64("shadow.sample", 0.01, "the fraction of the read requests mirrored to the shadow instances")
+		profHedge  = flag.Float64("profile.hedge", 0, "hedge the profile reads slower than this latency percentile, e.g. 0.95, 0 disables")
+		topHedge   = flag.Float64("topic.hedge", 0, "hedge the topic reads slower than this latency percentile, 0 disables")
+		hedgeRate  = flag.Float64("hedge.budget", 0.05, "the fraction of the read requests which may be hedged")
 	)
 	flag.Parse()
 	ctx := context.Background()
 	// Logging domain.
-	var logger log.Logger
-	logger = log.NewLogfmtLogger(os.Stderr)
-	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-	logger = log.With(logger, "caller", log.DefaultCaller)
-
-	// Service discovery domain. In this example we use etcd.
-	var sdClient etcd.Client
-	var peers []string
-	if len(*etcdAddr) > 0 {
-		peers = strings.Split(*etcdAddr, ",")
-	}
-	sdClient, err := etcd.NewClient(ctx, peers, etcd.ClientOptions{})
+	logLevel, err := logging.NewLevel(*logLvl)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logging.Redact(strings.Split(*logRedact, ",")...)
+	logger := logging.NewLogger(os.Stderr, logLevel, "apigateway")
+
+	// Service level objectives.
+	if *sloConfig != "" {
+		c, err := slo.Load(*sloConfig)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		slo.Init(c, "apigateway")
+	}
+
+	// Mutual TLS with the services.
+	tlsConfig := mtls.Config{CA: *tlsCA, Cert: *tlsCert, Key: *tlsKey}
+	if err := tlsConfig.CheckMutual(); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	if tlsConfig.Enabled() {
+		r, err := mtls.NewReloader(tlsConfig, logger)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		mtls.InitClient(r)
+	}
+
+	// Service discovery domain, etcd by default.
+	registryAddr := *sdAddr
+	if *sdBackend == discovery.BackendEtcd && registryAddr == "" {
+		registryAddr = *etcdAddr
+	}
+	registry, err := discovery.New(ctx, *sdBackend, registryAddr, logger)
 	if err != nil {
 		logger.Log("err", err)
 		os.Exit(1)
@@ -49,7 +118,22 @@ func main() {
 
 	// Transport domain.
 	tracer := stdopentracing.GlobalTracer() // nop by default
-	if *zipkinAddr != "" {
+	if *otelExp != "" {
+		logger := log.With(logger, "tracer", "OpenTelemetry")
+		logger.Log("exporter", *otelExp, "endpoint", *otelAddr)
+		t, err := telemetry.New(ctx, telemetry.Config{
+			Service:  "apigateway",
+			Exporter: *otelExp,
+			Endpoint: *otelAddr,
+			Sample:   *otelSample,
+		}, logger)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		defer t.Shutdown(context.Background())
+		tracer = t.Tracer
+	} else if *zipkinAddr != "" {
 		logger := log.With(logger, "tracer", "Zipkin")
 		logger.Log("addr", *zipkinAddr)
 		collector, err := zipkin.NewHTTPCollector(
@@ -68,6 +152,12 @@ func main() {
 			os.Exit(1)
 		}
 	}
+	// the spans of the inbound requests are the parents of those of the clients.
+	apigateway.InitTracer(tracer)
+
+	if *adminToken == "" {
+		*adminToken = os.Getenv("ADMIN_TOKEN")
+	}
 
 	// Debug listener.
 	go func() {
@@ -81,19 +171,129 @@ func main() {
 		m.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 		m.Handle("/metrics", stdprometheus.Handler())
 
+		adminLogger := log.With(logger, "component", "admin")
+		m.Handle("/debug/loglevel", admin.Protect(*adminToken, adminLogger, logLevel))
+		admin.Register(m, *adminToken, adminLogger)
+
 		logger.Log("addr", ":6060")
 		http.ListenAndServe(":6060", m)
 	}()
 
-	feed.InitWithSD(sdClient, tracer, logger)
-	profile.InitWithSD(sdClient, tracer, logger)
-	topic.InitWithSD(sdClient, tracer, logger)
+	// Client domain. The feed client always routes on the owner of the feeds.
+	feedOpts, err := clientOptions("", *feedVer, *onlyZone, *zone)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	profileOpts, err := clientOptions(*profileLB, *profileVer, *onlyZone, *zone)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	topicOpts, err := clientOptions(*topicLB, *topicVer, *onlyZone, *zone)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	// The traffic of each service can be split between its versions at
+	// runtime, see /admin/split.
+	splitters := map[string]*balancer.Splitter{
+		"feed":    balancer.NewSplitter(),
+		"profile": balancer.NewSplitter(),
+		"topic":   balancer.NewSplitter(),
+	}
+	feedOpts = append(feedOpts, balancer.WithSplit(splitters["feed"]))
+	profileOpts = append(profileOpts, balancer.WithSplit(splitters["profile"]))
+	topicOpts = append(topicOpts, balancer.WithSplit(splitters["topic"]))
+	apigateway.InitSplit(splitters, *adminToken)
+
+	// Read requests can be mirrored to shadow instances, to validate them.
+	for _, shadow := range []struct {
+		name string
+		opts *[]balancer.Option
+	}{{*feedShadow, &feedOpts}, {*profShadow, &profileOpts}, {*topShadow, &topicOpts}} {
+		if shadow.name == "" {
+			continue
+		}
+		instancer, err := registry.Instancer(shadow.name)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		*shadow.opts = append(*shadow.opts, balancer.WithShadow(balancer.Shadow{Instancer: instancer, Sample: *shadowRate}))
+	}
+	// Slow reads can be hedged to a second instance. The feed requests are
+	// routed to the owner of the feeds, they are never hedged.
+	if *profHedge > 0 {
+		profileOpts = append(profileOpts, balancer.WithHedge(balancer.Hedge{Percentile: *profHedge, Budget: *hedgeRate}))
+	}
+	if *topHedge > 0 {
+		topicOpts = append(topicOpts, balancer.WithHedge(balancer.Hedge{Percentile: *topHedge, Budget: *hedgeRate}))
+	}
+
+	if err = feed.InitWithSD(registry, tracer, logger, feedOpts...); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	if err = profile.InitWithSD(registry, tracer, logger, profileOpts...); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	if err = topic.InitWithSD(registry, tracer, logger, topicOpts...); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+
+	// Media domain.
+	store, err := media.NewLocalStore(*mediaDir)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	apigateway.InitMedia(store, *mediaMax)
+
+	// Review queue of the moderators.
+	if *modToken == "" {
+		*modToken = os.Getenv("MODERATION_TOKEN")
+	}
+	apigateway.InitModeration(*modToken)
 
 	router := gin.New()
+	router.Use(apigateway.AccessLog(logger, *accessRate, *accessSlow))
 	apigateway.Register(router)
 
 	server := &http.Server{Addr: *httpAddr, Handler: router}
+	if c := (mtls.Config{Cert: *httpCert, Key: *httpKey}); c.Enabled() {
+		r, err := mtls.NewReloader(c, logger)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		// gracehttp serves TLS when the config is set.
+		server.TLSConfig = r.ServerConfig(nil)
+	}
 	if err = gracehttp.Serve(server); err != nil {
 		panic(err)
 	}
 }
+
+// clientOptions returns the balancer options of a client: its strategy, the
+// instances it may use, empty version and onlyZone accept any, and the zone
+// it prefers.
+func clientOptions(strategy, version, onlyZone, zone string) ([]balancer.Option, error) {
+	opt, err := balancer.Parse(strategy)
+	if err != nil {
+		return nil, err
+	}
+	opts := []balancer.Option{opt}
+	if version != "" {
+		opts = append(opts, balancer.WithFilter(discovery.Version(version)))
+	}
+	if onlyZone != "" {
+		opts = append(opts, balancer.WithFilter(discovery.Zone(onlyZone)))
+	}
+	if zone != "" {
+		opts = append(opts, balancer.PreferZone(zone))
+	}
+	return opts, nil
+}
@@ -0,0 +1,37 @@
+// Command devca generates a throwaway CA and the certificates of the
+// gateway and the services, to try TLS and mutual TLS locally.
+//
+//	$ go run ./cmd/devca -dir certs
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/buptmiao/microservice-app/mtls"
+)
+
+func main() {
+	var (
+		dir        = flag.String("dir", "certs", "the directory the PEM files are written to")
+		identities = flag.String("identities", "apigateway,feed,profile,topic", "comma separated identities a certificate is issued for")
+		validity   = flag.Duration("validity", 30*24*time.Hour, "the validity of the issued certificates")
+	)
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ca, err := mtls.NewDevCA()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := ca.WriteFiles(*dir, *validity, mtls.Peers(*identities)...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
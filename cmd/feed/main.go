@@ -4,10 +4,20 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"github.com/buptmiao/microservice-app/admin"
+	"github.com/buptmiao/microservice-app/authz"
+	"github.com/buptmiao/microservice-app/client/balancer"
+	"github.com/buptmiao/microservice-app/client/topic"
+	"github.com/buptmiao/microservice-app/discovery"
 	"github.com/buptmiao/microservice-app/feed"
+	"github.com/buptmiao/microservice-app/logging"
+	"github.com/buptmiao/microservice-app/moderation"
+	"github.com/buptmiao/microservice-app/mtls"
 	p_feed "github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/buptmiao/microservice-app/slo"
+	"github.com/buptmiao/microservice-app/telemetry"
 	"github.com/go-kit/kit/log"
-	"github.com/go-kit/kit/sd/etcd"
+	"github.com/go-kit/kit/log/level"
 	stdopentracing "github.com/opentracing/opentracing-go"
 	zipkin "github.com/openzipkin/zipkin-go-opentracing"
 	stdprometheus "github.com/prometheus/client_golang/prometheus"
@@ -27,38 +37,115 @@ func main() {
 		addr       = flag.String("addr", ":8082", "the microservices grpc address")
 		debugAddr  = flag.String("debug.addr", ":6062", "the debug and metrics address")
 		etcdAddr   = flag.String("etcd.addr", "", "etcd registry address")
+		sdBackend  = flag.String("discovery", "etcd", "the service registry: etcd, consul, dns or static")
+		sdAddr     = flag.String("discovery.addr", "", "the registry address, -etcd.addr is used for etcd when empty")
+		sdName     = flag.String("discovery.name", "feed", "the service name registered, e.g. feed-shadow for shadow instances")
 		zipkinAddr = flag.String("zipkin.addr", "", "the zipkin address")
+		otelExp    = flag.String("otel.exporter", "", "export the traces and metrics with OpenTelemetry: otlp, stdout or file, it replaces zipkin")
+		otelAddr   = flag.String("otel.endpoint", "", "the OTLP/HTTP collector address, OTEL_EXPORTER_OTLP_ENDPOINT by default, or the path of the file exporter")
+		otelSample = flag.Float64("otel.sample", 1, "the fraction of the new traces recorded, the others follow the decision of their caller")
+		logLvl     = flag.String("log.level", "info", "the minimum level logged: debug, info, warn or error, it can be changed at /debug/loglevel with the admin token")
+		logRedact  = flag.String("log.redact", "", "comma separated payload fields never logged, on top of password, token, secret, authorization, email and phone")
+		sloConfig  = flag.String("slo.config", "", "the JSON file declaring the service level objectives, e.g. monitor/slo.json")
+		adminToken = flag.String("admin.token", "", "the token of the admin actions on the debug address, ADMIN_TOKEN by default, they are disabled without one")
+		tlsCA      = flag.String("tls.ca", "", "the CA bundle the peers are verified with, mutual TLS is on with -tls.cert and -tls.key")
+		tlsCert    = flag.String("tls.cert", "", "the certificate of this instance, its first DNS name being the service, it is reloaded on change")
+		tlsKey     = flag.String("tls.key", "", "the private key of -tls.cert")
+		tlsPeers   = flag.String("tls.peers", "apigateway", "comma separated identities of the clients allowed with mutual TLS, any signed by -tls.ca when empty")
+		authzPath  = flag.String("authz.policy", "", "the JSON file of the callers allowed for each method, e.g. authz/policy.json, it requires mutual TLS, every caller is allowed when empty")
+		advertise  = flag.String("advertise.addr", "", "the address registered for the other hosts, by default the local IP and the port of -addr")
+		version    = flag.String("version", "dev", "the version registered for this instance")
+		zone       = flag.String("zone", "", "the zone registered for this instance")
+		weight     = flag.Int("weight", 1, "the share of requests sent to this instance by weighted balancers")
+		topicLB    = flag.String("topic.balancer", "roundrobin", "the topic client balancer: roundrobin, hash, p2c or weighted")
+		modPolicy  = flag.String("moderation.policy", "", "the JSON file of the moderation chain of the new feeds, e.g. moderation/policy.json, every feed is allowed when empty")
+		outboxTick = flag.Duration("outbox.interval", 500*time.Millisecond, "the outbox relay polling interval")
 	)
 	flag.Parse()
-	key := "/services/feed/" + *addr
-	value := *addr
 	ctx := context.Background()
 	// logger
-	var logger log.Logger
-	logger = log.NewLogfmtLogger(os.Stdout)
-
-	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
-	logger = log.With(logger, "caller", log.DefaultCaller)
-	logger = log.With(logger, "service", "feed")
-
-	// Service registrar domain. In this example we use etcd.
-	var sdClient etcd.Client
-	var peers []string
-	if len(*etcdAddr) > 0 {
-		peers = strings.Split(*etcdAddr, ",")
+	logLevel, err := logging.NewLevel(*logLvl)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logging.Redact(strings.Split(*logRedact, ",")...)
+	logger := logging.NewLogger(os.Stdout, logLevel, "feed")
+
+	// Service level objectives.
+	if *sloConfig != "" {
+		c, err := slo.Load(*sloConfig)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		slo.Init(c, "feed")
+	}
+
+	// Mutual TLS with the clients, and with the services called.
+	var serverOpts []grpc.ServerOption
+	tlsConfig := mtls.Config{CA: *tlsCA, Cert: *tlsCert, Key: *tlsKey}
+	if err := tlsConfig.CheckMutual(); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	if tlsConfig.Enabled() {
+		r, err := mtls.NewReloader(tlsConfig, logger)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		serverOpts = append(serverOpts, mtls.ServerOption(r, mtls.Peers(*tlsPeers)))
+		mtls.InitClient(r)
+	}
+	// Authorization of the callers, identified by their certificate.
+	if *authzPath != "" {
+		policy, err := authz.Load(*authzPath)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		// without mutual TLS every caller is anonymous, and denied.
+		if !tlsConfig.Enabled() {
+			if !policy.DryRun {
+				logger.Log("err", "-authz.policy requires mutual TLS, set -tls.ca, -tls.cert and -tls.key")
+				os.Exit(1)
+			}
+			level.Warn(logger).Log("authz", "every caller is anonymous without mutual TLS")
+		}
+		serverOpts = append(serverOpts, grpc.UnaryInterceptor(authz.UnaryServerInterceptor(policy, "feed", log.With(logger, "component", "authz"))))
+	}
+
+	// Service registrar domain, etcd by default.
+	registryAddr := *sdAddr
+	if *sdBackend == discovery.BackendEtcd && registryAddr == "" {
+		registryAddr = *etcdAddr
 	}
-	sdClient, err := etcd.NewClient(ctx, peers, etcd.ClientOptions{})
+	registry, err := discovery.New(ctx, *sdBackend, registryAddr, logger)
 	if err != nil {
 		logger.Log("err", err)
 		os.Exit(1)
 	}
 
-	// Build the registrar.
-	registrar := etcd.NewRegistrar(sdClient, etcd.Service{
-		Key:   key,
-		Value: value,
-		TTL:   etcd.NewTTLOption(time.Second, time.Second*3),
-	}, log.NewNopLogger())
+	// Build the registrar. The value describes the instance to the clients.
+	advertised, err := discovery.AdvertisedAddr(*addr, *advertise)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	instance := discovery.Instance{
+		Addr:      advertised,
+		Version:   *version,
+		Zone:      *zone,
+		Weight:    *weight,
+		Protocol:  discovery.ProtocolGRPC,
+		StartTime: time.Now(),
+	}
+	registrar, err := registry.Registrar(*sdName, instance)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
 
 	// Register our instance.
 	registrar.Register()
@@ -66,7 +153,22 @@ func main() {
 	defer registrar.Deregister()
 
 	tracer := stdopentracing.GlobalTracer() // nop by default
-	if *zipkinAddr != "" {
+	if *otelExp != "" {
+		logger := log.With(logger, "tracer", "OpenTelemetry")
+		logger.Log("exporter", *otelExp, "endpoint", *otelAddr)
+		t, err := telemetry.New(ctx, telemetry.Config{
+			Service:  "feed",
+			Exporter: *otelExp,
+			Endpoint: *otelAddr,
+			Sample:   *otelSample,
+		}, logger)
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+		defer t.Shutdown(context.Background())
+		tracer = t.Tracer
+	} else if *zipkinAddr != "" {
 		logger := log.With(logger, "tracer", "Zipkin")
 		logger.Log("addr", *zipkinAddr)
 		collector, err := zipkin.NewHTTPCollector(
@@ -86,7 +188,34 @@ func main() {
 		}
 	}
 
-	service := feed.NewFeedService()
+	// Topic client, used to link the hashtags of new posts to topics.
+	topicBalancer, err := balancer.Parse(*topicLB)
+	if err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+	if err = topic.InitWithSD(registry, tracer, logger, topicBalancer, balancer.PreferZone(*zone)); err != nil {
+		logger.Log("err", err)
+		os.Exit(1)
+	}
+
+	// Moderation of the new feeds, the held ones wait for review.
+	var chain *moderation.Chain
+	if *modPolicy != "" {
+		c, err := moderation.Load(*modPolicy)
+		if err == nil {
+			chain, err = c.Chain()
+		}
+		if err != nil {
+			logger.Log("err", err)
+			os.Exit(1)
+		}
+	}
+	service := feed.NewFeedService(topic.GetClient(), chain, log.With(logger, "component", "moderation"))
+
+	// Outbox relay, publishes the events written together with the feeds.
+	relay := feed.NewRelay(feed.NewLogPublisher(log.With(logger, "component", "outbox")), *outboxTick, logger)
+	go relay.Run()
 
 	errchan := make(chan error)
 
@@ -103,7 +232,7 @@ func main() {
 	}
 
 	srv := feed.MakeGRPCServer(service, tracer, logger)
-	s := grpc.NewServer()
+	s := grpc.NewServer(serverOpts...)
 	p_feed.RegisterFeedServer(s, srv)
 
 	go func() {
@@ -124,10 +253,20 @@ func main() {
 		m.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 		m.Handle("/metrics", stdprometheus.Handler())
 
+		token := *adminToken
+		if token == "" {
+			token = os.Getenv("ADMIN_TOKEN")
+		}
+		adminLogger := log.With(logger, "component", "admin")
+		m.Handle("/debug/loglevel", admin.Protect(token, adminLogger, logLevel))
+		admin.Register(m, token, adminLogger)
+
 		logger.Log("addr", *debugAddr)
 		errchan <- http.ListenAndServe(*debugAddr, m)
 	}()
 
 	logger.Log("graceful shutdown...", <-errchan)
 	s.GracefulStop()
+	// publish the events of the last requests.
+	relay.Stop()
 }
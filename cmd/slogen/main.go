@@ -0,0 +1,48 @@
+// Command slogen generates the Prometheus rules alerting on the error budget
+// burn rate of the service level objectives, and their Grafana dashboard.
+//
+//	$ go run ./cmd/slogen -config monitor/slo.json
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/buptmiao/microservice-app/slo"
+)
+
+func main() {
+	var (
+		config    = flag.String("config", "monitor/slo.json", "the JSON file declaring the service level objectives")
+		rules     = flag.String("rules", "monitor/prometheus/slo.rules", "the Prometheus rules file written")
+		dashboard = flag.String("dashboard", "monitor/grafana/slo.json", "the Grafana dashboard file written")
+	)
+	flag.Parse()
+
+	if err := generate(*config, *rules, *dashboard); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(config, rules, dashboard string) error {
+	c, err := slo.Load(config)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := slo.WriteRules(&buf, c); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(rules, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	b, err := slo.Dashboard(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dashboard, append(b, '\n'), 0644)
+}
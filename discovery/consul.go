@@ -0,0 +1,133 @@
+package discovery
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/consul"
+	stdconsul "github.com/hashicorp/consul/api"
+	"golang.org/x/net/context"
+)
+
+// metaInstance is the key of the service meta holding the Instance.
+const metaInstance = "instance"
+
+// The agent dials every instance it registered, and removes those which stay
+// unreachable, e.g. after a crash which skipped the deregistration.
+const (
+	checkInterval   = "10s"
+	checkTimeout    = "2s"
+	deregisterAfter = "1m"
+)
+
+type consulRegistry struct {
+	client consul.Client
+	logger log.Logger
+}
+
+// NewConsul returns a registry backed by the Consul agent at addr.
+func NewConsul(addr string, logger log.Logger) (Registry, error) {
+	config := stdconsul.DefaultConfig()
+	if addr != "" {
+		config.Address = addr
+	}
+	client, err := stdconsul.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &consulRegistry{client: consul.NewClient(client), logger: logger}, nil
+}
+
+func (r *consulRegistry) Registrar(service string, instance Instance) (sd.Registrar, error) {
+	host, port, err := net.SplitHostPort(instance.Addr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+	return consul.NewRegistrar(r.client, &stdconsul.AgentServiceRegistration{
+		ID:      service + "-" + instance.Addr,
+		Name:    service,
+		Address: host,
+		Port:    p,
+		Meta:    map[string]string{metaInstance: instance.Encode()},
+		Check: &stdconsul.AgentServiceCheck{
+			TCP:                            instance.Addr,
+			Interval:                       checkInterval,
+			Timeout:                        checkTimeout,
+			DeregisterCriticalServiceAfter: deregisterAfter,
+		},
+	}, log.NewNopLogger()), nil
+}
+
+func (r *consulRegistry) Instancer(service string) (sd.Instancer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := &consulInstancer{
+		broadcaster: newBroadcaster(),
+		client:      r.client,
+		service:     service,
+		logger:      r.logger,
+		cancel:      cancel,
+	}
+	go in.loop(ctx)
+	return in, nil
+}
+
+// consulInstancer watches the healthy instances of a service with blocking
+// queries. Unlike the go-kit one, it yields the registered Instance rather
+// than the bare address.
+type consulInstancer struct {
+	*broadcaster
+	client  consul.Client
+	service string
+	logger  log.Logger
+	cancel  context.CancelFunc
+}
+
+func (in *consulInstancer) loop(ctx context.Context) {
+	var index uint64
+	backoff := 10 * time.Millisecond
+	for ctx.Err() == nil {
+		opts := (&stdconsul.QueryOptions{WaitIndex: index}).WithContext(ctx)
+		entries, meta, err := in.client.Service(in.service, "", true, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			in.logger.Log("service", in.service, "err", err)
+			in.update(sd.Event{Err: err})
+			time.Sleep(backoff)
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = 10 * time.Millisecond
+		index = meta.LastIndex
+
+		instances := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if value, ok := entry.Service.Meta[metaInstance]; ok {
+				instances = append(instances, value)
+				continue
+			}
+			// registered by something else than the app.
+			addr := entry.Service.Address
+			if addr == "" {
+				addr = entry.Node.Address
+			}
+			instances = append(instances, net.JoinHostPort(addr, strconv.Itoa(entry.Service.Port)))
+		}
+		in.update(sd.Event{Instances: instances})
+	}
+}
+
+// Stop ends the watch.
+func (in *consulInstancer) Stop() {
+	in.cancel()
+}
@@ -0,0 +1,33 @@
+package discovery
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/dnssrv"
+)
+
+// dnsTTL is how often the SRV records are resolved again.
+const dnsTTL = 5 * time.Second
+
+type dnsRegistry struct {
+	domain string
+	logger log.Logger
+}
+
+// NewDNS returns a registry resolving the SRV records _<service>._tcp.<domain>,
+// as published by Kubernetes headless services or Consul. The records are
+// managed outside the app, registering is a no-op, and the instances carry
+// no metadata.
+func NewDNS(domain string, logger log.Logger) Registry {
+	return &dnsRegistry{domain: domain, logger: logger}
+}
+
+func (r *dnsRegistry) Registrar(string, Instance) (sd.Registrar, error) {
+	return nopRegistrar{}, nil
+}
+
+func (r *dnsRegistry) Instancer(service string) (sd.Instancer, error) {
+	return dnssrv.NewInstancer("_"+service+"._tcp."+r.domain, dnsTTL, r.logger), nil
+}
@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/etcd"
+	"golang.org/x/net/context"
+)
+
+type etcdRegistry struct {
+	client etcd.Client
+	logger log.Logger
+}
+
+// NewEtcd returns a registry storing the instances under
+// /services/<service>/<addr> in etcd, peers is comma separated.
+func NewEtcd(ctx context.Context, peers string, logger log.Logger) (Registry, error) {
+	var machines []string
+	if len(peers) > 0 {
+		machines = strings.Split(peers, ",")
+	}
+	client, err := etcd.NewClient(ctx, machines, etcd.ClientOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &etcdRegistry{client: client, logger: logger}, nil
+}
+
+func prefix(service string) string {
+	return "/services/" + service + "/"
+}
+
+// Registrar keeps the key alive while the instance runs, so that a crashed
+// instance disappears after a few seconds.
+func (r *etcdRegistry) Registrar(service string, instance Instance) (sd.Registrar, error) {
+	return etcd.NewRegistrar(r.client, etcd.Service{
+		Key:   prefix(service) + instance.Addr,
+		Value: instance.Encode(),
+		TTL:   etcd.NewTTLOption(time.Second, time.Second*3),
+	}, log.NewNopLogger()), nil
+}
+
+func (r *etcdRegistry) Instancer(service string) (sd.Instancer, error) {
+	return etcd.NewInstancer(r.client, prefix(service), r.logger)
+}
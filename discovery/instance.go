@@ -0,0 +1,81 @@
+// Package discovery describes the instances the services register, so that
+// the clients can tell them apart.
+package discovery
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/buptmiao/microservice-app/util"
+)
+
+// ProtocolGRPC is the protocol of every service of the app.
+const ProtocolGRPC = "grpc"
+
+// Instance is the metadata registered for one instance of a service.
+type Instance struct {
+	// Addr is the host:port the other hosts dial.
+	Addr      string    `json:"addr"`
+	Version   string    `json:"version,omitempty"`
+	Zone      string    `json:"zone,omitempty"`
+	Weight    int       `json:"weight"`
+	Protocol  string    `json:"protocol"`
+	StartTime time.Time `json:"start_time"`
+}
+
+// Encode returns the registration value of the instance.
+func (i Instance) Encode() string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+// Parse decodes a registration value. Values which are not JSON are plain
+// addresses, as registered before the metadata existed.
+func Parse(value string) (Instance, error) {
+	inst := Instance{Addr: value}
+	if strings.HasPrefix(value, "{") {
+		inst = Instance{}
+		if err := json.Unmarshal([]byte(value), &inst); err != nil {
+			return Instance{}, err
+		}
+	}
+	if inst.Weight <= 0 {
+		inst.Weight = 1
+	}
+	if inst.Protocol == "" {
+		inst.Protocol = ProtocolGRPC
+	}
+	return inst, nil
+}
+
+// AdvertisedAddr returns the address to register for a service listening on
+// listen. advertise wins when set, otherwise a listen address without host,
+// such as ":8082", gets the IP of the local host.
+func AdvertisedAddr(listen, advertise string) (string, error) {
+	if advertise != "" {
+		return advertise, nil
+	}
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = util.GetLocalIP()
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Filter selects the instances a client may use.
+type Filter func(Instance) bool
+
+// Version keeps the instances of the given version.
+func Version(version string) Filter {
+	return func(i Instance) bool { return i.Version == version }
+}
+
+// Zone keeps the instances of the given zone.
+func Zone(zone string) Filter {
+	return func(i Instance) bool { return i.Zone == zone }
+}
@@ -0,0 +1,70 @@
+package discovery_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/buptmiao/microservice-app/discovery"
+	"github.com/buptmiao/microservice-app/util"
+)
+
+func TestParse(t *testing.T) {
+	start := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	full := discovery.Instance{Addr: "10.0.0.1:8082", Version: "1.2.0", Zone: "us-east-1a", Weight: 3, Protocol: "grpc", StartTime: start}
+
+	for _, c := range []struct {
+		value string
+		want  discovery.Instance
+	}{
+		// plain addresses registered before the metadata existed.
+		{"10.0.0.1:8082", discovery.Instance{Addr: "10.0.0.1:8082", Weight: 1, Protocol: discovery.ProtocolGRPC}},
+		{full.Encode(), full},
+		// a missing or invalid weight counts as 1, a missing protocol is gRPC.
+		{`{"addr":"10.0.0.1:8082"}`, discovery.Instance{Addr: "10.0.0.1:8082", Weight: 1, Protocol: discovery.ProtocolGRPC}},
+		{`{"addr":"10.0.0.1:8082","weight":-2,"protocol":""}`, discovery.Instance{Addr: "10.0.0.1:8082", Weight: 1, Protocol: discovery.ProtocolGRPC}},
+		// fields of newer versions are ignored.
+		{`{"addr":"10.0.0.1:8082","weight":2,"region":"us-east"}`, discovery.Instance{Addr: "10.0.0.1:8082", Weight: 2, Protocol: discovery.ProtocolGRPC}},
+	} {
+		got, err := discovery.Parse(c.value)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", c.value, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Parse(%s) = %+v, want %+v", c.value, got, c.want)
+		}
+	}
+
+	for _, value := range []string{`{"addr":`, `{"weight":"heavy"}`, `{"start_time":"yesterday"}`} {
+		if _, err := discovery.Parse(value); err == nil {
+			t.Errorf("Parse(%s) should fail", value)
+		}
+	}
+}
+
+func TestAdvertisedAddr(t *testing.T) {
+	local := util.GetLocalIP()
+	for _, c := range []struct {
+		listen, advertise, want string
+	}{
+		{":8082", "feed.example:9000", "feed.example:9000"},
+		{"10.0.0.1:8082", "", "10.0.0.1:8082"},
+		{"localhost:8082", "", "localhost:8082"},
+		{":8082", "", net.JoinHostPort(local, "8082")},
+		{"0.0.0.0:8082", "", net.JoinHostPort(local, "8082")},
+		{"[::]:8082", "", net.JoinHostPort(local, "8082")},
+	} {
+		got, err := discovery.AdvertisedAddr(c.listen, c.advertise)
+		if err != nil {
+			t.Errorf("AdvertisedAddr(%q, %q): %v", c.listen, c.advertise, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("AdvertisedAddr(%q, %q) = %s, want %s", c.listen, c.advertise, got, c.want)
+		}
+	}
+	if _, err := discovery.AdvertisedAddr("8082", ""); err == nil {
+		t.Error("a listen address without port should fail")
+	}
+}
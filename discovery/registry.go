@@ -0,0 +1,152 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	"golang.org/x/net/context"
+)
+
+// Names of the registry backends, as accepted by New.
+const (
+	BackendEtcd   = "etcd"
+	BackendConsul = "consul"
+	BackendDNS    = "dns"
+	BackendStatic = "static"
+)
+
+// Registry is where the services announce their instances and where the
+// clients watch them. Instancers yield registration values, see Parse.
+type Registry interface {
+	Registrar(service string, instance Instance) (sd.Registrar, error)
+	Instancer(service string) (sd.Instancer, error)
+}
+
+// New returns the registry backend called name, addr is its address:
+//
+//	etcd     comma separated peers, e.g. http://etcd:2379
+//	consul   the agent, e.g. localhost:8500
+//	dns      the domain of the SRV records, e.g. service.consul
+//	static   the instances of each service, e.g. feed=host:8082,host:8092;topic=host:8084
+func New(ctx context.Context, name, addr string, logger log.Logger) (Registry, error) {
+	switch name {
+	case BackendEtcd:
+		return NewEtcd(ctx, addr, logger)
+	case BackendConsul:
+		return NewConsul(addr, logger)
+	case BackendDNS:
+		return NewDNS(addr, logger), nil
+	case BackendStatic:
+		return NewStatic(addr)
+	}
+	return nil, fmt.Errorf("unknown discovery backend %q", name)
+}
+
+// nopRegistrar is the registrar of the registries managed outside the app.
+type nopRegistrar struct{}
+
+func (nopRegistrar) Register()   {}
+func (nopRegistrar) Deregister() {}
+
+type static map[string][]string
+
+// NewStatic returns a registry with a fixed list of instances per service,
+// to run the stack without any registry. Registering is a no-op.
+func NewStatic(list string) (Registry, error) {
+	s := static{}
+	for _, entry := range strings.Split(list, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid static instances %q, want service=host:port,...", entry)
+		}
+		service := strings.TrimSpace(kv[0])
+		for _, addr := range strings.Split(kv[1], ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				s[service] = append(s[service], addr)
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s static) Registrar(string, Instance) (sd.Registrar, error) {
+	return nopRegistrar{}, nil
+}
+
+func (s static) Instancer(service string) (sd.Instancer, error) {
+	return sd.FixedInstancer(s[service]), nil
+}
+
+// broadcaster sends the last state of an instancer to its subscribers.
+type broadcaster struct {
+	mu    sync.Mutex
+	state sd.Event
+	// version counts the changes of state.
+	version uint64
+	subs    map[chan<- sd.Event]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: make(map[chan<- sd.Event]struct{})}
+}
+
+// update notifies the subscribers if the state changed.
+func (b *broadcaster) update(event sd.Event) {
+	sort.Strings(event.Instances)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if event.Err == nil && b.state.Err == nil && equal(event.Instances, b.state.Instances) {
+		return
+	}
+	b.state = event
+	b.version++
+	for ch := range b.subs {
+		ch <- event
+	}
+}
+
+// Register implements sd.Instancer. The current state is sent without the
+// lock, as the subscriber may not read ch until Register returns, and sent
+// again if it changed meanwhile, so that ch never misses the last state.
+func (b *broadcaster) Register(ch chan<- sd.Event) {
+	for {
+		b.mu.Lock()
+		state, version := b.state, b.version
+		b.mu.Unlock()
+		ch <- state
+
+		b.mu.Lock()
+		if b.version == version {
+			b.subs[ch] = struct{}{}
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Deregister implements sd.Instancer.
+func (b *broadcaster) Deregister(ch chan<- sd.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, ch)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
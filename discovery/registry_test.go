@@ -0,0 +1,166 @@
+package discovery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/sd"
+	stdconsul "github.com/hashicorp/consul/api"
+)
+
+func TestStatic(t *testing.T) {
+	r, err := NewStatic(" feed = a:8082, b:8082 ;topic=c:8084;;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for service, want := range map[string][]string{
+		"feed":    {"a:8082", "b:8082"},
+		"topic":   {"c:8084"},
+		"profile": nil,
+	} {
+		in, _ := r.Instancer(service)
+		got, _ := in.(sd.FixedInstancer)
+		if !reflect.DeepEqual([]string(got), want) {
+			t.Errorf("%s: got %v, want %v", service, got, want)
+		}
+	}
+	if _, err := NewStatic("feed:a:8082"); err == nil {
+		t.Error("an entry without service should fail")
+	}
+}
+
+func receive(t *testing.T, ch <-chan sd.Event) sd.Event {
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+	return sd.Event{}
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := newBroadcaster()
+	b.update(sd.Event{Instances: []string{"b", "a"}})
+
+	// a subscriber which does not read yet does not block the others.
+	slow := make(chan sd.Event)
+	go b.Register(slow)
+	ch := make(chan sd.Event, 4)
+	done := make(chan struct{})
+	go func() {
+		b.Register(ch)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Register blocked behind a slow subscriber")
+	}
+	if e := receive(t, ch); !reflect.DeepEqual(e.Instances, []string{"a", "b"}) {
+		t.Fatalf("got %v, want the sorted state", e.Instances)
+	}
+
+	go b.update(sd.Event{Instances: []string{"a", "c"}})
+	// slow gets the state it was registered with, then the new one if it
+	// changed meanwhile: either way it ends with the last state.
+	e := receive(t, slow)
+	if reflect.DeepEqual(e.Instances, []string{"a", "b"}) {
+		e = receive(t, slow)
+	}
+	if !reflect.DeepEqual(e.Instances, []string{"a", "c"}) {
+		t.Fatalf("slow subscriber got %v, want the last state", e.Instances)
+	}
+	if e := receive(t, ch); !reflect.DeepEqual(e.Instances, []string{"a", "c"}) {
+		t.Fatalf("got %v", e.Instances)
+	}
+
+	// unchanged states are not sent again, errors always are.
+	b.Deregister(slow)
+	b.update(sd.Event{Instances: []string{"c", "a"}})
+	b.update(sd.Event{Err: errors.New("down")})
+	if e := receive(t, ch); e.Err == nil {
+		t.Fatalf("got %v, want the error", e)
+	}
+	b.Deregister(ch)
+	b.update(sd.Event{Instances: []string{"d"}})
+	if len(ch) != 0 {
+		t.Fatal("deregistered subscriber still notified")
+	}
+}
+
+type consulReply struct {
+	entries []*stdconsul.ServiceEntry
+	index   uint64
+	err     error
+}
+
+// fakeConsul answers the blocking queries with the replies sent to it.
+type fakeConsul struct {
+	registered *stdconsul.AgentServiceRegistration
+	replies    chan consulReply
+	indexes    chan uint64
+}
+
+func (c *fakeConsul) Register(r *stdconsul.AgentServiceRegistration) error {
+	c.registered = r
+	return nil
+}
+
+func (c *fakeConsul) Deregister(*stdconsul.AgentServiceRegistration) error {
+	c.registered = nil
+	return nil
+}
+
+func (c *fakeConsul) Service(_, _ string, _ bool, opts *stdconsul.QueryOptions) ([]*stdconsul.ServiceEntry, *stdconsul.QueryMeta, error) {
+	c.indexes <- opts.WaitIndex
+	select {
+	case r := <-c.replies:
+		return r.entries, &stdconsul.QueryMeta{LastIndex: r.index}, r.err
+	case <-opts.Context().Done():
+		return nil, nil, opts.Context().Err()
+	}
+}
+
+func TestConsul(t *testing.T) {
+	client := &fakeConsul{replies: make(chan consulReply), indexes: make(chan uint64, 8)}
+	r := &consulRegistry{client: client, logger: log.NewNopLogger()}
+
+	registrar, err := r.Registrar("feed", Instance{Addr: "10.0.0.1:8082"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registrar.Register()
+	check := client.registered.Check
+	if check == nil || check.TCP != "10.0.0.1:8082" || check.DeregisterCriticalServiceAfter == "" {
+		t.Fatalf("registered without a health check: %+v", check)
+	}
+
+	in, _ := r.Instancer("feed")
+	defer in.(*consulInstancer).Stop()
+	ch := make(chan sd.Event, 4)
+	in.Register(ch)
+	receive(t, ch)
+
+	<-client.indexes
+	client.replies <- consulReply{err: errors.New("agent down")}
+	if e := receive(t, ch); e.Err == nil {
+		t.Fatalf("got %v, want the error", e)
+	}
+	<-client.indexes
+	client.replies <- consulReply{index: 7, entries: []*stdconsul.ServiceEntry{
+		{Service: &stdconsul.AgentService{Meta: map[string]string{metaInstance: `{"addr":"10.0.0.1:8082"}`}}},
+		// registered by something else than the app.
+		{Node: &stdconsul.Node{Address: "10.0.0.2"}, Service: &stdconsul.AgentService{Port: 8082}},
+	}}
+	want := []string{"10.0.0.2:8082", `{"addr":"10.0.0.1:8082"}`}
+	if e := receive(t, ch); !reflect.DeepEqual(e.Instances, want) {
+		t.Fatalf("got %v, want %v", e.Instances, want)
+	}
+	if index := <-client.indexes; index != 7 {
+		t.Fatalf("the next query waits on index %d, want 7", index)
+	}
+}
@@ -2,10 +2,15 @@ package feed
 
 import (
 	"context"
-	"fmt"
+	"github.com/buptmiao/microservice-app/limit"
+	"github.com/buptmiao/microservice-app/logging"
 	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/buptmiao/microservice-app/slo"
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/buptmiao/microservice-app/validate"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/kit/tracing/opentracing"
@@ -17,11 +22,27 @@ import (
 )
 
 var (
-	duration metrics.Histogram = prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+	duration metrics.Histogram = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
 		Namespace: "feed",
-		Name:      "request_duration_ns",
-		Help:      "Request duration in nanoseconds.",
-	}, []string{"method", "success"})
+		Name:      "request_duration_seconds",
+		Help:      "Request duration in seconds, by method and gRPC code.",
+		Buckets:   util.LatencyBuckets,
+	}, []string{"method", "code"})
+	requests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "requests_total",
+		Help:      "Number of requests by method and gRPC code, the shed ones included.",
+	}, []string{"method", "code"})
+	concurrencyLimit metrics.Gauge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "feed",
+		Name:      "concurrency_limit",
+		Help:      "Current adaptive limit of concurrent requests.",
+	}, []string{})
+	shedRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "feed",
+		Name:      "shed_requests_total",
+		Help:      "Requests rejected by the concurrency limit.",
+	}, []string{"method", "priority"})
 )
 
 func EndpointInstrumentingMiddleware(duration metrics.Histogram) endpoint.Middleware {
@@ -29,19 +50,41 @@ func EndpointInstrumentingMiddleware(duration metrics.Histogram) endpoint.Middle
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				duration.With("success", fmt.Sprint(err == nil)).Observe(time.Since(begin).Seconds())
+				duration.With("code", util.Code(err)).Observe(time.Since(begin).Seconds())
 			}(time.Now())
 			return next(ctx, request)
 		}
 	}
 }
 
+// EndpointCountingMiddleware counts the requests by gRPC code. It wraps the
+// concurrency limit, so that the shed requests are counted too.
+func EndpointCountingMiddleware(requests metrics.Counter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func() {
+				requests.With("code", util.Code(err)).Add(1)
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// EndpointLoggingMiddleware logs every request with the trace and request
+// ids of its context, failures as errors. The payloads are logged at the
+// debug level, redacted.
 func EndpointLoggingMiddleware(logger log.Logger) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				logger.Log("error", err, "took", time.Since(begin))
+				logger := logging.With(ctx, logger)
+				if err != nil {
+					level.Error(logger).Log("error", err, "took", time.Since(begin))
+				} else {
+					level.Info(logger).Log("took", time.Since(begin))
+				}
+				level.Debug(logger).Log("request", logging.Payload(request), "response", logging.Payload(response))
 			}(time.Now())
 			return next(ctx, request)
 		}
@@ -74,31 +117,259 @@ func MakeCreateFeedEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, log
 	return ep
 }
 
+func MakeSearchFeedsEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.SearchFeedsRequest)
+		return s.SearchFeeds(ctx, req)
+	}
+	epduration := duration.With("method", "SearchFeeds")
+	eplog := log.With(logger, "method", "SearchFeeds")
+	ep = opentracing.TraceServer(tracer, "SearchFeeds")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeGetFeedsByTopicEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.GetFeedsByTopicRequest)
+		return s.GetFeedsByTopic(ctx, req)
+	}
+	epduration := duration.With("method", "GetFeedsByTopic")
+	eplog := log.With(logger, "method", "GetFeedsByTopic")
+	ep = opentracing.TraceServer(tracer, "GetFeedsByTopic")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeUpdateFeedEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.UpdateFeedRequest)
+		return s.UpdateFeed(ctx, req)
+	}
+	epduration := duration.With("method", "UpdateFeed")
+	eplog := log.With(logger, "method", "UpdateFeed")
+	ep = opentracing.TraceServer(tracer, "UpdateFeed")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeDeleteFeedEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.DeleteFeedRequest)
+		return s.DeleteFeed(ctx, req)
+	}
+	epduration := duration.With("method", "DeleteFeed")
+	eplog := log.With(logger, "method", "DeleteFeed")
+	ep = opentracing.TraceServer(tracer, "DeleteFeed")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeGetFeedHistoryEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.GetFeedHistoryRequest)
+		return s.GetFeedHistory(ctx, req)
+	}
+	epduration := duration.With("method", "GetFeedHistory")
+	eplog := log.With(logger, "method", "GetFeedHistory")
+	ep = opentracing.TraceServer(tracer, "GetFeedHistory")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeLikeFeedEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.LikeRequest)
+		return s.LikeFeed(ctx, req)
+	}
+	epduration := duration.With("method", "LikeFeed")
+	eplog := log.With(logger, "method", "LikeFeed")
+	ep = opentracing.TraceServer(tracer, "LikeFeed")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeUnlikeFeedEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.LikeRequest)
+		return s.UnlikeFeed(ctx, req)
+	}
+	epduration := duration.With("method", "UnlikeFeed")
+	eplog := log.With(logger, "method", "UnlikeFeed")
+	ep = opentracing.TraceServer(tracer, "UnlikeFeed")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeCreateCommentEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.Comment)
+		return s.CreateComment(ctx, req)
+	}
+	epduration := duration.With("method", "CreateComment")
+	eplog := log.With(logger, "method", "CreateComment")
+	ep = opentracing.TraceServer(tracer, "CreateComment")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeGetCommentsEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.GetCommentsRequest)
+		return s.GetComments(ctx, req)
+	}
+	epduration := duration.With("method", "GetComments")
+	eplog := log.With(logger, "method", "GetComments")
+	ep = opentracing.TraceServer(tracer, "GetComments")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeListHeldFeedsEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.ListHeldFeedsRequest)
+		return s.ListHeldFeeds(ctx, req)
+	}
+	epduration := duration.With("method", "ListHeldFeeds")
+	eplog := log.With(logger, "method", "ListHeldFeeds")
+	ep = opentracing.TraceServer(tracer, "ListHeldFeeds")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeReviewFeedEndpoint(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*feed.ReviewFeedRequest)
+		return s.ReviewFeed(ctx, req)
+	}
+	epduration := duration.With("method", "ReviewFeed")
+	eplog := log.With(logger, "method", "ReviewFeed")
+	ep = opentracing.TraceServer(tracer, "ReviewFeed")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
 // MakeGRPCServer makes a set of endpoints available as a gRPC AddServer.
 func MakeGRPCServer(s feed.FeedServer, tracer stdopentracing.Tracer, logger log.Logger) feed.FeedServer {
 	options := []grpctransport.ServerOption{
 		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(logging.GRPCToContext),
+	}
+	limiter := limit.NewLimiter(concurrencyLimit)
+	limited := func(method string, p limit.Priority, ep endpoint.Endpoint) endpoint.Endpoint {
+		ep = limit.Middleware(limiter, p, shedRequests.With("method", method))(ep)
+		ep = validate.Middleware()(ep)
+		ep = slo.Middleware(method)(ep)
+		return EndpointCountingMiddleware(requests.With("method", method))(ep)
 	}
 
 	return &grpcServer{
 		getfeeds: grpctransport.NewServer(
-			MakeGetFeedsEndpoint(s, tracer, logger),
+			limited("GetFeeds", limit.Read, MakeGetFeedsEndpoint(s, tracer, logger)),
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "GetFeeds", logger)))...,
 		),
 		createfeed: grpctransport.NewServer(
-			MakeCreateFeedEndpoint(s, tracer, logger),
+			limited("CreateFeed", limit.Write, MakeCreateFeedEndpoint(s, tracer, logger)),
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "CreateFeed", logger)))...,
 		),
+		searchfeeds: grpctransport.NewServer(
+			limited("SearchFeeds", limit.Read, MakeSearchFeedsEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "SearchFeeds", logger)))...,
+		),
+		getfeedsbytopic: grpctransport.NewServer(
+			limited("GetFeedsByTopic", limit.Read, MakeGetFeedsByTopicEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "GetFeedsByTopic", logger)))...,
+		),
+		updatefeed: grpctransport.NewServer(
+			limited("UpdateFeed", limit.Write, MakeUpdateFeedEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "UpdateFeed", logger)))...,
+		),
+		deletefeed: grpctransport.NewServer(
+			limited("DeleteFeed", limit.Write, MakeDeleteFeedEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "DeleteFeed", logger)))...,
+		),
+		getfeedhistory: grpctransport.NewServer(
+			limited("GetFeedHistory", limit.Read, MakeGetFeedHistoryEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "GetFeedHistory", logger)))...,
+		),
+		likefeed: grpctransport.NewServer(
+			limited("LikeFeed", limit.Write, MakeLikeFeedEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "LikeFeed", logger)))...,
+		),
+		unlikefeed: grpctransport.NewServer(
+			limited("UnlikeFeed", limit.Write, MakeUnlikeFeedEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "UnlikeFeed", logger)))...,
+		),
+		createcomment: grpctransport.NewServer(
+			limited("CreateComment", limit.Write, MakeCreateCommentEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "CreateComment", logger)))...,
+		),
+		getcomments: grpctransport.NewServer(
+			limited("GetComments", limit.Read, MakeGetCommentsEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "GetComments", logger)))...,
+		),
+		listheldfeeds: grpctransport.NewServer(
+			limited("ListHeldFeeds", limit.Read, MakeListHeldFeedsEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "ListHeldFeeds", logger)))...,
+		),
+		reviewfeed: grpctransport.NewServer(
+			limited("ReviewFeed", limit.Write, MakeReviewFeedEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "ReviewFeed", logger)))...,
+		),
 	}
 }
 
 type grpcServer struct {
-	getfeeds   grpctransport.Handler
-	createfeed grpctransport.Handler
+	getfeeds        grpctransport.Handler
+	createfeed      grpctransport.Handler
+	searchfeeds     grpctransport.Handler
+	getfeedsbytopic grpctransport.Handler
+	updatefeed      grpctransport.Handler
+	deletefeed      grpctransport.Handler
+	getfeedhistory  grpctransport.Handler
+	likefeed        grpctransport.Handler
+	unlikefeed      grpctransport.Handler
+	createcomment   grpctransport.Handler
+	getcomments     grpctransport.Handler
+	listheldfeeds   grpctransport.Handler
+	reviewfeed      grpctransport.Handler
 }
 
 func (s *grpcServer) GetFeeds(ctx oldcontext.Context, req *feed.GetFeedsRequest) (*feed.GetFeedsResponse, error) {
@@ -116,3 +387,91 @@ func (s *grpcServer) CreateFeed(ctx oldcontext.Context, req *feed.FeedRecord) (*
 	}
 	return rep.(*feed.OkResponse), nil
 }
+
+func (s *grpcServer) SearchFeeds(ctx oldcontext.Context, req *feed.SearchFeedsRequest) (*feed.GetFeedsResponse, error) {
+	_, rep, err := s.searchfeeds.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.GetFeedsResponse), nil
+}
+
+func (s *grpcServer) GetFeedsByTopic(ctx oldcontext.Context, req *feed.GetFeedsByTopicRequest) (*feed.GetFeedsResponse, error) {
+	_, rep, err := s.getfeedsbytopic.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.GetFeedsResponse), nil
+}
+
+func (s *grpcServer) UpdateFeed(ctx oldcontext.Context, req *feed.UpdateFeedRequest) (*feed.FeedRecord, error) {
+	_, rep, err := s.updatefeed.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.FeedRecord), nil
+}
+
+func (s *grpcServer) DeleteFeed(ctx oldcontext.Context, req *feed.DeleteFeedRequest) (*feed.OkResponse, error) {
+	_, rep, err := s.deletefeed.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.OkResponse), nil
+}
+
+func (s *grpcServer) GetFeedHistory(ctx oldcontext.Context, req *feed.GetFeedHistoryRequest) (*feed.GetFeedHistoryResponse, error) {
+	_, rep, err := s.getfeedhistory.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.GetFeedHistoryResponse), nil
+}
+
+func (s *grpcServer) LikeFeed(ctx oldcontext.Context, req *feed.LikeRequest) (*feed.LikeResponse, error) {
+	_, rep, err := s.likefeed.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.LikeResponse), nil
+}
+
+func (s *grpcServer) UnlikeFeed(ctx oldcontext.Context, req *feed.LikeRequest) (*feed.LikeResponse, error) {
+	_, rep, err := s.unlikefeed.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.LikeResponse), nil
+}
+
+func (s *grpcServer) CreateComment(ctx oldcontext.Context, req *feed.Comment) (*feed.Comment, error) {
+	_, rep, err := s.createcomment.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.Comment), nil
+}
+
+func (s *grpcServer) GetComments(ctx oldcontext.Context, req *feed.GetCommentsRequest) (*feed.GetCommentsResponse, error) {
+	_, rep, err := s.getcomments.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.GetCommentsResponse), nil
+}
+
+func (s *grpcServer) ListHeldFeeds(ctx oldcontext.Context, req *feed.ListHeldFeedsRequest) (*feed.ListHeldFeedsResponse, error) {
+	_, rep, err := s.listheldfeeds.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.ListHeldFeedsResponse), nil
+}
+
+func (s *grpcServer) ReviewFeed(ctx oldcontext.Context, req *feed.ReviewFeedRequest) (*feed.OkResponse, error) {
+	_, rep, err := s.reviewfeed.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*feed.OkResponse), nil
+}
@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/buptmiao/microservice-app/moderation"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// Review verdicts, as labelled in the metrics.
+const (
+	reviewApprove = "approve"
+	reviewReject  = "reject"
+)
+
+var (
+	// the review queue: the new feeds and the edits held by the moderation
+	// chain, by id. The ids of the new feeds stay claimed until they are
+	// reviewed. A feed is either new or live, so an id has a single entry.
+	heldFeeds = make(map[int64]*feed.HeldFeed)
+	heldMu    sync.Mutex
+)
+
+var (
+	heldBacklog metrics.Gauge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "feed",
+		Subsystem: "moderation",
+		Name:      "held",
+		Help:      "Number of feeds waiting for review.",
+	}, []string{})
+	reviews metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "feed",
+		Subsystem: "moderation",
+		Name:      "reviews_total",
+		Help:      "Number of held feeds reviewed, by verdict.",
+	}, []string{"verdict"})
+	reviewWait metrics.Histogram = prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+		Namespace: "feed",
+		Subsystem: "moderation",
+		Name:      "review_wait_seconds",
+		Help:      "Time between a feed being held and being reviewed.",
+	}, []string{"verdict"})
+)
+
+// hold queues a new feed for review.
+func hold(f *feed.FeedRecord, d moderation.Decision) {
+	queue(&feed.HeldFeed{Feed: f, Stage: d.Stage, Reason: d.Reason})
+}
+
+// holdEdit queues the next version of a live feed for review. It replaces
+// an edit of the feed still waiting for review.
+func holdEdit(f *feed.FeedRecord, d moderation.Decision) {
+	queue(&feed.HeldFeed{Feed: f, Stage: d.Stage, Reason: d.Reason, Edit: true})
+}
+
+func queue(h *feed.HeldFeed) {
+	h.HeldAt = time.Now().UnixNano()
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if _, ok := heldFeeds[h.Feed.Id]; !ok {
+		heldBacklog.Add(1)
+	}
+	heldFeeds[h.Feed.Id] = h
+}
+
+// unhold takes feed id of the user ownerID out of the review queue.
+func unhold(id, ownerID int64) (*feed.HeldFeed, bool) {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	h, ok := heldFeeds[id]
+	if !ok || h.Feed.UserId != ownerID {
+		return nil, false
+	}
+	delete(heldFeeds, id)
+	heldBacklog.Add(-1)
+	return h, true
+}
+
+// ListHeldFeeds returns the review queue, oldest first.
+func (s service) ListHeldFeeds(_ context.Context, req *feed.ListHeldFeedsRequest) (*feed.ListHeldFeedsResponse, error) {
+	size := int(req.GetSize())
+	if size <= 0 {
+		size = feed.DefaultHeldSize
+	}
+	heldMu.Lock()
+	feeds := make([]*feed.HeldFeed, 0, len(heldFeeds))
+	for _, h := range heldFeeds {
+		feeds = append(feeds, h)
+	}
+	heldMu.Unlock()
+
+	sort.Slice(feeds, func(i, j int) bool {
+		if feeds[i].HeldAt != feeds[j].HeldAt {
+			return feeds[i].HeldAt < feeds[j].HeldAt
+		}
+		return feeds[i].Feed.Id < feeds[j].Feed.Id
+	})
+	if len(feeds) > size {
+		feeds = feeds[:size]
+	}
+	return &feed.ListHeldFeedsResponse{Feeds: feeds}, nil
+}
+
+// ReviewFeed publishes or drops a held feed. A dropped new feed releases
+// its id, a dropped edit leaves the feed as it is. An approved edit fails
+// with ErrVersionConflict when the feed changed while it was held.
+func (s service) ReviewFeed(_ context.Context, req *feed.ReviewFeedRequest) (*feed.OkResponse, error) {
+	h, ok := unhold(req.GetId(), req.GetOwnerId())
+	if !ok {
+		return nil, ErrNotHeld
+	}
+	verdict := reviewReject
+	if req.GetApprove() {
+		verdict = reviewApprove
+	}
+	reviews.With("verdict", verdict).Add(1)
+	reviewWait.With("verdict", verdict).Observe(time.Since(time.Unix(0, h.HeldAt)).Seconds())
+	s.logger.Log("review", verdict, "feed_id", h.Feed.Id, "user_id", h.Feed.UserId, "edit", h.Edit, "stage", h.Stage, "reason", h.Reason, "moderator", req.GetModerator(), "note", req.GetNote())
+
+	switch {
+	case h.Edit && req.GetApprove():
+		if err := applyEdit(h.Feed); err != nil {
+			return nil, err
+		}
+	case h.Edit:
+	case req.GetApprove():
+		publish(h.Feed)
+	default:
+		release(h.Feed.Id)
+	}
+	return &feed.OkResponse{}, nil
+}
@@ -0,0 +1,45 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/buptmiao/microservice-app/moderation"
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailedWritesAreNotModerated(t *testing.T) {
+	reset()
+	s := NewFeedService(nil, moderation.NewChain(moderation.NewRate(2, time.Hour, moderation.Reject)), log.NewNopLogger())
+	ctx := context.Background()
+
+	if _, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: 6001, UserId: 6001, Content: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	// none of these writes goes through, so none counts against the rate.
+	if _, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: 6001, UserId: 6001, Content: "again"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("duplicate got %v, want AlreadyExists", err)
+	}
+	if _, err := s.UpdateFeed(ctx, &feed.UpdateFeedRequest{Id: 6001, UserId: 6002, Content: "mine"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("edit of another user got %v, want PermissionDenied", err)
+	}
+	if _, err := s.UpdateFeed(ctx, &feed.UpdateFeedRequest{Id: 6001, UserId: 6001, Content: "stale", ExpectedVersion: 7}); status.Code(err) != codes.Aborted {
+		t.Fatalf("stale edit got %v, want Aborted", err)
+	}
+	if _, err := s.UpdateFeed(ctx, &feed.UpdateFeedRequest{Id: 6001, UserId: 6001, Content: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the third post is beyond the rate, and a rejected post frees its id.
+	_, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: 6003, UserId: 6001, Content: "third"})
+	if _, ok := err.(*moderation.Rejected); !ok {
+		t.Fatalf("third post got %v, want it rejected", err)
+	}
+	if _, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: 6003, UserId: 6004, Content: "hello"}); err != nil {
+		t.Fatalf("id of a rejected post still taken: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package feed
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// Event types written to the outbox.
+const (
+	EventFeedCreated = "FeedCreated"
+	EventFeedUpdated = "FeedUpdated"
+	EventFeedDeleted = "FeedDeleted"
+)
+
+// OutboxEvent is a domain event waiting to be published by the Relay.
+// Events of one user are published in order, events of users in different
+// shards may be published out of Seq order.
+type OutboxEvent struct {
+	Seq       int64
+	Type      string
+	Record    *feed.FeedRecord
+	CreatedAt time.Time
+	SentAt    time.Time
+}
+
+// outboxSeq numbers the events across the shards.
+var outboxSeq int64
+
+var (
+	outboxBacklog metrics.Gauge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "feed",
+		Subsystem: "outbox",
+		Name:      "backlog",
+		Help:      "Number of outbox events not yet published.",
+	}, []string{})
+	relayLag metrics.Histogram = prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+		Namespace: "feed",
+		Subsystem: "outbox",
+		Name:      "relay_lag_seconds",
+		Help:      "Time between an event being written and being published.",
+	}, []string{"type"})
+)
+
+// appendOutbox records an event. The caller must hold sh.mu for writing.
+func (sh *shard) appendOutbox(typ string, record *feed.FeedRecord) {
+	sh.outbox = append(sh.outbox, &OutboxEvent{
+		Seq:       atomic.AddInt64(&outboxSeq, 1),
+		Type:      typ,
+		Record:    record,
+		CreatedAt: time.Now(),
+	})
+	outboxBacklog.Add(1)
+}
+
+// pendingOutbox returns at most n unsent events of the shard in write order.
+func (sh *shard) pendingOutbox(n int) []*OutboxEvent {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	res := []*OutboxEvent{}
+	for _, e := range sh.outbox {
+		if len(res) >= n {
+			break
+		}
+		if e.SentAt.IsZero() {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// markSent flags the event as published and drops the sent prefix of the outbox.
+func (sh *shard) markSent(e *OutboxEvent) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e.SentAt = time.Now()
+	outboxBacklog.Add(-1)
+	relayLag.With("type", e.Type).Observe(e.SentAt.Sub(e.CreatedAt).Seconds())
+
+	i := 0
+	for i < len(sh.outbox) && !sh.outbox[i].SentAt.IsZero() {
+		i++
+	}
+	sh.outbox = sh.outbox[i:]
+}
+
+// Publisher delivers outbox events to the outside world.
+type Publisher interface {
+	Publish(ctx context.Context, e *OutboxEvent) error
+}
+
+// PublisherFunc is an adapter to use ordinary functions as Publishers.
+type PublisherFunc func(ctx context.Context, e *OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e *OutboxEvent) error {
+	return f(ctx, e)
+}
+
+// NewLogPublisher returns a Publisher which only logs the events.
+func NewLogPublisher(logger log.Logger) Publisher {
+	return PublisherFunc(func(_ context.Context, e *OutboxEvent) error {
+		return logger.Log("event", e.Type, "seq", e.Seq, "feed_id", e.Record.GetId(), "user_id", e.Record.GetUserId())
+	})
+}
+
+// Relay polls the outbox of every shard and publishes the pending events in
+// order. Events are delivered at least once: a failed publish is retried on
+// the next tick.
+type Relay struct {
+	publisher Publisher
+	interval  time.Duration
+	batch     int
+	logger    log.Logger
+	quit      chan struct{}
+	done      chan struct{}
+}
+
+func NewRelay(publisher Publisher, interval time.Duration, logger log.Logger) *Relay {
+	return &Relay{
+		publisher: publisher,
+		interval:  interval,
+		batch:     100,
+		logger:    logger,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run blocks until Stop is called.
+func (r *Relay) Run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-r.quit:
+			r.flush()
+			return
+		}
+	}
+}
+
+// Stop makes Run publish the pending events a last time and return, and
+// waits for it.
+func (r *Relay) Stop() {
+	close(r.quit)
+	<-r.done
+}
+
+func (r *Relay) flush() {
+	for _, sh := range shards {
+		for _, e := range sh.pendingOutbox(r.batch) {
+			if err := r.publisher.Publish(context.Background(), e); err != nil {
+				r.logger.Log("seq", e.Seq, "err", err)
+				// keep ordering: later events of the shard wait for this one.
+				break
+			}
+			sh.markSent(e)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package feed
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+)
+
+// recorder publishes the events of one user, failing the first fail calls.
+type recorder struct {
+	userID int64
+	fail   int
+	types  []string
+}
+
+func (r *recorder) Publish(_ context.Context, e *OutboxEvent) error {
+	if e.Record.GetUserId() != r.userID {
+		return nil
+	}
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("broker down")
+	}
+	r.types = append(r.types, e.Type)
+	return nil
+}
+
+func appendEvent(typ string, record *feed.FeedRecord) {
+	sh := shardFor(record.UserId)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.appendOutbox(typ, record)
+}
+
+func TestRelayRetriesInOrder(t *testing.T) {
+	record := &feed.FeedRecord{Id: 5001, UserId: 5001}
+	appendEvent(EventFeedCreated, record)
+	appendEvent(EventFeedUpdated, record)
+
+	pub := &recorder{userID: 5001, fail: 1}
+	r := NewRelay(pub, time.Hour, log.NewNopLogger())
+	r.flush()
+	if len(pub.types) != 0 {
+		t.Fatalf("published %v after a failure, want nothing", pub.types)
+	}
+	r.flush()
+	if len(pub.types) != 2 || pub.types[0] != EventFeedCreated || pub.types[1] != EventFeedUpdated {
+		t.Fatalf("published %v, want the created then the updated event", pub.types)
+	}
+	if pending := shardFor(5001).pendingOutbox(10); len(pending) != 0 {
+		t.Errorf("%d events still pending", len(pending))
+	}
+}
+
+func TestRelayStopFlushes(t *testing.T) {
+	pub := &recorder{userID: 5002}
+	r := NewRelay(pub, time.Hour, log.NewNopLogger())
+	go r.Run()
+	appendEvent(EventFeedDeleted, &feed.FeedRecord{Id: 5002, UserId: 5002})
+	r.Stop()
+	if len(pub.types) != 1 || pub.types[0] != EventFeedDeleted {
+		t.Fatalf("published %v on stop, want the deleted event", pub.types)
+	}
+}
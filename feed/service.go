@@ -1,61 +1,262 @@
 package feed
 
 import (
-	"errors"
+	"github.com/buptmiao/microservice-app/moderation"
 	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/buptmiao/microservice-app/proto/topic"
+	"github.com/go-kit/kit/log"
 	"golang.org/x/net/context"
-	"sync"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+	"unicode/utf8"
 )
 
-// Storage
+// The errors are gRPC statuses, so that the callers can tell the faults of
+// the request from those of the service.
 var (
-	mem map[int64]map[int64]*feed.FeedRecord
-	mu  sync.RWMutex
-)
-
-func init() {
-	mem = make(map[int64]map[int64]*feed.FeedRecord)
-}
-
-var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound    = status.Error(codes.NotFound, "user not found")
+	ErrFeedNotFound    = status.Error(codes.NotFound, "feed not found")
+	ErrFeedExists      = status.Error(codes.AlreadyExists, "feed already exists")
+	ErrNotOwner        = status.Error(codes.PermissionDenied, "feed is owned by another user")
+	ErrVersionConflict = status.Error(codes.Aborted, "feed version conflict")
+	ErrNotHeld         = status.Error(codes.NotFound, "feed is not held for review")
 )
 
 // NewFeedService returns a naive, stateless implementation of Feed Service.
-func NewFeedService() feed.FeedServer {
-	return service{}
+// The topic client resolves hashtags to topics, it may be nil in which case
+// hashtags are kept on the records but not linked. The new feeds go through
+// the moderation chain, nil allowing every feed, and the reviews of the held
+// ones are logged with logger.
+func NewFeedService(topics topic.TopicClient, chain *moderation.Chain, logger log.Logger) feed.FeedServer {
+	return service{topics: topics, moderation: chain, logger: logger}
 }
 
-type service struct{}
+type service struct {
+	topics     topic.TopicClient
+	moderation *moderation.Chain
+	logger     log.Logger
+}
 
+// GetFeeds returns up to size feeds of a user, feed.DefaultPageSize when
+// the size is unset.
 func (s service) GetFeeds(_ context.Context, req *feed.GetFeedsRequest) (*feed.GetFeedsResponse, error) {
 	userID := req.GetUserId()
 	size := req.GetSize()
+	if size <= 0 {
+		size = feed.DefaultPageSize
+	}
 	feeds := []*feed.FeedRecord{}
-	mu.RLock()
-	defer mu.RUnlock()
-	if v, ok := mem[userID]; !ok {
+	sh := shardFor(userID)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if v, ok := sh.mem[userID]; !ok {
 		return nil, ErrUserNotFound
 	} else {
 		for _, f := range v {
-			if size <= 0 {
+			if size == 0 {
 				break
 			}
-			feeds = append(feeds, f)
+			if f.Deleted {
+				continue
+			}
+			feeds = append(feeds, sh.withCounters(f))
 			size--
 		}
 	}
 	return &feed.GetFeedsResponse{Feeds: feeds}, nil
 }
 
-func (s service) CreateFeed(_ context.Context, req *feed.FeedRecord) (*feed.OkResponse, error) {
-	mu.Lock()
-	defer mu.Unlock()
-	userFeeds, ok := mem[req.UserId]
-	if !ok {
-		mem[req.UserId] = map[int64]*feed.FeedRecord{req.Id: req}
-		return &feed.OkResponse{}, nil
+// CreateFeed publishes a new feed, unless the moderation chain rejects it
+// or holds it for review. The id is claimed first, so that a duplicate does
+// not count against the posting rate of the user.
+func (s service) CreateFeed(ctx context.Context, req *feed.FeedRecord) (*feed.OkResponse, error) {
+	if !claim(req.Id, req.UserId) {
+		return nil, ErrFeedExists
+	}
+	d := s.moderation.Moderate(ctx, &moderation.Post{ID: req.Id, UserID: req.UserId, Content: req.Content})
+	if d.Verdict == moderation.Reject {
+		release(req.Id)
+		return nil, &moderation.Rejected{Decision: d}
+	}
+
+	req.Hashtags, req.Mentions = ParseContent(req.Content)
+	req.TopicIds = s.resolveTopics(ctx, req.Hashtags)
+	req.Version = 1
+	req.Deleted = false
+	req.Held = false
+	req.Score = 0
+	req.CreatedAt = time.Now().UnixNano()
+
+	if d.Verdict == moderation.Hold {
+		hold(req, d)
+		return &feed.OkResponse{Held: true}, nil
 	}
-	userFeeds[req.Id] = req
+	publish(req)
 	return &feed.OkResponse{}, nil
 }
+
+// publish stores a new feed whose id is claimed.
+func publish(f *feed.FeedRecord) {
+	sh := shardFor(f.UserId)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.store(f)
+	// the event is appended under the same lock as the record, so the relay
+	// never sees one without the other.
+	sh.appendOutbox(EventFeedCreated, f)
+}
+
+// UpdateFeed replaces the content of a feed and records a new version. The
+// new content goes through the moderation chain like a new feed, once the
+// edit is known to be allowed: a held edit is queued for review and the
+// unchanged record is returned, flagged as held.
+func (s service) UpdateFeed(ctx context.Context, req *feed.UpdateFeedRequest) (*feed.FeedRecord, error) {
+	hashtags, mentions := ParseContent(req.GetContent())
+	topicIDs := s.resolveTopics(ctx, hashtags)
+
+	sh := shardFor(req.GetUserId())
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	old, err := sh.checkWrite(req.GetId(), req.GetUserId(), req.GetExpectedVersion())
+	if err != nil {
+		if err == ErrFeedNotFound && inOtherShard(req.GetId(), sh) {
+			err = ErrNotOwner
+		}
+		return nil, err
+	}
+	// the stages of the chain work in memory, the edit is moderated under
+	// the lock so that it applies to the version it was checked against.
+	d := s.moderation.Moderate(ctx, &moderation.Post{ID: req.GetId(), UserID: req.GetUserId(), Content: req.GetContent()})
+	if d.Verdict == moderation.Reject {
+		return nil, &moderation.Rejected{Decision: d}
+	}
+	// records are never modified in place, readers may still hold the old one.
+	updated := *old
+	updated.Content = req.GetContent()
+	updated.Hashtags = hashtags
+	updated.Mentions = mentions
+	updated.TopicIds = topicIDs
+	updated.Version++
+	if d.Verdict == moderation.Hold {
+		holdEdit(&updated, d)
+		res := sh.withCounters(old)
+		res.Held = true
+		return res, nil
+	}
+	sh.store(&updated)
+	sh.appendOutbox(EventFeedUpdated, &updated)
+	return sh.withCounters(&updated), nil
+}
+
+// applyEdit stores an approved edit, unless the feed was changed or deleted
+// since the edit was held.
+func applyEdit(f *feed.FeedRecord) error {
+	sh := shardFor(f.UserId)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if _, err := sh.checkWrite(f.Id, f.UserId, f.Version-1); err != nil {
+		return err
+	}
+	sh.store(f)
+	sh.appendOutbox(EventFeedUpdated, f)
+	return nil
+}
+
+// DeleteFeed soft deletes a feed: the record stays as a tombstone which is
+// hidden from reads but kept in the history.
+func (s service) DeleteFeed(_ context.Context, req *feed.DeleteFeedRequest) (*feed.OkResponse, error) {
+	sh := shardFor(req.GetUserId())
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	old, err := sh.checkWrite(req.GetId(), req.GetUserId(), req.GetExpectedVersion())
+	if err != nil {
+		if err == ErrFeedNotFound && inOtherShard(req.GetId(), sh) {
+			err = ErrNotOwner
+		}
+		return nil, err
+	}
+	tombstone := *old
+	tombstone.Deleted = true
+	tombstone.Version++
+	sh.store(&tombstone)
+	sh.appendOutbox(EventFeedDeleted, &tombstone)
+	return &feed.OkResponse{}, nil
+}
+
+// GetFeedHistory returns every version of a live feed, oldest first. The
+// owner_id must own the feed.
+func (s service) GetFeedHistory(_ context.Context, req *feed.GetFeedHistoryRequest) (*feed.GetFeedHistoryResponse, error) {
+	sh := shardOfFeed(req.GetId())
+	if sh == nil {
+		return nil, ErrFeedNotFound
+	}
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if _, err := sh.owned(req.GetId(), req.GetOwnerId()); err != nil {
+		return nil, err
+	}
+	return &feed.GetFeedHistoryResponse{Revisions: sh.history[req.GetId()]}, nil
+}
+
+func (s service) SearchFeeds(_ context.Context, req *feed.SearchFeedsRequest) (*feed.GetFeedsResponse, error) {
+	size := req.GetSize()
+	if size <= 0 {
+		size = feed.DefaultPageSize
+	}
+	hits := index.Search(req.GetQuery(), int(size))
+	feeds := []*feed.FeedRecord{}
+	for _, hit := range hits {
+		if f, ok := lookup(hit.ID); ok {
+			f.Score = hit.Score
+			feeds = append(feeds, f)
+		}
+	}
+	return &feed.GetFeedsResponse{Feeds: feeds}, nil
+}
+
+// GetFeedsByTopic returns the posts linked to a topic, newest first.
+func (s service) GetFeedsByTopic(_ context.Context, req *feed.GetFeedsByTopicRequest) (*feed.GetFeedsResponse, error) {
+	size := req.GetSize()
+	if size <= 0 {
+		size = feed.DefaultPageSize
+	}
+	topicMu.RLock()
+	ids := append([]int64(nil), topicFeeds[req.GetTopicId()]...)
+	topicMu.RUnlock()
+
+	feeds := []*feed.FeedRecord{}
+	for i := len(ids) - 1; i >= 0 && size > 0; i-- {
+		if f, ok := lookup(ids[i]); ok {
+			feeds = append(feeds, f)
+			size--
+		}
+	}
+	return &feed.GetFeedsResponse{Feeds: feeds}, nil
+}
+
+// resolveTopics looks the hashtags up in the topic service. Linking is best
+// effort: a post is never rejected because the topic service is unavailable,
+// and the hashtags beyond the limits of ResolveTags are not linked.
+func (s service) resolveTopics(ctx context.Context, hashtags []string) []int64 {
+	if s.topics == nil || len(hashtags) == 0 {
+		return nil
+	}
+	tags := []string{}
+	for _, tag := range hashtags {
+		if len(tags) < topic.MaxTags && utf8.RuneCountInString(tag) <= topic.MaxTagLength {
+			tags = append(tags, tag)
+		}
+	}
+	resp, err := s.topics.ResolveTags(ctx, &topic.ResolveTagsRequest{Tags: tags})
+	if err != nil {
+		return nil
+	}
+	ids := []int64{}
+	for _, tag := range hashtags {
+		if id, ok := resp.GetTopicIds()[tag]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
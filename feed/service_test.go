@@ -0,0 +1,132 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/buptmiao/microservice-app/search"
+	"github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reset empties the storage and the review queue, which the tests of the
+// package share.
+func reset() {
+	for i := range shards {
+		shards[i] = newShard()
+	}
+	ownersMu.Lock()
+	owners = make(map[int64]int64)
+	ownersMu.Unlock()
+	topicMu.Lock()
+	index = search.NewIndex()
+	topicFeeds = make(map[int64][]int64)
+	topicMu.Unlock()
+	heldMu.Lock()
+	heldBacklog.Add(-float64(len(heldFeeds)))
+	heldFeeds = make(map[int64]*feed.HeldFeed)
+	heldMu.Unlock()
+}
+
+func TestDefaultPageSize(t *testing.T) {
+	reset()
+	s := NewFeedService(nil, nil, log.NewNopLogger())
+	ctx := context.Background()
+	for id := int64(7001); id <= 7025; id++ {
+		if _, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: id, UserId: 7001, Content: "paging through"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, size := range []int64{0, 5} {
+		want := int(size)
+		if size == 0 {
+			want = feed.DefaultPageSize
+		}
+		listed, err := s.GetFeeds(ctx, &feed.GetFeedsRequest{UserId: 7001, Size: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed.Feeds) != want {
+			t.Errorf("size %d: listed %d feeds, want %d", size, len(listed.Feeds), want)
+		}
+		found, err := s.SearchFeeds(ctx, &feed.SearchFeedsRequest{Query: "paging", Size: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found.Feeds) != want {
+			t.Errorf("size %d: found %d feeds, want %d", size, len(found.Feeds), want)
+		}
+	}
+}
+
+func TestWriteRules(t *testing.T) {
+	reset()
+	s := NewFeedService(nil, nil, log.NewNopLogger())
+	ctx := context.Background()
+	if _, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: 8001, UserId: 8001, Content: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateFeed(ctx, &feed.UpdateFeedRequest{Id: 8001, UserId: 8001, Content: "v2", ExpectedVersion: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		// 8002 hashes to another shard than the owner, 8033 to the same one.
+		{"update of another user", update(s, 8001, 8002, 0), codes.PermissionDenied},
+		{"update of another user in the shard", update(s, 8001, 8033, 0), codes.PermissionDenied},
+		{"delete of another user", remove(s, 8001, 8002, 0), codes.PermissionDenied},
+		{"update of a stale version", update(s, 8001, 8001, 1), codes.Aborted},
+		{"delete of a stale version", remove(s, 8001, 8001, 1), codes.Aborted},
+		{"update of an unknown feed", update(s, 8099, 8001, 0), codes.NotFound},
+		{"history of another owner", history(s, 8001, 8002), codes.PermissionDenied},
+	} {
+		if got := status.Code(tc.err); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.err, tc.want)
+		}
+	}
+
+	// a deleted feed is a tombstone: hidden, and no longer writable.
+	if err := remove(s, 8001, 8001, 2); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"update":  update(s, 8001, 8001, 0),
+		"delete":  remove(s, 8001, 8001, 0),
+		"history": history(s, 8001, 8001),
+	} {
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("%s of a deleted feed got %v, want NotFound", name, err)
+		}
+	}
+	sh := shardFor(8001)
+	sh.mu.RLock()
+	revisions := sh.history[8001]
+	sh.mu.RUnlock()
+	if len(revisions) != 3 || !revisions[2].Deleted || revisions[2].Version != 3 || revisions[1].Content != "v2" {
+		t.Errorf("unexpected history %v", revisions)
+	}
+	if _, err := s.CreateFeed(ctx, &feed.FeedRecord{Id: 8001, UserId: 8001, Content: "again"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("create over a tombstone got %v, want AlreadyExists", err)
+	}
+}
+
+func update(s feed.FeedServer, id, userID, expectedVersion int64) error {
+	_, err := s.UpdateFeed(context.Background(), &feed.UpdateFeedRequest{Id: id, UserId: userID, Content: "edit", ExpectedVersion: expectedVersion})
+	return err
+}
+
+func remove(s feed.FeedServer, id, userID, expectedVersion int64) error {
+	_, err := s.DeleteFeed(context.Background(), &feed.DeleteFeedRequest{Id: id, UserId: userID, ExpectedVersion: expectedVersion})
+	return err
+}
+
+func history(s feed.FeedServer, id, ownerID int64) error {
+	_, err := s.GetFeedHistory(context.Background(), &feed.GetFeedHistoryRequest{Id: id, OwnerId: ownerID})
+	return err
+}
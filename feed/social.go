@@ -0,0 +1,126 @@
+package feed
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// comment ids are unique across the shards.
+var commentSeq int64
+
+var (
+	ErrCommentNotFound = status.Error(codes.NotFound, "comment not found")
+)
+
+const defaultCommentsSize = 20
+
+// LikeFeed records that the user likes the feed. Liking twice is a no-op.
+func (s service) LikeFeed(_ context.Context, req *feed.LikeRequest) (*feed.LikeResponse, error) {
+	sh := shardOfFeed(req.GetFeedId())
+	if sh == nil {
+		return nil, ErrFeedNotFound
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if _, err := sh.owned(req.GetFeedId(), req.GetOwnerId()); err != nil {
+		return nil, err
+	}
+	users, ok := sh.likes[req.GetFeedId()]
+	if !ok {
+		users = make(map[int64]bool)
+		sh.likes[req.GetFeedId()] = users
+	}
+	users[req.GetUserId()] = true
+	return &feed.LikeResponse{LikeCount: int64(len(users))}, nil
+}
+
+func (s service) UnlikeFeed(_ context.Context, req *feed.LikeRequest) (*feed.LikeResponse, error) {
+	sh := shardOfFeed(req.GetFeedId())
+	if sh == nil {
+		return nil, ErrFeedNotFound
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if _, err := sh.owned(req.GetFeedId(), req.GetOwnerId()); err != nil {
+		return nil, err
+	}
+	users := sh.likes[req.GetFeedId()]
+	delete(users, req.GetUserId())
+	return &feed.LikeResponse{LikeCount: int64(len(users))}, nil
+}
+
+// CreateComment adds a comment to a feed, or a reply to one of its comments.
+// The id and creation time are assigned by the service.
+func (s service) CreateComment(_ context.Context, req *feed.Comment) (*feed.Comment, error) {
+	sh := shardOfFeed(req.GetFeedId())
+	if sh == nil {
+		return nil, ErrFeedNotFound
+	}
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	f, err := sh.owned(req.GetFeedId(), req.GetOwnerId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetParentId() != 0 && sh.commentFeed[req.GetParentId()] != req.GetFeedId() {
+		return nil, ErrCommentNotFound
+	}
+	c := &feed.Comment{
+		Id:        atomic.AddInt64(&commentSeq, 1),
+		FeedId:    req.GetFeedId(),
+		UserId:    req.GetUserId(),
+		ParentId:  req.GetParentId(),
+		Content:   req.GetContent(),
+		CreatedAt: time.Now().UnixNano(),
+		OwnerId:   f.UserId,
+	}
+	sh.comments[c.FeedId] = append(sh.comments[c.FeedId], c)
+	sh.commentFeed[c.Id] = c.FeedId
+	if c.ParentId != 0 {
+		sh.replies[c.ParentId]++
+	}
+	return c, nil
+}
+
+// GetComments returns one page of the direct replies to req.ParentId, oldest
+// first. NextCursor is zero on the last page.
+func (s service) GetComments(_ context.Context, req *feed.GetCommentsRequest) (*feed.GetCommentsResponse, error) {
+	size := req.GetSize()
+	if size <= 0 {
+		size = defaultCommentsSize
+	}
+	sh := shardOfFeed(req.GetFeedId())
+	if sh == nil {
+		return nil, ErrFeedNotFound
+	}
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	// the comments of a deleted feed stay readable, the record is enough.
+	f, ok := sh.records[req.GetFeedId()]
+	if !ok {
+		return nil, ErrFeedNotFound
+	}
+	if f.UserId != req.GetOwnerId() {
+		return nil, ErrNotOwner
+	}
+	resp := &feed.GetCommentsResponse{Comments: []*feed.Comment{}}
+	for _, c := range sh.comments[req.GetFeedId()] {
+		if c.ParentId != req.GetParentId() || c.Id <= req.GetCursor() {
+			continue
+		}
+		if int64(len(resp.Comments)) == size {
+			resp.NextCursor = resp.Comments[size-1].Id
+			break
+		}
+		// comments are shared, the reply count is set on a copy.
+		reply := *c
+		reply.ReplyCount = sh.replies[c.Id]
+		resp.Comments = append(resp.Comments, &reply)
+	}
+	return resp, nil
+}
@@ -0,0 +1,227 @@
+package feed
+
+import (
+	"sync"
+	"time"
+
+	"github.com/buptmiao/microservice-app/proto/feed"
+	"github.com/buptmiao/microservice-app/search"
+)
+
+// numShards is the number of lock stripes of the feed storage.
+const numShards = 32
+
+// shard holds the feeds of the users hashed to it, and everything keyed by
+// those feeds, under a single lock. Writes of users in different shards do
+// not contend.
+type shard struct {
+	mu sync.RWMutex
+
+	// user id to its feeds.
+	mem map[int64]map[int64]*feed.FeedRecord
+	// feed id to its live record.
+	records map[int64]*feed.FeedRecord
+	// every version of each feed, oldest first.
+	history map[int64][]*feed.FeedRevision
+
+	// users who like each feed.
+	likes map[int64]map[int64]bool
+	// comments of each feed in creation order, all threads mixed.
+	comments map[int64][]*feed.Comment
+	// comment id to its feed id, and to its number of direct replies.
+	commentFeed map[int64]int64
+	replies     map[int64]int64
+
+	// events written together with the records, see outbox.go.
+	outbox []*OutboxEvent
+}
+
+func newShard() *shard {
+	return &shard{
+		mem:         make(map[int64]map[int64]*feed.FeedRecord),
+		records:     make(map[int64]*feed.FeedRecord),
+		history:     make(map[int64][]*feed.FeedRevision),
+		likes:       make(map[int64]map[int64]bool),
+		comments:    make(map[int64][]*feed.Comment),
+		commentFeed: make(map[int64]int64),
+		replies:     make(map[int64]int64),
+	}
+}
+
+// Storage
+var (
+	shards [numShards]*shard
+
+	// feed id to the user owning it. Feed ids are unique across users, this
+	// is the only lock every CreateFeed goes through, and it is held briefly.
+	owners   map[int64]int64
+	ownersMu sync.RWMutex
+
+	// full-text index over the feed contents, keyed by feed id.
+	index *search.Index
+
+	// feed ids linked to each topic, oldest first. Lock order is shard.mu
+	// then topicMu.
+	topicFeeds map[int64][]int64
+	topicMu    sync.RWMutex
+)
+
+func init() {
+	for i := range shards {
+		shards[i] = newShard()
+	}
+	owners = make(map[int64]int64)
+	index = search.NewIndex()
+	topicFeeds = make(map[int64][]int64)
+}
+
+func shardFor(userID int64) *shard {
+	return shards[uint64(userID)%numShards]
+}
+
+// shardOfFeed returns the shard holding feed id, nil if it does not exist.
+func shardOfFeed(id int64) *shard {
+	ownersMu.RLock()
+	userID, ok := owners[id]
+	ownersMu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return shardFor(userID)
+}
+
+// inOtherShard tells if feed id exists in another shard than sh, which
+// means it is owned by a user of that shard.
+func inOtherShard(id int64, sh *shard) bool {
+	other := shardOfFeed(id)
+	return other != nil && other != sh
+}
+
+// claim reserves feed id for userID, it fails when the id is taken.
+func claim(id, userID int64) bool {
+	ownersMu.Lock()
+	defer ownersMu.Unlock()
+	if _, ok := owners[id]; ok {
+		return false
+	}
+	owners[id] = userID
+	return true
+}
+
+// release frees a claimed id whose feed was never stored.
+func release(id int64) {
+	ownersMu.Lock()
+	defer ownersMu.Unlock()
+	delete(owners, id)
+}
+
+// live returns the record id if it exists and is not deleted. The caller
+// must hold sh.mu.
+func (sh *shard) live(id int64) (*feed.FeedRecord, bool) {
+	f, ok := sh.records[id]
+	if !ok || f.Deleted {
+		return nil, false
+	}
+	return f, true
+}
+
+// owned returns the live record id if ownerID owns it. The caller must hold
+// sh.mu.
+func (sh *shard) owned(id, ownerID int64) (*feed.FeedRecord, error) {
+	f, ok := sh.live(id)
+	if !ok {
+		return nil, ErrFeedNotFound
+	}
+	if f.UserId != ownerID {
+		return nil, ErrNotOwner
+	}
+	return f, nil
+}
+
+// checkWrite returns the live record id if userID owns it and, when
+// expectedVersion is not zero, it is still at that version. The caller must
+// hold sh.mu.
+func (sh *shard) checkWrite(id, userID, expectedVersion int64) (*feed.FeedRecord, error) {
+	f, ok := sh.live(id)
+	if !ok {
+		return nil, ErrFeedNotFound
+	}
+	if f.UserId != userID {
+		return nil, ErrNotOwner
+	}
+	if expectedVersion != 0 && f.Version != expectedVersion {
+		return nil, ErrVersionConflict
+	}
+	return f, nil
+}
+
+// store saves a new version of a record and keeps the secondary indexes and
+// the history in sync. The caller must hold sh.mu for writing.
+func (sh *shard) store(f *feed.FeedRecord) {
+	old, replaced := sh.records[f.Id]
+	userFeeds, ok := sh.mem[f.UserId]
+	if !ok {
+		userFeeds = make(map[int64]*feed.FeedRecord)
+		sh.mem[f.UserId] = userFeeds
+	}
+	userFeeds[f.Id] = f
+	sh.records[f.Id] = f
+	sh.history[f.Id] = append(sh.history[f.Id], &feed.FeedRevision{
+		Version:    f.Version,
+		Content:    f.Content,
+		Deleted:    f.Deleted,
+		ModifiedAt: time.Now().UnixNano(),
+	})
+
+	topicMu.Lock()
+	defer topicMu.Unlock()
+	if replaced {
+		unlinkTopics(old)
+	}
+	if f.Deleted {
+		index.Remove(f.Id)
+		return
+	}
+	index.Add(f.Id, f.Content)
+	for _, id := range f.TopicIds {
+		topicFeeds[id] = append(topicFeeds[id], f.Id)
+	}
+}
+
+// unlinkTopics removes a replaced record from its topics. The caller must
+// hold topicMu for writing.
+func unlinkTopics(f *feed.FeedRecord) {
+	for _, topicID := range f.TopicIds {
+		ids := topicFeeds[topicID]
+		for i, id := range ids {
+			if id == f.Id {
+				topicFeeds[topicID] = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// withCounters returns a copy of f carrying its like and comment counts. The
+// caller must hold sh.mu.
+func (sh *shard) withCounters(f *feed.FeedRecord) *feed.FeedRecord {
+	res := *f
+	res.LikeCount = int64(len(sh.likes[f.Id]))
+	res.CommentCount = int64(len(sh.comments[f.Id]))
+	return &res
+}
+
+// lookup returns a copy of the live feed id with its counters.
+func lookup(id int64) (*feed.FeedRecord, bool) {
+	sh := shardOfFeed(id)
+	if sh == nil {
+		return nil, false
+	}
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	f, ok := sh.live(id)
+	if !ok {
+		return nil, false
+	}
+	return sh.withCounters(f), true
+}
@@ -0,0 +1,57 @@
+package feed
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/buptmiao/microservice-app/search"
+)
+
+// ParseContent extracts the #hashtags and @mentions of a post. Hashtags are
+// normalized with search.Tag, both lists are deduplicated and keep the order
+// of first appearance.
+func ParseContent(content string) (hashtags, mentions []string) {
+	seen := make(map[string]bool)
+	for i := 0; i < len(content); {
+		c := content[i]
+		if (c != '#' && c != '@') || !isBoundary(content, i) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(content) {
+			r, size := utf8.DecodeRuneInString(content[j:])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+				break
+			}
+			j += size
+		}
+		word := content[i+1 : j]
+		i = j
+		if word == "" {
+			continue
+		}
+		if c == '#' {
+			word = search.Tag(word)
+		}
+		if key := string(c) + word; word != "" && !seen[key] {
+			seen[key] = true
+			if c == '#' {
+				hashtags = append(hashtags, word)
+			} else {
+				mentions = append(mentions, word)
+			}
+		}
+	}
+	return hashtags, mentions
+}
+
+// isBoundary reports whether the marker at i starts a new word, so that
+// e-mail addresses and anchors like "a#b" are not taken for tags.
+func isBoundary(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
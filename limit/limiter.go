@@ -0,0 +1,188 @@
+// Package limit implements the adaptive concurrency limit of the services.
+// The limit follows the latency of the requests: it grows while the latency
+// stays close to its long term average and shrinks as soon as requests start
+// queueing, the requests over the limit are rejected right away.
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrLimitExceeded is returned for the requests shed by the limiter.
+var ErrLimitExceeded = status.Error(codes.ResourceExhausted, "concurrency limit exceeded")
+
+// Priority tells which requests are shed first.
+type Priority int
+
+const (
+	// Read requests are shed first, a client can retry them on another
+	// instance or do without.
+	Read Priority = iota
+	// Write requests may use the whole limit.
+	Write
+)
+
+func (p Priority) String() string {
+	if p == Write {
+		return "write"
+	}
+	return "read"
+}
+
+const (
+	initialLimit = 20
+	minLimit     = 4
+	maxLimit     = 1000
+
+	// the latency may grow by this factor before the limit shrinks.
+	tolerance = 1.5
+	// weight of a new estimate in the limit.
+	smoothing = 0.2
+	// number of samples the long term latency is averaged over.
+	longWindow = 600
+	// reads may only use this share of the limit, the rest is kept for writes.
+	readShare = 0.9
+)
+
+// Limiter is a gradient concurrency limit. It compares the latency of each
+// request with the long term average latency: the ratio, capped to [0.5, 1],
+// scales the limit down, and a queue of sqrt(limit) requests is allowed on
+// top so that the limit keeps probing for more capacity.
+type Limiter struct {
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	// long term average latency, in seconds.
+	longRTT float64
+	gauge   metrics.Gauge
+}
+
+// the limiters of the process, for the admin endpoints.
+var (
+	limitersMu sync.Mutex
+	limiters   []*Limiter
+)
+
+// NewLimiter returns a Limiter reporting its current limit to gauge.
+func NewLimiter(gauge metrics.Gauge) *Limiter {
+	gauge.Set(initialLimit)
+	l := &Limiter{limit: initialLimit, gauge: gauge}
+	limitersMu.Lock()
+	limiters = append(limiters, l)
+	limitersMu.Unlock()
+	return l
+}
+
+// Limit returns the current number of concurrent requests allowed.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// Stats is a snapshot of a Limiter.
+type Stats struct {
+	Limit    int `json:"limit"`
+	Inflight int `json:"inflight"`
+	// Available is the number of requests which may start, writes included.
+	Available int `json:"available"`
+	// LatencySeconds is the long term average latency.
+	LatencySeconds float64 `json:"latency_seconds"`
+}
+
+// Stats returns the current state of l.
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := Stats{Limit: int(l.limit), Inflight: l.inflight, LatencySeconds: l.longRTT}
+	if s.Available = s.Limit - s.Inflight; s.Available < 0 {
+		s.Available = 0
+	}
+	return s
+}
+
+// All returns the Stats of every Limiter of the process.
+func All() []Stats {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	res := make([]Stats, 0, len(limiters))
+	for _, l := range limiters {
+		res = append(res, l.Stats())
+	}
+	return res
+}
+
+// acquire reserves a slot for a request of priority p and returns the number
+// of requests in flight, itself included. It fails when the limit is reached.
+func (l *Limiter) acquire(p Priority) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	max := l.limit
+	if p == Read {
+		max *= readShare
+	}
+	if float64(l.inflight) >= max {
+		return l.inflight, false
+	}
+	l.inflight++
+	return l.inflight, true
+}
+
+// release frees the slot of a request which took rtt with inflight requests
+// in flight, and updates the limit.
+func (l *Limiter) release(rtt time.Duration, inflight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+
+	short := rtt.Seconds()
+	if short <= 0 {
+		return
+	}
+	if l.longRTT == 0 {
+		l.longRTT = short
+	} else {
+		l.longRTT += (short - l.longRTT) / longWindow
+	}
+	// the latency dropped well below the average, typically after an
+	// overload: forget the slow period faster.
+	if l.longRTT/short > 2 {
+		l.longRTT *= 0.95
+	}
+	// the limit is not used, the latency tells nothing about it.
+	if float64(inflight) < l.limit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, tolerance*l.longRTT/short))
+	next := l.limit*gradient + math.Sqrt(l.limit)
+	next = l.limit*(1-smoothing) + next*smoothing
+	l.limit = math.Max(minLimit, math.Min(maxLimit, next))
+	l.gauge.Set(l.limit)
+}
+
+// Middleware rejects the requests over the limit of l with ErrLimitExceeded
+// and counts them in shed by priority.
+func Middleware(l *Limiter, p Priority, shed metrics.Counter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			inflight, ok := l.acquire(p)
+			if !ok {
+				shed.With("priority", p.String()).Add(1)
+				return nil, ErrLimitExceeded
+			}
+			defer func(begin time.Time) {
+				l.release(time.Since(begin), inflight)
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/discard"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterShedsReadsFirst(t *testing.T) {
+	l := NewLimiter(discard.NewGauge())
+	for i := 0; i < initialLimit*readShare; i++ {
+		if _, ok := l.acquire(Read); !ok {
+			t.Fatalf("read %d shed under the limit", i)
+		}
+	}
+	if _, ok := l.acquire(Read); ok {
+		t.Fatal("read over its share of the limit admitted")
+	}
+	for i := initialLimit * readShare; i < initialLimit; i++ {
+		if _, ok := l.acquire(Write); !ok {
+			t.Fatal("write shed under the limit")
+		}
+	}
+	if _, ok := l.acquire(Write); ok {
+		t.Fatal("write over the limit admitted")
+	}
+
+	ep := Middleware(l, Write, discard.NewCounter())(func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if _, err := ep(context.Background(), nil); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("want ResourceExhausted, got %v", err)
+	}
+}
+
+func TestLimiterFollowsLatency(t *testing.T) {
+	l := NewLimiter(discard.NewGauge())
+	busy := func(rtt time.Duration, n int) {
+		for i := 0; i < n; i++ {
+			l.acquire(Write)
+			l.release(rtt, l.Limit())
+		}
+	}
+	busy(10*time.Millisecond, 50)
+	grown := l.Limit()
+	if grown <= initialLimit {
+		t.Fatalf("limit did not grow at a steady latency: %d", grown)
+	}
+	busy(100*time.Millisecond, 50)
+	if l.Limit() >= grown {
+		t.Fatalf("limit did not shrink when the latency grew: %d >= %d", l.Limit(), grown)
+	}
+	if l.Limit() < minLimit {
+		t.Fatalf("limit below its minimum: %d", l.Limit())
+	}
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/go-kit/kit/log"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// gRPC metadata keys of the Fields.
+const (
+	MetadataRequestID = "x-request-id"
+	MetadataIdentity  = "x-identity"
+)
+
+// Fields are the request scoped values logged with every line about the
+// request, across the services it goes through.
+type Fields struct {
+	// RequestID is given by the gateway, or by the client of the gateway.
+	RequestID string
+	// Identity is the user on whose behalf the request is made.
+	Identity string
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx carrying f.
+func WithFields(ctx context.Context, f Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// FieldsFrom returns the Fields of ctx, empty ones if it has none.
+func FieldsFrom(ctx context.Context) Fields {
+	f, _ := ctx.Value(fieldsKey{}).(Fields)
+	return f
+}
+
+// NewRequestID returns a random request id.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// With returns logger with the trace id, the span id and the Fields of ctx
+// which are set.
+func With(ctx context.Context, logger log.Logger) log.Logger {
+	var keyvals []interface{}
+	if sc := spanContext(ctx); sc.IsValid() {
+		keyvals = append(keyvals, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+	}
+	f := FieldsFrom(ctx)
+	if f.RequestID != "" {
+		keyvals = append(keyvals, "request_id", f.RequestID)
+	}
+	if f.Identity != "" {
+		keyvals = append(keyvals, "identity", f.Identity)
+	}
+	if len(keyvals) == 0 {
+		return logger
+	}
+	return log.With(logger, keyvals...)
+}
+
+// spanContext returns the OpenTelemetry span context of ctx. The go-kit
+// middlewares keep the span in the OpenTracing slot, its context exposes the
+// ids when it comes from the OpenTelemetry bridge, see package telemetry.
+func spanContext(ctx context.Context) trace.SpanContext {
+	if span := stdopentracing.SpanFromContext(ctx); span != nil {
+		if sc, ok := span.Context().(interface {
+			TraceID() trace.TraceID
+			SpanID() trace.SpanID
+			TraceFlags() trace.TraceFlags
+		}); ok {
+			return trace.NewSpanContext(trace.SpanContextConfig{TraceID: sc.TraceID(), SpanID: sc.SpanID(), TraceFlags: sc.TraceFlags()})
+		}
+	}
+	return trace.SpanContextFromContext(ctx)
+}
+
+// ContextToGRPC sends the Fields of ctx along with the request, for the
+// grpctransport.ClientBefore of the clients.
+func ContextToGRPC(ctx context.Context, md *metadata.MD) context.Context {
+	f := FieldsFrom(ctx)
+	if f.RequestID != "" {
+		(*md)[MetadataRequestID] = []string{f.RequestID}
+	}
+	if f.Identity != "" {
+		(*md)[MetadataIdentity] = []string{f.Identity}
+	}
+	return ctx
+}
+
+// GRPCToContext reads the Fields sent by ContextToGRPC, for the
+// grpctransport.ServerBefore of the services.
+func GRPCToContext(ctx context.Context, md metadata.MD) context.Context {
+	f := Fields{}
+	if v := md.Get(MetadataRequestID); len(v) > 0 {
+		f.RequestID = v[0]
+	}
+	if v := md.Get(MetadataIdentity); len(v) > 0 {
+		f.Identity = v[0]
+	}
+	return WithFields(ctx, f)
+}
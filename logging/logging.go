@@ -0,0 +1,114 @@
+// Package logging provides the leveled JSON loggers of the processes and the
+// request scoped fields carried by their lines: the trace and span ids, the
+// request id and the identity of the caller.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// Level names, from the most to the least verbose.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+var levels = []string{LevelDebug, LevelInfo, LevelWarn, LevelError}
+
+func rank(name string) (int32, bool) {
+	for i, l := range levels {
+		if l == name {
+			return int32(i), true
+		}
+	}
+	return 0, false
+}
+
+// Level is the minimum level of the lines logged. It can be changed at
+// runtime, it is an http.Handler for the debug listener.
+type Level struct {
+	rank int32
+}
+
+// NewLevel returns a Level set to name.
+func NewLevel(name string) (*Level, error) {
+	l := &Level{}
+	if err := l.Set(name); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Level) Set(name string) error {
+	r, ok := rank(name)
+	if !ok {
+		return fmt.Errorf("unknown log level %q", name)
+	}
+	atomic.StoreInt32(&l.rank, r)
+	return nil
+}
+
+func (l *Level) String() string {
+	return levels[atomic.LoadInt32(&l.rank)]
+}
+
+// Filter drops the lines of next below the level. Lines without a level are
+// info ones.
+func (l *Level) Filter(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(keyvals ...interface{}) error {
+		r, _ := rank(LevelInfo)
+		for i := 0; i < len(keyvals)-1; i += 2 {
+			if keyvals[i] != level.Key() {
+				continue
+			}
+			if v, ok := keyvals[i+1].(level.Value); ok {
+				r, _ = rank(v.String())
+			}
+			break
+		}
+		if r < atomic.LoadInt32(&l.rank) {
+			return nil
+		}
+		return next.Log(keyvals...)
+	})
+}
+
+// ServeHTTP returns the level on GET, and changes it on PUT, e.g.
+// curl -XPUT localhost:6062/debug/loglevel?level=debug. The binaries only
+// accept the changes carrying the admin token, see admin.Protect.
+func (l *Level) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if err := l.Set(r.FormValue("level")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"level": l.String()})
+}
+
+// NewLogger returns a JSON logger writing to w the lines of lvl and above,
+// with the timestamp, the source location and the service on every line.
+func NewLogger(w io.Writer, lvl *Level, service string) log.Logger {
+	var logger log.Logger
+	logger = log.NewJSONLogger(log.NewSyncWriter(w))
+	logger = lvl.Filter(logger)
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC)
+	logger = log.With(logger, "caller", log.DefaultCaller)
+	logger = log.With(logger, "service", service)
+	return logger
+}
@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+func TestLevelAndFields(t *testing.T) {
+	var buf bytes.Buffer
+	lvl, err := NewLevel(LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithFields(context.Background(), Fields{RequestID: "r1", Identity: "42"})
+	logger := With(ctx, NewLogger(&buf, lvl, "test"))
+
+	level.Debug(logger).Log("msg", "hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug line logged at info: %s", buf.String())
+	}
+	level.Info(logger).Log("msg", "shown")
+	line := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line["request_id"] != "r1" || line["identity"] != "42" || line["service"] != "test" {
+		t.Fatalf("missing fields in %v", line)
+	}
+
+	buf.Reset()
+	lvl.Set(LevelDebug)
+	level.Debug(logger).Log("msg", "shown")
+	if buf.Len() == 0 {
+		t.Fatal("debug line not logged after the level changed")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	type user struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+		Friends  []user `json:"friends"`
+	}
+	redactMu.Lock()
+	saved := make(map[string]bool, len(redacted))
+	for k, v := range redacted {
+		saved[k] = v
+	}
+	redactMu.Unlock()
+	defer func() {
+		redactMu.Lock()
+		redacted = saved
+		redactMu.Unlock()
+	}()
+	Redact("name")
+	var buf bytes.Buffer
+	log.NewJSONLogger(&buf).Log("request", Payload(user{Name: "a", Password: "p", Friends: []user{{Password: "q"}}}))
+	if strings.Contains(buf.String(), `"p"`) || strings.Contains(buf.String(), `"q"`) || strings.Contains(buf.String(), `"a"`) {
+		t.Fatalf("redacted fields logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// redactedValue replaces the values of the redacted fields.
+const redactedValue = "[REDACTED]"
+
+var (
+	redactMu sync.RWMutex
+	// names of the fields never logged, in the JSON form of the payloads.
+	redacted = map[string]bool{
+		"password":      true,
+		"token":         true,
+		"secret":        true,
+		"authorization": true,
+		"email":         true,
+		"phone":         true,
+	}
+)
+
+// Redact adds fields to the fields whose values are never logged, matched
+// case-insensitively at any depth of the payloads, e.g. "content".
+func Redact(fields ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			redacted[strings.ToLower(f)] = true
+		}
+	}
+}
+
+// Payload wraps a request or a response for the JSON loggers, with the
+// redacted fields masked. The work is only done when the line is written,
+// it is free when the level filters it out.
+func Payload(v interface{}) interface{} {
+	return payload{v}
+}
+
+type payload struct {
+	v interface{}
+}
+
+func (p payload) MarshalJSON() ([]byte, error) {
+	raw, err := json.Marshal(p.v)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, err
+	}
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	return json.Marshal(redact(tree))
+}
+
+func redact(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, child := range x {
+			if redacted[strings.ToLower(k)] {
+				x[k] = redactedValue
+				continue
+			}
+			x[k] = redact(child)
+		}
+	case []interface{}:
+		for i, child := range x {
+			x[i] = redact(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := strings.Repeat("ab", 32)
+
+	if err := s.Put(ctx, "../etc/passwd", strings.NewReader("x")); err != ErrInvalidKey {
+		t.Errorf("invalid key got %v", err)
+	}
+	if _, err := s.Get(ctx, key); err != ErrBlobNotFound {
+		t.Errorf("missing blob got %v", err)
+	}
+	if err := s.Put(ctx, key, strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Exists(ctx, key); !ok || err != nil {
+		t.Fatalf("stored blob exists %v, %v", ok, err)
+	}
+	rc, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if string(b) != "content" {
+		t.Errorf("got %q", b)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Exists(ctx, key); ok {
+		t.Error("deleted blob exists")
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing blob got %v", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	var src bytes.Buffer
+	png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 800, 400)))
+	var thumb bytes.Buffer
+	if err := Thumbnail(&thumb, &src); err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(&thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != ThumbnailSize/2 {
+		t.Errorf("got a %dx%d thumbnail", b.Dx(), b.Dy())
+	}
+	if err := Thumbnail(&thumb, strings.NewReader("not an image")); err == nil {
+		t.Error("garbage decoded")
+	}
+}
+
+func TestThumbnailBomb(t *testing.T) {
+	var src bytes.Buffer
+	if err := gif.Encode(&src, image.NewPaletted(image.Rect(0, 0, 1, 1), palette.Plan9), nil); err != nil {
+		t.Fatal(err)
+	}
+	// claim a 65535x65535 screen in the header, 4 gigapixels.
+	b := src.Bytes()
+	copy(b[6:10], []byte{0xff, 0xff, 0xff, 0xff})
+	if err := Thumbnail(ioutil.Discard, bytes.NewReader(b)); err != ErrImageTooLarge {
+		t.Errorf("got %v, want ErrImageTooLarge", err)
+	}
+}
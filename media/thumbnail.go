@@ -0,0 +1,81 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// ThumbnailSize is the maximum width and height of a thumbnail.
+const ThumbnailSize = 200
+
+// ThumbnailSuffix makes the key of the thumbnail of a blob.
+const ThumbnailSuffix = "_thumb"
+
+// MaxPixels is the largest image decoded, a few bytes of a compressed image
+// may claim dimensions which would take gigabytes once decoded.
+const MaxPixels = 40 << 20
+
+var ErrImageTooLarge = errors.New("image too large")
+
+// Thumbnail decodes a gif, jpeg or png image and writes a jpeg copy of it
+// scaled down to fit in ThumbnailSize x ThumbnailSize. Images already small
+// enough are re-encoded unscaled. The images over MaxPixels are refused
+// before being decoded.
+func Thumbnail(w io.Writer, r io.Reader) error {
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return ErrImageTooLarge
+	}
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return err
+	}
+	return jpeg.Encode(w, scale(src, ThumbnailSize), &jpeg.Options{Quality: 80})
+}
+
+// scale shrinks img with a box filter, keeping its aspect ratio.
+func scale(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+	tw, th := max, h*max/w
+	if h > w {
+		tw, th = w*max/h, max
+	}
+	if tw == 0 {
+		tw = 1
+	}
+	if th == 0 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,73 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// BlockRule matches the posts containing Keyword, whatever its case, or
+// matching Regexp. The Reason is shown to the author, it defaults to a
+// message which does not repeat the blocked words.
+type BlockRule struct {
+	Keyword string  `json:"keyword,omitempty"`
+	Regexp  string  `json:"regexp,omitempty"`
+	Verdict Verdict `json:"verdict"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+type blockRule struct {
+	BlockRule
+	keyword string
+	re      *regexp.Regexp
+}
+
+// Blocklist is the Stage checking the content of the posts against a list
+// of keywords and regular expressions.
+type Blocklist struct {
+	rules []blockRule
+}
+
+func NewBlocklist(rules []BlockRule) (*Blocklist, error) {
+	b := &Blocklist{}
+	for i, r := range rules {
+		if (r.Keyword == "") == (r.Regexp == "") {
+			return nil, fmt.Errorf("blocklist rule %d: set either keyword or regexp", i)
+		}
+		if r.Verdict == Allow {
+			return nil, fmt.Errorf("blocklist rule %d: verdict must be hold or reject", i)
+		}
+		compiled := blockRule{BlockRule: r, keyword: strings.ToLower(r.Keyword)}
+		if r.Regexp != "" {
+			re, err := regexp.Compile(r.Regexp)
+			if err != nil {
+				return nil, fmt.Errorf("blocklist rule %d: %v", i, err)
+			}
+			compiled.re = re
+		}
+		if compiled.Reason == "" {
+			compiled.Reason = "content is not allowed"
+		}
+		b.rules = append(b.rules, compiled)
+	}
+	return b, nil
+}
+
+func (b *Blocklist) Name() string { return "blocklist" }
+
+// Check returns the strictest verdict of the rules matching p.
+func (b *Blocklist) Check(_ context.Context, p *Post) Decision {
+	res := Decision{Verdict: Allow}
+	lower := strings.ToLower(p.Content)
+	for _, r := range b.rules {
+		if r.Verdict <= res.Verdict {
+			continue
+		}
+		if (r.re != nil && r.re.MatchString(p.Content)) || (r.keyword != "" && strings.Contains(lower, r.keyword)) {
+			res = Decision{Verdict: r.Verdict, Reason: r.Reason}
+		}
+	}
+	return res
+}
@@ -0,0 +1,71 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Config declares the stages of the chain, see moderation/policy.json. The
+// chain runs the blocklist, then the links, then the rates, so that the
+// posts rejected for their content do not count in the rates.
+type Config struct {
+	Blocklist []BlockRule `json:"blocklist"`
+	Links     *LinkRules  `json:"links"`
+	Rates     []RateRule  `json:"rates"`
+}
+
+// LinkRules map the domains to their verdict, the Unknown verdict applying
+// to the other domains.
+type LinkRules struct {
+	Hosts   map[string]Verdict `json:"hosts"`
+	Unknown Verdict            `json:"unknown"`
+}
+
+// RateRule gives the verdict on the posts of a user beyond Max in Window,
+// e.g. "10m".
+type RateRule struct {
+	Max     int     `json:"max"`
+	Window  string  `json:"window"`
+	Verdict Verdict `json:"verdict"`
+}
+
+// Load reads the Config of a JSON file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Chain builds the stages of c.
+func (c *Config) Chain() (*Chain, error) {
+	var stages []Stage
+	if len(c.Blocklist) > 0 {
+		b, err := NewBlocklist(c.Blocklist)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, b)
+	}
+	if c.Links != nil {
+		stages = append(stages, NewLinks(c.Links.Hosts, c.Links.Unknown))
+	}
+	for i, r := range c.Rates {
+		window, err := time.ParseDuration(r.Window)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %v", i, err)
+		}
+		if r.Max <= 0 || window <= 0 || r.Verdict == Allow {
+			return nil, fmt.Errorf("rate %d: max and window must be positive, verdict hold or reject", i)
+		}
+		stages = append(stages, NewRate(r.Max, window, r.Verdict))
+	}
+	return NewChain(stages...), nil
+}
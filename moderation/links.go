@@ -0,0 +1,67 @@
+package moderation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// link finds the links of a post, those with a scheme or starting with www.
+var link = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// Links is the Stage checking the hosts of the links of a post against a
+// reputation list.
+type Links struct {
+	hosts   map[string]Verdict
+	unknown Verdict
+}
+
+// NewLinks returns a Links stage giving the verdict of hosts to the links
+// to a domain or any of its subdomains, and the unknown verdict to the
+// links to the other domains.
+func NewLinks(hosts map[string]Verdict, unknown Verdict) *Links {
+	l := &Links{hosts: make(map[string]Verdict, len(hosts)), unknown: unknown}
+	for h, v := range hosts {
+		l.hosts[strings.ToLower(strings.TrimSuffix(h, "."))] = v
+	}
+	return l
+}
+
+func (l *Links) Name() string { return "links" }
+
+// Check returns the strictest verdict of the links of p.
+func (l *Links) Check(_ context.Context, p *Post) Decision {
+	res := Decision{Verdict: Allow}
+	for _, s := range link.FindAllString(p.Content, -1) {
+		if !strings.Contains(s, "://") {
+			s = "http://" + s
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		if v := l.verdict(host); v > res.Verdict {
+			res = Decision{Verdict: v, Reason: fmt.Sprintf("links to %s", host)}
+		}
+	}
+	return res
+}
+
+// verdict looks host up, then its parent domains.
+func (l *Links) verdict(host string) Verdict {
+	for h := host; h != ""; {
+		if v, ok := l.hosts[h]; ok {
+			return v
+		}
+		i := strings.IndexByte(h, '.')
+		if i < 0 {
+			break
+		}
+		h = h[i+1:]
+	}
+	return l.unknown
+}
@@ -0,0 +1,132 @@
+// Package moderation passes the new posts through a chain of stages, each
+// of which may allow, hold for review or reject them: a keyword and regular
+// expression blocklist, a per-user posting rate, and a link reputation list.
+// The chain is declared in a JSON file, see moderation/policy.json, and the
+// feed service keeps the held posts in a review queue for the moderators.
+package moderation
+
+import (
+	"fmt"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Verdict is what a stage decides for a post, from the most to the least
+// lenient.
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Hold
+	Reject
+)
+
+var verdicts = []string{"allow", "hold", "reject"}
+
+func (v Verdict) String() string {
+	if v < 0 || int(v) >= len(verdicts) {
+		return fmt.Sprintf("Verdict(%d)", int(v))
+	}
+	return verdicts[v]
+}
+
+func (v Verdict) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *Verdict) UnmarshalText(b []byte) error {
+	for i, s := range verdicts {
+		if s == string(b) {
+			*v = Verdict(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown verdict %q", b)
+}
+
+// Post is what the stages look at.
+type Post struct {
+	ID      int64
+	UserID  int64
+	Content string
+}
+
+// Decision is the verdict on a post, the stage which made it and why.
+type Decision struct {
+	Verdict Verdict `json:"verdict"`
+	Stage   string  `json:"stage,omitempty"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// Stage is one check of the chain. Check returns the Allow verdict when
+// the stage has nothing against the post, the Stage of the Decision is
+// filled by the Chain.
+type Stage interface {
+	Name() string
+	Check(ctx context.Context, p *Post) Decision
+}
+
+var (
+	stageDecisions metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "moderation",
+		Name:      "stage_decisions_total",
+		Help:      "Number of posts checked by each stage, by verdict.",
+	}, []string{"stage", "verdict"})
+	outcomes metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "moderation",
+		Name:      "posts_total",
+		Help:      "Number of posts moderated, by final verdict and the stage which decided it.",
+	}, []string{"verdict", "stage"})
+)
+
+// Chain runs the stages in order and keeps the strictest verdict. A
+// rejection stops the chain, a hold does not, so that a later stage may
+// still reject the post.
+type Chain struct {
+	stages []Stage
+}
+
+func NewChain(stages ...Stage) *Chain {
+	return &Chain{stages: stages}
+}
+
+// Moderate returns the Decision of the chain on p, counted in the metrics.
+// A nil Chain allows everything.
+func (c *Chain) Moderate(ctx context.Context, p *Post) Decision {
+	res := Decision{Verdict: Allow}
+	if c != nil {
+		for _, s := range c.stages {
+			d := s.Check(ctx, p)
+			d.Stage = s.Name()
+			stageDecisions.With("stage", d.Stage, "verdict", d.Verdict.String()).Add(1)
+			if d.Verdict > res.Verdict {
+				res = d
+			}
+			if res.Verdict == Reject {
+				break
+			}
+		}
+	}
+	outcomes.With("verdict", res.Verdict.String(), "stage", res.Stage).Add(1)
+	return res
+}
+
+// Rejected is the error of a rejected post. It is a PermissionDenied status
+// telling the stage and the reason.
+type Rejected struct {
+	Decision
+}
+
+func (e *Rejected) Error() string {
+	return fmt.Sprintf("rejected by %s: %s", e.Stage, e.Reason)
+}
+
+// GRPCStatus makes gRPC send e as a PermissionDenied status.
+func (e *Rejected) GRPCStatus() *status.Status {
+	return status.New(codes.PermissionDenied, e.Error())
+}
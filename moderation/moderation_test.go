@@ -0,0 +1,82 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoad(t *testing.T) {
+	c, err := Load("policy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := c.Chain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(chain.stages); n != 4 {
+		t.Errorf("got %d stages, want 4", n)
+	}
+}
+
+func TestChain(t *testing.T) {
+	blocklist, err := NewBlocklist([]BlockRule{
+		{Keyword: "Casino", Verdict: Reject, Reason: "spam"},
+		{Regexp: `(?i)\bfree\b`, Verdict: Hold},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := NewLinks(map[string]Verdict{"bit.ly": Hold, "bad.example.": Reject}, Allow)
+	chain := NewChain(blocklist, links)
+	ctx := context.Background()
+	for _, c := range []struct {
+		content string
+		want    Decision
+	}{
+		{"hello world", Decision{Verdict: Allow}},
+		{"freedom", Decision{Verdict: Allow}},
+		{"FREE stuff", Decision{Hold, "blocklist", "content is not allowed"}},
+		{"see https://bit.ly/x", Decision{Hold, "links", "links to bit.ly"}},
+		{"free at www.cdn.Bad.example/x", Decision{Reject, "links", "links to www.cdn.bad.example"}},
+		{"free casino", Decision{Reject, "blocklist", "spam"}},
+	} {
+		if got := chain.Moderate(ctx, &Post{UserID: 1, Content: c.content}); got != c.want {
+			t.Errorf("%q: got %+v, want %+v", c.content, got, c.want)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	r := NewRate(2, time.Minute, Hold)
+	ctx := context.Background()
+	verdicts := []Verdict{}
+	for _, step := range []time.Duration{0, time.Second, time.Second, time.Minute, 0} {
+		clock = clock.Add(step)
+		verdicts = append(verdicts, r.Check(ctx, &Post{UserID: 1}).Verdict)
+	}
+	want := []Verdict{Allow, Allow, Hold, Allow, Allow}
+	for i := range want {
+		if verdicts[i] != want[i] {
+			t.Fatalf("got %v, want %v", verdicts, want)
+		}
+	}
+	if v := r.Check(ctx, &Post{UserID: 2}).Verdict; v != Allow {
+		t.Errorf("another user got %v", v)
+	}
+}
+
+func TestRejected(t *testing.T) {
+	err := error(&Rejected{Decision{Reject, "blocklist", "spam"}})
+	if st, _ := status.FromError(err); st.Code() != codes.PermissionDenied || st.Message() != "rejected by blocklist: spam" {
+		t.Errorf("got %v", st)
+	}
+}
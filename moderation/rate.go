@@ -0,0 +1,68 @@
+package moderation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// now is stubbed by the tests.
+var now = time.Now
+
+// Rate is the Stage holding or rejecting the posts of a user beyond max in
+// a sliding window. Every post checked counts, whatever the final decision
+// on it, so that a user retrying a rejected post does not get through. The
+// feed client routes the posts of a user to a single instance, which makes
+// the count exact.
+type Rate struct {
+	max     int
+	window  time.Duration
+	verdict Verdict
+
+	mu sync.Mutex
+	// user id to the times of its posts in the window, oldest first.
+	posts map[int64][]time.Time
+	swept time.Time
+}
+
+func NewRate(max int, window time.Duration, verdict Verdict) *Rate {
+	return &Rate{
+		max:     max,
+		window:  window,
+		verdict: verdict,
+		posts:   make(map[int64][]time.Time),
+		swept:   now(),
+	}
+}
+
+func (r *Rate) Name() string { return "rate" }
+
+func (r *Rate) Check(_ context.Context, p *Post) Decision {
+	t := now()
+	cut := t.Add(-r.window)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t.Sub(r.swept) > r.window {
+		// forget the users who stopped posting.
+		for user, times := range r.posts {
+			if !times[len(times)-1].After(cut) {
+				delete(r.posts, user)
+			}
+		}
+		r.swept = t
+	}
+	times := r.posts[p.UserID]
+	i := 0
+	for i < len(times) && !times[i].After(cut) {
+		i++
+	}
+	times = append(times[i:], t)
+	r.posts[p.UserID] = times
+	if len(times) > r.max {
+		return Decision{Verdict: r.verdict, Reason: fmt.Sprintf("more than %d posts in %v", r.max, r.window)}
+	}
+	return Decision{Verdict: Allow}
+}
@@ -0,0 +1,148 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ErrNoCA is returned when a peer must be verified without a CA.
+var ErrNoCA = errors.New("mtls: no CA to verify the peer with")
+
+// ServerConfig returns the TLS config of a server presenting the
+// certificate of r. With a CA, the clients must present a certificate
+// signed by it for one of the identities of peers, any identity when peers
+// is empty. Without, the clients are not authenticated, as on the public
+// listener of the gateway.
+func (r *Reloader) ServerConfig(peers []string) *tls.Config {
+	c := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	if r.config.CA != "" {
+		// the chain is verified by verify, with the CA loaded last.
+		c.ClientAuth = tls.RequireAnyClientCert
+		c.VerifyPeerCertificate = r.verify(peers, x509.ExtKeyUsageClientAuth)
+	}
+	return c
+}
+
+// ClientConfig returns the TLS config of a client presenting the
+// certificate of r to the servers of service, which must present a
+// certificate signed by the CA of r for that identity. The instances are
+// dialed by address, the identity is checked instead of the host name.
+func (r *Reloader) ClientConfig(service string) *tls.Config {
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: r.getClientCertificate,
+		// the chain and the identity are verified by verify.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: r.verify([]string{service}, x509.ExtKeyUsageServerAuth),
+	}
+}
+
+// verify checks the chain of the peer against the current CA and its
+// identity against identities, if any.
+func (r *Reloader) verify(identities []string, usage x509.ExtKeyUsage) func([][]byte, [][]*x509.Certificate) error {
+	return func(raw [][]byte, _ [][]*x509.Certificate) error {
+		_, pool := r.current()
+		if pool == nil {
+			return ErrNoCA
+		}
+		if len(raw) == 0 {
+			return errors.New("mtls: no peer certificate")
+		}
+		certs := make([]*x509.Certificate, 0, len(raw))
+		for _, b := range raw {
+			cert, err := x509.ParseCertificate(b)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		if _, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{usage},
+		}); err != nil {
+			return err
+		}
+		if len(identities) == 0 {
+			return nil
+		}
+		for _, name := range certs[0].DNSNames {
+			for _, id := range identities {
+				if name == id {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("mtls: peer %v is not one of %s", certs[0].DNSNames, strings.Join(identities, ", "))
+	}
+}
+
+// client is the Reloader of the connections to the services, see InitClient.
+var client atomic.Value
+
+// InitClient makes the clients dial the services with mutual TLS, with the
+// certificate of r.
+func InitClient(r *Reloader) {
+	client.Store(r)
+}
+
+// DialOption returns the transport credentials of the connections to the
+// instances of service: mutual TLS after InitClient, none before.
+func DialOption(service string) grpc.DialOption {
+	r, ok := client.Load().(*Reloader)
+	if !ok {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(r.ClientConfig(service)))
+}
+
+// ServerOption returns the transport credentials of a gRPC server with the
+// certificate of r, accepting the clients of peers, see ServerConfig.
+func ServerOption(r *Reloader, peers []string) grpc.ServerOption {
+	return grpc.Creds(credentials.NewTLS(r.ServerConfig(peers)))
+}
+
+// PeerIdentity returns the identity of the client of a gRPC request
+// authenticated with mutual TLS: the first DNS name of its certificate.
+func PeerIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return "", false
+	}
+	names := info.State.PeerCertificates[0].DNSNames
+	if len(names) == 0 {
+		return "", false
+	}
+	return names[0], true
+}
+
+// Peers splits a comma separated list of identities, for flags.
+func Peers(s string) []string {
+	var res []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			res = append(res, id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,125 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func reloader(t *testing.T, dir, identity string) *Reloader {
+	r, err := NewReloader(Config{
+		CA:   filepath.Join(dir, "ca.pem"),
+		Cert: filepath.Join(dir, identity+".pem"),
+		Key:  filepath.Join(dir, identity+"-key.pem"),
+	}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// handshake connects a client to a server, it returns the error of the
+// server, else of the client. With TLS 1.3 the client is done before the
+// server verifies its certificate.
+func handshake(server, client *tls.Config) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	errc := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer conn.Close()
+		errc <- tls.Server(conn, server).Handshake()
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		return err
+	}
+	cerr := tls.Client(conn, client).Handshake()
+	conn.Close()
+	if err := <-errc; err != nil {
+		return err
+	}
+	return cerr
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca, err := NewDevCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.WriteFiles(dir, time.Hour, "apigateway", "feed", "topic"); err != nil {
+		t.Fatal(err)
+	}
+	gateway, feed, topic := reloader(t, dir, "apigateway"), reloader(t, dir, "feed"), reloader(t, dir, "topic")
+
+	if err := handshake(topic.ServerConfig([]string{"apigateway", "feed"}), gateway.ClientConfig("topic")); err != nil {
+		t.Fatalf("the gateway must reach topic: %v", err)
+	}
+	if err := handshake(feed.ServerConfig([]string{"apigateway"}), topic.ClientConfig("feed")); err == nil {
+		t.Fatal("topic is not an allowed client of feed")
+	}
+	if err := handshake(feed.ServerConfig(nil), gateway.ClientConfig("topic")); err == nil {
+		t.Fatal("feed must not pass for topic")
+	}
+
+	// a certificate of another CA is refused until the CA is rotated too.
+	other, err := NewDevCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, keyPEM, err := other.Issue("apigateway", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(d time.Duration) { checkInterval = d }(checkInterval)
+	checkInterval = 0
+	later := time.Now().Add(time.Minute)
+	for name, b := range map[string][]byte{"apigateway.pem": certPEM, "apigateway-key.pem": keyPEM} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, later, later)
+	}
+	if err := handshake(topic.ServerConfig(nil), gateway.ClientConfig("topic")); err == nil {
+		t.Fatal("the rotated certificate of another CA must be refused")
+	}
+	ioutil.WriteFile(filepath.Join(dir, "ca.pem"), append(ca.CertPEM(), other.CertPEM()...), 0644)
+	later = later.Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "ca.pem"), later, later)
+	if err := handshake(topic.ServerConfig(nil), gateway.ClientConfig("topic")); err != nil {
+		t.Fatalf("the rotated CA must be reloaded: %v", err)
+	}
+}
+
+func TestCheckMutual(t *testing.T) {
+	for c, ok := range map[Config]bool{
+		{}: true,
+		{CA: "ca.pem", Cert: "c.pem", Key: "k.pem"}: true,
+		{Cert: "c.pem", Key: "k.pem"}:               false,
+		{CA: "ca.pem"}:                              false,
+		{CA: "ca.pem", Cert: "c.pem"}:               false,
+	} {
+		if err := c.CheckMutual(); (err == nil) != ok {
+			t.Errorf("%+v: got %v", c, err)
+		}
+	}
+}
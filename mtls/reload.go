@@ -0,0 +1,169 @@
+// Package mtls secures the traffic with TLS: the public listener of the
+// gateway, and the gRPC connections between the gateway and the services
+// with mutual TLS. The certificates are read from files and read again when
+// the files change, so that they can be rotated without a restart. A peer
+// is identified by the DNS names of its certificate, the name of its
+// service, e.g. feed.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// checkInterval is how often the files are checked for changes, at most.
+var checkInterval = 10 * time.Second
+
+// Config names the PEM files of a Reloader.
+type Config struct {
+	// CA holds the certificates of the authorities the peers are verified
+	// with, no peer is verified without.
+	CA string
+	// Cert and Key hold the certificate presented to the peers, with its
+	// intermediates, and its private key.
+	Cert, Key string
+}
+
+// Enabled tells whether a certificate is configured.
+func (c Config) Enabled() bool {
+	return c.Cert != "" && c.Key != ""
+}
+
+// CheckMutual checks a Config of mutual TLS between the services, which
+// must have all of CA, Cert and Key, or none: without the CA the peers are
+// not verified, without the certificate TLS is silently off.
+func (c Config) CheckMutual() error {
+	set := 0
+	for _, f := range []string{c.CA, c.Cert, c.Key} {
+		if f != "" {
+			set++
+		}
+	}
+	if set != 0 && set != 3 {
+		return errors.New("mtls: mutual TLS requires a CA, a certificate and a key")
+	}
+	return nil
+}
+
+// Reloader holds the certificate and the CA of a Config, loading them again
+// when the files change.
+type Reloader struct {
+	config Config
+	logger log.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+	checked time.Time
+}
+
+// NewReloader loads the files of c, it fails when they are invalid.
+func NewReloader(c Config, logger log.Logger) (*Reloader, error) {
+	if !c.Enabled() {
+		return nil, errors.New("mtls: a certificate and a key are required")
+	}
+	r := &Reloader{config: c, logger: logger}
+	modTime, err := r.lastModified()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.config.Cert, r.config.Key}
+	if r.config.CA != "" {
+		files = append(files, r.config.CA)
+	}
+	return files
+}
+
+// lastModified returns the latest modification time of the files.
+func (r *Reloader) lastModified() (time.Time, error) {
+	var last time.Time
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return last, err
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
+func (r *Reloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.config.Cert, r.config.Key)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if r.config.CA != "" {
+		b, err := ioutil.ReadFile(r.config.CA)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("mtls: no certificate in %s", r.config.CA)
+		}
+	}
+	r.mu.Lock()
+	r.cert, r.pool, r.modTime = &cert, pool, modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// current returns the certificate and the CA, after loading them again if
+// the files changed since the last check. The files of a rotation are not
+// written at once: a pair which does not match is skipped until the next
+// check, the previous one is kept meanwhile.
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	now := time.Now()
+	r.mu.Lock()
+	due := now.Sub(r.checked) >= checkInterval
+	if due {
+		r.checked = now
+	}
+	cert, pool, modTime := r.cert, r.pool, r.modTime
+	r.mu.Unlock()
+	if !due {
+		return cert, pool
+	}
+
+	last, err := r.lastModified()
+	if err != nil || !last.After(modTime) {
+		return cert, pool
+	}
+	if err := r.load(last); err != nil {
+		r.logger.Log("component", "mtls", "err", err)
+		return cert, pool
+	}
+	r.logger.Log("component", "mtls", "reloaded", r.config.Cert)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := r.current()
+	return cert, nil
+}
+
+func (r *Reloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := r.current()
+	return cert, nil
+}
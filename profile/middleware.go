@@ -2,10 +2,15 @@ package profile
 
 import (
 	"context"
-	"fmt"
+	"github.com/buptmiao/microservice-app/limit"
+	"github.com/buptmiao/microservice-app/logging"
 	"github.com/buptmiao/microservice-app/proto/profile"
+	"github.com/buptmiao/microservice-app/slo"
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/buptmiao/microservice-app/validate"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/kit/tracing/opentracing"
@@ -17,11 +22,27 @@ import (
 )
 
 var (
-	duration metrics.Histogram = prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+	duration metrics.Histogram = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
 		Namespace: "profile",
-		Name:      "request_duration_ns",
-		Help:      "Request duration in nanoseconds.",
-	}, []string{"method", "success"})
+		Name:      "request_duration_seconds",
+		Help:      "Request duration in seconds, by method and gRPC code.",
+		Buckets:   util.LatencyBuckets,
+	}, []string{"method", "code"})
+	requests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "profile",
+		Name:      "requests_total",
+		Help:      "Number of requests by method and gRPC code, the shed ones included.",
+	}, []string{"method", "code"})
+	concurrencyLimit metrics.Gauge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "profile",
+		Name:      "concurrency_limit",
+		Help:      "Current adaptive limit of concurrent requests.",
+	}, []string{})
+	shedRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "profile",
+		Name:      "shed_requests_total",
+		Help:      "Requests rejected by the concurrency limit.",
+	}, []string{"method", "priority"})
 )
 
 func EndpointInstrumentingMiddleware(duration metrics.Histogram) endpoint.Middleware {
@@ -29,19 +50,41 @@ func EndpointInstrumentingMiddleware(duration metrics.Histogram) endpoint.Middle
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				duration.With("success", fmt.Sprint(err == nil)).Observe(time.Since(begin).Seconds())
+				duration.With("code", util.Code(err)).Observe(time.Since(begin).Seconds())
 			}(time.Now())
 			return next(ctx, request)
 		}
 	}
 }
 
+// EndpointCountingMiddleware counts the requests by gRPC code. It wraps the
+// concurrency limit, so that the shed requests are counted too.
+func EndpointCountingMiddleware(requests metrics.Counter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func() {
+				requests.With("code", util.Code(err)).Add(1)
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// EndpointLoggingMiddleware logs every request with the trace and request
+// ids of its context, failures as errors. The payloads are logged at the
+// debug level, redacted.
 func EndpointLoggingMiddleware(logger log.Logger) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				logger.Log("error", err, "took", time.Since(begin))
+				logger := logging.With(ctx, logger)
+				if err != nil {
+					level.Error(logger).Log("error", err, "took", time.Since(begin))
+				} else {
+					level.Info(logger).Log("took", time.Since(begin))
+				}
+				level.Debug(logger).Log("request", logging.Payload(request), "response", logging.Payload(response))
 			}(time.Now())
 			return next(ctx, request)
 		}
@@ -65,11 +108,19 @@ func MakeGetPrifileEndpoint(s profile.ProfileServer, tracer stdopentracing.Trace
 func MakeGRPCServer(ctx context.Context, s profile.ProfileServer, tracer stdopentracing.Tracer, logger log.Logger) profile.ProfileServer {
 	options := []grpctransport.ServerOption{
 		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(logging.GRPCToContext),
+	}
+	limiter := limit.NewLimiter(concurrencyLimit)
+	limited := func(method string, p limit.Priority, ep endpoint.Endpoint) endpoint.Endpoint {
+		ep = limit.Middleware(limiter, p, shedRequests.With("method", method))(ep)
+		ep = validate.Middleware()(ep)
+		ep = slo.Middleware(method)(ep)
+		return EndpointCountingMiddleware(requests.With("method", method))(ep)
 	}
 
 	return &grpcServer{
 		getprofile: grpctransport.NewServer(
-			MakeGetPrifileEndpoint(s, tracer, logger),
+			limited("GetProfile", limit.Read, MakeGetPrifileEndpoint(s, tracer, logger)),
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "GetProfile", logger)))...,
@@ -1,14 +1,17 @@
 package profile
 
 import (
-	"errors"
 	"github.com/buptmiao/microservice-app/proto/profile"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"sync"
 )
 
+// The errors are gRPC statuses, so that the gateway can tell the faults of
+// the request from those of the service.
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound = status.Error(codes.NotFound, "user not found")
 )
 
 var (
@@ -10,9 +10,26 @@ It is generated from these files:
 
 It has these top-level messages:
 	GetFeedsRequest
+	SearchFeedsRequest
+	GetFeedsByTopicRequest
 	GetFeedsResponse
 	FeedRecord
+	Attachment
+	UpdateFeedRequest
+	DeleteFeedRequest
+	GetFeedHistoryRequest
+	FeedRevision
+	GetFeedHistoryResponse
 	OkResponse
+	LikeRequest
+	LikeResponse
+	Comment
+	GetCommentsRequest
+	GetCommentsResponse
+	HeldFeed
+	ListHeldFeedsRequest
+	ListHeldFeedsResponse
+	ReviewFeedRequest
 */
 package feed
 
@@ -60,6 +77,54 @@ func (m *GetFeedsRequest) GetSize() int64 {
 	return 0
 }
 
+type SearchFeedsRequest struct {
+	Query string `protobuf:"bytes,1,opt,name=query" json:"query,omitempty"`
+	Size  int64  `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+}
+
+func (m *SearchFeedsRequest) Reset()                    { *m = SearchFeedsRequest{} }
+func (m *SearchFeedsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SearchFeedsRequest) ProtoMessage()               {}
+func (*SearchFeedsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *SearchFeedsRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *SearchFeedsRequest) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type GetFeedsByTopicRequest struct {
+	TopicId int64 `protobuf:"varint,1,opt,name=topic_id,json=topicId" json:"topic_id,omitempty"`
+	Size    int64 `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+}
+
+func (m *GetFeedsByTopicRequest) Reset()                    { *m = GetFeedsByTopicRequest{} }
+func (m *GetFeedsByTopicRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetFeedsByTopicRequest) ProtoMessage()               {}
+func (*GetFeedsByTopicRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *GetFeedsByTopicRequest) GetTopicId() int64 {
+	if m != nil {
+		return m.TopicId
+	}
+	return 0
+}
+
+func (m *GetFeedsByTopicRequest) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
 type GetFeedsResponse struct {
 	Feeds []*FeedRecord `protobuf:"bytes,1,rep,name=feeds" json:"feeds,omitempty"`
 }
@@ -67,7 +132,7 @@ type GetFeedsResponse struct {
 func (m *GetFeedsResponse) Reset()                    { *m = GetFeedsResponse{} }
 func (m *GetFeedsResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetFeedsResponse) ProtoMessage()               {}
-func (*GetFeedsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*GetFeedsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *GetFeedsResponse) GetFeeds() []*FeedRecord {
 	if m != nil {
@@ -76,51 +141,705 @@ func (m *GetFeedsResponse) GetFeeds() []*FeedRecord {
 	return nil
 }
 
-type FeedRecord struct {
-	Id      int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
-	UserId  int64  `protobuf:"varint,2,opt,name=user_id,json=userId" json:"user_id,omitempty"`
-	Content string `protobuf:"bytes,3,opt,name=content" json:"content,omitempty"`
+type FeedRecord struct {
+	Id           int64         `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	UserId       int64         `protobuf:"varint,2,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	Content      string        `protobuf:"bytes,3,opt,name=content" json:"content,omitempty"`
+	Hashtags     []string      `protobuf:"bytes,4,rep,name=hashtags" json:"hashtags,omitempty"`
+	Mentions     []string      `protobuf:"bytes,5,rep,name=mentions" json:"mentions,omitempty"`
+	TopicIds     []int64       `protobuf:"varint,6,rep,packed,name=topic_ids,json=topicIds" json:"topic_ids,omitempty"`
+	Version      int64         `protobuf:"varint,7,opt,name=version" json:"version,omitempty"`
+	Deleted      bool          `protobuf:"varint,8,opt,name=deleted" json:"deleted,omitempty"`
+	LikeCount    int64         `protobuf:"varint,9,opt,name=like_count,json=likeCount" json:"like_count,omitempty"`
+	CommentCount int64         `protobuf:"varint,10,opt,name=comment_count,json=commentCount" json:"comment_count,omitempty"`
+	Attachments  []*Attachment `protobuf:"bytes,11,rep,name=attachments" json:"attachments,omitempty"`
+	// relevance of the feed to the query, set by SearchFeeds only.
+	Score float64 `protobuf:"fixed64,12,opt,name=score" json:"score,omitempty"`
+	// creation time of the feed in unix nanoseconds, set by the service.
+	CreatedAt int64 `protobuf:"varint,13,opt,name=created_at,json=createdAt" json:"created_at,omitempty"`
+	// set by UpdateFeed when the edit is held for review, the record is
+	// then the unchanged one.
+	Held bool `protobuf:"varint,14,opt,name=held" json:"held,omitempty"`
+}
+
+func (m *FeedRecord) Reset()                    { *m = FeedRecord{} }
+func (m *FeedRecord) String() string            { return proto.CompactTextString(m) }
+func (*FeedRecord) ProtoMessage()               {}
+func (*FeedRecord) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *FeedRecord) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *FeedRecord) GetHashtags() []string {
+	if m != nil {
+		return m.Hashtags
+	}
+	return nil
+}
+
+func (m *FeedRecord) GetMentions() []string {
+	if m != nil {
+		return m.Mentions
+	}
+	return nil
+}
+
+func (m *FeedRecord) GetTopicIds() []int64 {
+	if m != nil {
+		return m.TopicIds
+	}
+	return nil
+}
+
+func (m *FeedRecord) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *FeedRecord) GetLikeCount() int64 {
+	if m != nil {
+		return m.LikeCount
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetCommentCount() int64 {
+	if m != nil {
+		return m.CommentCount
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetAttachments() []*Attachment {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m *FeedRecord) GetScore() float64 {
+	if m != nil {
+		return m.Score
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *FeedRecord) GetHeld() bool {
+	if m != nil {
+		return m.Held
+	}
+	return false
+}
+
+// Attachment references a media blob uploaded through the gateway. The id is
+// the hex sha256 of the content.
+type Attachment struct {
+	Id           string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	ContentType  string `protobuf:"bytes,2,opt,name=content_type,json=contentType" json:"content_type,omitempty"`
+	Size         int64  `protobuf:"varint,3,opt,name=size" json:"size,omitempty"`
+	Url          string `protobuf:"bytes,4,opt,name=url" json:"url,omitempty"`
+	ThumbnailUrl string `protobuf:"bytes,5,opt,name=thumbnail_url,json=thumbnailUrl" json:"thumbnail_url,omitempty"`
+}
+
+func (m *Attachment) Reset()                    { *m = Attachment{} }
+func (m *Attachment) String() string            { return proto.CompactTextString(m) }
+func (*Attachment) ProtoMessage()               {}
+func (*Attachment) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Attachment) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Attachment) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Attachment) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Attachment) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *Attachment) GetThumbnailUrl() string {
+	if m != nil {
+		return m.ThumbnailUrl
+	}
+	return ""
+}
+
+// UpdateFeedRequest replaces the content of a feed owned by user_id. When
+// expected_version is set the update only applies to that version.
+type UpdateFeedRequest struct {
+	Id              int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	UserId          int64  `protobuf:"varint,2,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	Content         string `protobuf:"bytes,3,opt,name=content" json:"content,omitempty"`
+	ExpectedVersion int64  `protobuf:"varint,4,opt,name=expected_version,json=expectedVersion" json:"expected_version,omitempty"`
+}
+
+func (m *UpdateFeedRequest) Reset()                    { *m = UpdateFeedRequest{} }
+func (m *UpdateFeedRequest) String() string            { return proto.CompactTextString(m) }
+func (*UpdateFeedRequest) ProtoMessage()               {}
+func (*UpdateFeedRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *UpdateFeedRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *UpdateFeedRequest) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *UpdateFeedRequest) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *UpdateFeedRequest) GetExpectedVersion() int64 {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return 0
+}
+
+type DeleteFeedRequest struct {
+	Id              int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	UserId          int64 `protobuf:"varint,2,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	ExpectedVersion int64 `protobuf:"varint,3,opt,name=expected_version,json=expectedVersion" json:"expected_version,omitempty"`
+}
+
+func (m *DeleteFeedRequest) Reset()                    { *m = DeleteFeedRequest{} }
+func (m *DeleteFeedRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteFeedRequest) ProtoMessage()               {}
+func (*DeleteFeedRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *DeleteFeedRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *DeleteFeedRequest) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *DeleteFeedRequest) GetExpectedVersion() int64 {
+	if m != nil {
+		return m.ExpectedVersion
+	}
+	return 0
+}
+
+type GetFeedHistoryRequest struct {
+	Id      int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	OwnerId int64 `protobuf:"varint,2,opt,name=owner_id,json=ownerId" json:"owner_id,omitempty"`
+}
+
+func (m *GetFeedHistoryRequest) Reset()                    { *m = GetFeedHistoryRequest{} }
+func (m *GetFeedHistoryRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetFeedHistoryRequest) ProtoMessage()               {}
+func (*GetFeedHistoryRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *GetFeedHistoryRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *GetFeedHistoryRequest) GetOwnerId() int64 {
+	if m != nil {
+		return m.OwnerId
+	}
+	return 0
+}
+
+type FeedRevision struct {
+	Version    int64  `protobuf:"varint,1,opt,name=version" json:"version,omitempty"`
+	Content    string `protobuf:"bytes,2,opt,name=content" json:"content,omitempty"`
+	Deleted    bool   `protobuf:"varint,3,opt,name=deleted" json:"deleted,omitempty"`
+	ModifiedAt int64  `protobuf:"varint,4,opt,name=modified_at,json=modifiedAt" json:"modified_at,omitempty"`
+}
+
+func (m *FeedRevision) Reset()                    { *m = FeedRevision{} }
+func (m *FeedRevision) String() string            { return proto.CompactTextString(m) }
+func (*FeedRevision) ProtoMessage()               {}
+func (*FeedRevision) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *FeedRevision) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *FeedRevision) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *FeedRevision) GetDeleted() bool {
+	if m != nil {
+		return m.Deleted
+	}
+	return false
+}
+
+func (m *FeedRevision) GetModifiedAt() int64 {
+	if m != nil {
+		return m.ModifiedAt
+	}
+	return 0
+}
+
+type GetFeedHistoryResponse struct {
+	Revisions []*FeedRevision `protobuf:"bytes,1,rep,name=revisions" json:"revisions,omitempty"`
+}
+
+func (m *GetFeedHistoryResponse) Reset()                    { *m = GetFeedHistoryResponse{} }
+func (m *GetFeedHistoryResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetFeedHistoryResponse) ProtoMessage()               {}
+func (*GetFeedHistoryResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *GetFeedHistoryResponse) GetRevisions() []*FeedRevision {
+	if m != nil {
+		return m.Revisions
+	}
+	return nil
+}
+
+// OkResponse of CreateFeed has held set when the feed waits for the review
+// of a moderator, see HeldFeed.
+type OkResponse struct {
+	Held bool `protobuf:"varint,1,opt,name=held" json:"held,omitempty"`
+}
+
+func (m *OkResponse) Reset()                    { *m = OkResponse{} }
+func (m *OkResponse) String() string            { return proto.CompactTextString(m) }
+func (*OkResponse) ProtoMessage()               {}
+func (*OkResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *OkResponse) GetHeld() bool {
+	if m != nil {
+		return m.Held
+	}
+	return false
+}
+
+type LikeRequest struct {
+	FeedId  int64 `protobuf:"varint,1,opt,name=feed_id,json=feedId" json:"feed_id,omitempty"`
+	UserId  int64 `protobuf:"varint,2,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	OwnerId int64 `protobuf:"varint,3,opt,name=owner_id,json=ownerId" json:"owner_id,omitempty"`
+}
+
+func (m *LikeRequest) Reset()                    { *m = LikeRequest{} }
+func (m *LikeRequest) String() string            { return proto.CompactTextString(m) }
+func (*LikeRequest) ProtoMessage()               {}
+func (*LikeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *LikeRequest) GetFeedId() int64 {
+	if m != nil {
+		return m.FeedId
+	}
+	return 0
+}
+
+func (m *LikeRequest) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *LikeRequest) GetOwnerId() int64 {
+	if m != nil {
+		return m.OwnerId
+	}
+	return 0
+}
+
+type LikeResponse struct {
+	LikeCount int64 `protobuf:"varint,1,opt,name=like_count,json=likeCount" json:"like_count,omitempty"`
+}
+
+func (m *LikeResponse) Reset()                    { *m = LikeResponse{} }
+func (m *LikeResponse) String() string            { return proto.CompactTextString(m) }
+func (*LikeResponse) ProtoMessage()               {}
+func (*LikeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *LikeResponse) GetLikeCount() int64 {
+	if m != nil {
+		return m.LikeCount
+	}
+	return 0
+}
+
+// Comment is a reply to a feed, or to another comment when parent_id is set.
+type Comment struct {
+	Id         int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	FeedId     int64  `protobuf:"varint,2,opt,name=feed_id,json=feedId" json:"feed_id,omitempty"`
+	UserId     int64  `protobuf:"varint,3,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	ParentId   int64  `protobuf:"varint,4,opt,name=parent_id,json=parentId" json:"parent_id,omitempty"`
+	Content    string `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	CreatedAt  int64  `protobuf:"varint,6,opt,name=created_at,json=createdAt" json:"created_at,omitempty"`
+	ReplyCount int64  `protobuf:"varint,7,opt,name=reply_count,json=replyCount" json:"reply_count,omitempty"`
+	OwnerId    int64  `protobuf:"varint,8,opt,name=owner_id,json=ownerId" json:"owner_id,omitempty"`
+}
+
+func (m *Comment) Reset()                    { *m = Comment{} }
+func (m *Comment) String() string            { return proto.CompactTextString(m) }
+func (*Comment) ProtoMessage()               {}
+func (*Comment) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *Comment) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Comment) GetFeedId() int64 {
+	if m != nil {
+		return m.FeedId
+	}
+	return 0
+}
+
+func (m *Comment) GetUserId() int64 {
+	if m != nil {
+		return m.UserId
+	}
+	return 0
+}
+
+func (m *Comment) GetParentId() int64 {
+	if m != nil {
+		return m.ParentId
+	}
+	return 0
+}
+
+func (m *Comment) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+func (m *Comment) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Comment) GetReplyCount() int64 {
+	if m != nil {
+		return m.ReplyCount
+	}
+	return 0
+}
+
+func (m *Comment) GetOwnerId() int64 {
+	if m != nil {
+		return m.OwnerId
+	}
+	return 0
+}
+
+// GetCommentsRequest pages through the direct replies to parent_id (zero for
+// top level comments), starting after the comment id given as cursor.
+type GetCommentsRequest struct {
+	FeedId   int64 `protobuf:"varint,1,opt,name=feed_id,json=feedId" json:"feed_id,omitempty"`
+	ParentId int64 `protobuf:"varint,2,opt,name=parent_id,json=parentId" json:"parent_id,omitempty"`
+	Cursor   int64 `protobuf:"varint,3,opt,name=cursor" json:"cursor,omitempty"`
+	Size     int64 `protobuf:"varint,4,opt,name=size" json:"size,omitempty"`
+	OwnerId  int64 `protobuf:"varint,5,opt,name=owner_id,json=ownerId" json:"owner_id,omitempty"`
+}
+
+func (m *GetCommentsRequest) Reset()                    { *m = GetCommentsRequest{} }
+func (m *GetCommentsRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetCommentsRequest) ProtoMessage()               {}
+func (*GetCommentsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *GetCommentsRequest) GetFeedId() int64 {
+	if m != nil {
+		return m.FeedId
+	}
+	return 0
+}
+
+func (m *GetCommentsRequest) GetParentId() int64 {
+	if m != nil {
+		return m.ParentId
+	}
+	return 0
+}
+
+func (m *GetCommentsRequest) GetCursor() int64 {
+	if m != nil {
+		return m.Cursor
+	}
+	return 0
+}
+
+func (m *GetCommentsRequest) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *GetCommentsRequest) GetOwnerId() int64 {
+	if m != nil {
+		return m.OwnerId
+	}
+	return 0
+}
+
+type GetCommentsResponse struct {
+	Comments   []*Comment `protobuf:"bytes,1,rep,name=comments" json:"comments,omitempty"`
+	NextCursor int64      `protobuf:"varint,2,opt,name=next_cursor,json=nextCursor" json:"next_cursor,omitempty"`
+}
+
+func (m *GetCommentsResponse) Reset()                    { *m = GetCommentsResponse{} }
+func (m *GetCommentsResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetCommentsResponse) ProtoMessage()               {}
+func (*GetCommentsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *GetCommentsResponse) GetComments() []*Comment {
+	if m != nil {
+		return m.Comments
+	}
+	return nil
+}
+
+func (m *GetCommentsResponse) GetNextCursor() int64 {
+	if m != nil {
+		return m.NextCursor
+	}
+	return 0
+}
+
+// HeldFeed is a new feed, or an edit of a live one when edit is set, the
+// moderation chain held for review, with the stage and the reason. It is not
+// visible until a moderator approves it.
+type HeldFeed struct {
+	Feed   *FeedRecord `protobuf:"bytes,1,opt,name=feed" json:"feed,omitempty"`
+	Stage  string      `protobuf:"bytes,2,opt,name=stage" json:"stage,omitempty"`
+	Reason string      `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	HeldAt int64       `protobuf:"varint,4,opt,name=held_at,json=heldAt" json:"held_at,omitempty"`
+	Edit   bool        `protobuf:"varint,5,opt,name=edit" json:"edit,omitempty"`
+}
+
+func (m *HeldFeed) Reset()                    { *m = HeldFeed{} }
+func (m *HeldFeed) String() string            { return proto.CompactTextString(m) }
+func (*HeldFeed) ProtoMessage()               {}
+func (*HeldFeed) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *HeldFeed) GetFeed() *FeedRecord {
+	if m != nil {
+		return m.Feed
+	}
+	return nil
+}
+
+func (m *HeldFeed) GetStage() string {
+	if m != nil {
+		return m.Stage
+	}
+	return ""
+}
+
+func (m *HeldFeed) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *HeldFeed) GetHeldAt() int64 {
+	if m != nil {
+		return m.HeldAt
+	}
+	return 0
+}
+
+func (m *HeldFeed) GetEdit() bool {
+	if m != nil {
+		return m.Edit
+	}
+	return false
+}
+
+// ListHeldFeedsRequest lists the review queue, oldest first.
+type ListHeldFeedsRequest struct {
+	Size int64 `protobuf:"varint,1,opt,name=size" json:"size,omitempty"`
+}
+
+func (m *ListHeldFeedsRequest) Reset()                    { *m = ListHeldFeedsRequest{} }
+func (m *ListHeldFeedsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListHeldFeedsRequest) ProtoMessage()               {}
+func (*ListHeldFeedsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *ListHeldFeedsRequest) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type ListHeldFeedsResponse struct {
+	Feeds []*HeldFeed `protobuf:"bytes,1,rep,name=feeds" json:"feeds,omitempty"`
+}
+
+func (m *ListHeldFeedsResponse) Reset()                    { *m = ListHeldFeedsResponse{} }
+func (m *ListHeldFeedsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListHeldFeedsResponse) ProtoMessage()               {}
+func (*ListHeldFeedsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *ListHeldFeedsResponse) GetFeeds() []*HeldFeed {
+	if m != nil {
+		return m.Feeds
+	}
+	return nil
+}
+
+// ReviewFeedRequest publishes the held feed id when approve is set, and
+// drops it otherwise. The owner_id, the user of the held feed, is required
+// so that the review is routed to the instance holding it. The moderator
+// and the note are logged.
+type ReviewFeedRequest struct {
+	Id        int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	OwnerId   int64  `protobuf:"varint,2,opt,name=owner_id,json=ownerId" json:"owner_id,omitempty"`
+	Approve   bool   `protobuf:"varint,3,opt,name=approve" json:"approve,omitempty"`
+	Moderator string `protobuf:"bytes,4,opt,name=moderator" json:"moderator,omitempty"`
+	Note      string `protobuf:"bytes,5,opt,name=note" json:"note,omitempty"`
 }
 
-func (m *FeedRecord) Reset()                    { *m = FeedRecord{} }
-func (m *FeedRecord) String() string            { return proto.CompactTextString(m) }
-func (*FeedRecord) ProtoMessage()               {}
-func (*FeedRecord) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (m *ReviewFeedRequest) Reset()                    { *m = ReviewFeedRequest{} }
+func (m *ReviewFeedRequest) String() string            { return proto.CompactTextString(m) }
+func (*ReviewFeedRequest) ProtoMessage()               {}
+func (*ReviewFeedRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
-func (m *FeedRecord) GetId() int64 {
+func (m *ReviewFeedRequest) GetId() int64 {
 	if m != nil {
 		return m.Id
 	}
 	return 0
 }
 
-func (m *FeedRecord) GetUserId() int64 {
+func (m *ReviewFeedRequest) GetOwnerId() int64 {
 	if m != nil {
-		return m.UserId
+		return m.OwnerId
 	}
 	return 0
 }
 
-func (m *FeedRecord) GetContent() string {
+func (m *ReviewFeedRequest) GetApprove() bool {
 	if m != nil {
-		return m.Content
+		return m.Approve
 	}
-	return ""
+	return false
 }
 
-type OkResponse struct {
+func (m *ReviewFeedRequest) GetModerator() string {
+	if m != nil {
+		return m.Moderator
+	}
+	return ""
 }
 
-func (m *OkResponse) Reset()                    { *m = OkResponse{} }
-func (m *OkResponse) String() string            { return proto.CompactTextString(m) }
-func (*OkResponse) ProtoMessage()               {}
-func (*OkResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (m *ReviewFeedRequest) GetNote() string {
+	if m != nil {
+		return m.Note
+	}
+	return ""
+}
 
 func init() {
 	proto.RegisterType((*GetFeedsRequest)(nil), "feed.GetFeedsRequest")
+	proto.RegisterType((*SearchFeedsRequest)(nil), "feed.SearchFeedsRequest")
+	proto.RegisterType((*GetFeedsByTopicRequest)(nil), "feed.GetFeedsByTopicRequest")
 	proto.RegisterType((*GetFeedsResponse)(nil), "feed.GetFeedsResponse")
 	proto.RegisterType((*FeedRecord)(nil), "feed.FeedRecord")
+	proto.RegisterType((*Attachment)(nil), "feed.Attachment")
+	proto.RegisterType((*UpdateFeedRequest)(nil), "feed.UpdateFeedRequest")
+	proto.RegisterType((*DeleteFeedRequest)(nil), "feed.DeleteFeedRequest")
+	proto.RegisterType((*GetFeedHistoryRequest)(nil), "feed.GetFeedHistoryRequest")
+	proto.RegisterType((*FeedRevision)(nil), "feed.FeedRevision")
+	proto.RegisterType((*GetFeedHistoryResponse)(nil), "feed.GetFeedHistoryResponse")
 	proto.RegisterType((*OkResponse)(nil), "feed.OkResponse")
+	proto.RegisterType((*LikeRequest)(nil), "feed.LikeRequest")
+	proto.RegisterType((*LikeResponse)(nil), "feed.LikeResponse")
+	proto.RegisterType((*Comment)(nil), "feed.Comment")
+	proto.RegisterType((*GetCommentsRequest)(nil), "feed.GetCommentsRequest")
+	proto.RegisterType((*GetCommentsResponse)(nil), "feed.GetCommentsResponse")
+	proto.RegisterType((*HeldFeed)(nil), "feed.HeldFeed")
+	proto.RegisterType((*ListHeldFeedsRequest)(nil), "feed.ListHeldFeedsRequest")
+	proto.RegisterType((*ListHeldFeedsResponse)(nil), "feed.ListHeldFeedsResponse")
+	proto.RegisterType((*ReviewFeedRequest)(nil), "feed.ReviewFeedRequest")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -136,6 +855,17 @@ const _ = grpc.SupportPackageIsVersion4
 type FeedClient interface {
 	GetFeeds(ctx context.Context, in *GetFeedsRequest, opts ...grpc.CallOption) (*GetFeedsResponse, error)
 	CreateFeed(ctx context.Context, in *FeedRecord, opts ...grpc.CallOption) (*OkResponse, error)
+	SearchFeeds(ctx context.Context, in *SearchFeedsRequest, opts ...grpc.CallOption) (*GetFeedsResponse, error)
+	GetFeedsByTopic(ctx context.Context, in *GetFeedsByTopicRequest, opts ...grpc.CallOption) (*GetFeedsResponse, error)
+	UpdateFeed(ctx context.Context, in *UpdateFeedRequest, opts ...grpc.CallOption) (*FeedRecord, error)
+	DeleteFeed(ctx context.Context, in *DeleteFeedRequest, opts ...grpc.CallOption) (*OkResponse, error)
+	GetFeedHistory(ctx context.Context, in *GetFeedHistoryRequest, opts ...grpc.CallOption) (*GetFeedHistoryResponse, error)
+	LikeFeed(ctx context.Context, in *LikeRequest, opts ...grpc.CallOption) (*LikeResponse, error)
+	UnlikeFeed(ctx context.Context, in *LikeRequest, opts ...grpc.CallOption) (*LikeResponse, error)
+	CreateComment(ctx context.Context, in *Comment, opts ...grpc.CallOption) (*Comment, error)
+	GetComments(ctx context.Context, in *GetCommentsRequest, opts ...grpc.CallOption) (*GetCommentsResponse, error)
+	ListHeldFeeds(ctx context.Context, in *ListHeldFeedsRequest, opts ...grpc.CallOption) (*ListHeldFeedsResponse, error)
+	ReviewFeed(ctx context.Context, in *ReviewFeedRequest, opts ...grpc.CallOption) (*OkResponse, error)
 }
 
 type feedClient struct {
@@ -164,11 +894,121 @@ func (c *feedClient) CreateFeed(ctx context.Context, in *FeedRecord, opts ...grp
 	return out, nil
 }
 
+func (c *feedClient) SearchFeeds(ctx context.Context, in *SearchFeedsRequest, opts ...grpc.CallOption) (*GetFeedsResponse, error) {
+	out := new(GetFeedsResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/SearchFeeds", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) GetFeedsByTopic(ctx context.Context, in *GetFeedsByTopicRequest, opts ...grpc.CallOption) (*GetFeedsResponse, error) {
+	out := new(GetFeedsResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/GetFeedsByTopic", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) UpdateFeed(ctx context.Context, in *UpdateFeedRequest, opts ...grpc.CallOption) (*FeedRecord, error) {
+	out := new(FeedRecord)
+	err := grpc.Invoke(ctx, "/feed.Feed/UpdateFeed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) DeleteFeed(ctx context.Context, in *DeleteFeedRequest, opts ...grpc.CallOption) (*OkResponse, error) {
+	out := new(OkResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/DeleteFeed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) GetFeedHistory(ctx context.Context, in *GetFeedHistoryRequest, opts ...grpc.CallOption) (*GetFeedHistoryResponse, error) {
+	out := new(GetFeedHistoryResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/GetFeedHistory", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) LikeFeed(ctx context.Context, in *LikeRequest, opts ...grpc.CallOption) (*LikeResponse, error) {
+	out := new(LikeResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/LikeFeed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) UnlikeFeed(ctx context.Context, in *LikeRequest, opts ...grpc.CallOption) (*LikeResponse, error) {
+	out := new(LikeResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/UnlikeFeed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) CreateComment(ctx context.Context, in *Comment, opts ...grpc.CallOption) (*Comment, error) {
+	out := new(Comment)
+	err := grpc.Invoke(ctx, "/feed.Feed/CreateComment", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) GetComments(ctx context.Context, in *GetCommentsRequest, opts ...grpc.CallOption) (*GetCommentsResponse, error) {
+	out := new(GetCommentsResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/GetComments", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) ListHeldFeeds(ctx context.Context, in *ListHeldFeedsRequest, opts ...grpc.CallOption) (*ListHeldFeedsResponse, error) {
+	out := new(ListHeldFeedsResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/ListHeldFeeds", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *feedClient) ReviewFeed(ctx context.Context, in *ReviewFeedRequest, opts ...grpc.CallOption) (*OkResponse, error) {
+	out := new(OkResponse)
+	err := grpc.Invoke(ctx, "/feed.Feed/ReviewFeed", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Feed service
 
 type FeedServer interface {
 	GetFeeds(context.Context, *GetFeedsRequest) (*GetFeedsResponse, error)
 	CreateFeed(context.Context, *FeedRecord) (*OkResponse, error)
+	SearchFeeds(context.Context, *SearchFeedsRequest) (*GetFeedsResponse, error)
+	GetFeedsByTopic(context.Context, *GetFeedsByTopicRequest) (*GetFeedsResponse, error)
+	UpdateFeed(context.Context, *UpdateFeedRequest) (*FeedRecord, error)
+	DeleteFeed(context.Context, *DeleteFeedRequest) (*OkResponse, error)
+	GetFeedHistory(context.Context, *GetFeedHistoryRequest) (*GetFeedHistoryResponse, error)
+	LikeFeed(context.Context, *LikeRequest) (*LikeResponse, error)
+	UnlikeFeed(context.Context, *LikeRequest) (*LikeResponse, error)
+	CreateComment(context.Context, *Comment) (*Comment, error)
+	GetComments(context.Context, *GetCommentsRequest) (*GetCommentsResponse, error)
+	ListHeldFeeds(context.Context, *ListHeldFeedsRequest) (*ListHeldFeedsResponse, error)
+	ReviewFeed(context.Context, *ReviewFeedRequest) (*OkResponse, error)
 }
 
 func RegisterFeedServer(s *grpc.Server, srv FeedServer) {
@@ -211,6 +1051,204 @@ func _Feed_CreateFeed_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Feed_SearchFeeds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchFeedsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).SearchFeeds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/SearchFeeds",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).SearchFeeds(ctx, req.(*SearchFeedsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_GetFeedsByTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeedsByTopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).GetFeedsByTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/GetFeedsByTopic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).GetFeedsByTopic(ctx, req.(*GetFeedsByTopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_UpdateFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateFeedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).UpdateFeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/UpdateFeed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).UpdateFeed(ctx, req.(*UpdateFeedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_DeleteFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteFeedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).DeleteFeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/DeleteFeed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).DeleteFeed(ctx, req.(*DeleteFeedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_GetFeedHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeedHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).GetFeedHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/GetFeedHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).GetFeedHistory(ctx, req.(*GetFeedHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_LikeFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LikeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).LikeFeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/LikeFeed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).LikeFeed(ctx, req.(*LikeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_UnlikeFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LikeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).UnlikeFeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/UnlikeFeed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).UnlikeFeed(ctx, req.(*LikeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_CreateComment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Comment)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).CreateComment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/CreateComment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).CreateComment(ctx, req.(*Comment))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_GetComments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCommentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).GetComments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/GetComments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).GetComments(ctx, req.(*GetCommentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_ListHeldFeeds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHeldFeedsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).ListHeldFeeds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/ListHeldFeeds",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).ListHeldFeeds(ctx, req.(*ListHeldFeedsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Feed_ReviewFeed_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewFeedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FeedServer).ReviewFeed(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/feed.Feed/ReviewFeed",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FeedServer).ReviewFeed(ctx, req.(*ReviewFeedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Feed_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "feed.Feed",
 	HandlerType: (*FeedServer)(nil),
@@ -223,6 +1261,50 @@ var _Feed_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateFeed",
 			Handler:    _Feed_CreateFeed_Handler,
 		},
+		{
+			MethodName: "SearchFeeds",
+			Handler:    _Feed_SearchFeeds_Handler,
+		},
+		{
+			MethodName: "GetFeedsByTopic",
+			Handler:    _Feed_GetFeedsByTopic_Handler,
+		},
+		{
+			MethodName: "UpdateFeed",
+			Handler:    _Feed_UpdateFeed_Handler,
+		},
+		{
+			MethodName: "DeleteFeed",
+			Handler:    _Feed_DeleteFeed_Handler,
+		},
+		{
+			MethodName: "GetFeedHistory",
+			Handler:    _Feed_GetFeedHistory_Handler,
+		},
+		{
+			MethodName: "LikeFeed",
+			Handler:    _Feed_LikeFeed_Handler,
+		},
+		{
+			MethodName: "UnlikeFeed",
+			Handler:    _Feed_UnlikeFeed_Handler,
+		},
+		{
+			MethodName: "CreateComment",
+			Handler:    _Feed_CreateComment_Handler,
+		},
+		{
+			MethodName: "GetComments",
+			Handler:    _Feed_GetComments_Handler,
+		},
+		{
+			MethodName: "ListHeldFeeds",
+			Handler:    _Feed_ListHeldFeeds_Handler,
+		},
+		{
+			MethodName: "ReviewFeed",
+			Handler:    _Feed_ReviewFeed_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "feed.proto",
@@ -231,20 +1313,76 @@ var _Feed_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("feed.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 232 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x64, 0x90, 0x41, 0x4b, 0xc4, 0x30,
-	0x10, 0x85, 0x77, 0xdb, 0xba, 0xab, 0x4f, 0xd1, 0x65, 0x40, 0x0d, 0x7b, 0x92, 0x1c, 0x64, 0x4f,
-	0x7b, 0xa8, 0x37, 0x05, 0x2f, 0x82, 0xe2, 0x69, 0x21, 0x7f, 0x40, 0xb4, 0x19, 0xa1, 0x08, 0x4d,
-	0x4d, 0x52, 0x04, 0x7f, 0xbd, 0x49, 0x6a, 0xad, 0xd6, 0xdb, 0x9b, 0x37, 0x99, 0x37, 0xdf, 0x04,
-	0x78, 0x65, 0xd6, 0xdb, 0xd6, 0x1a, 0x6f, 0xa8, 0x88, 0x5a, 0xde, 0xe2, 0xe4, 0x81, 0xfd, 0x7d,
-	0x90, 0x4e, 0xf1, 0x7b, 0xc7, 0xce, 0xd3, 0x39, 0x96, 0x9d, 0x63, 0xfb, 0x54, 0x6b, 0x31, 0xbf,
-	0x98, 0x6f, 0x72, 0xb5, 0x88, 0xe5, 0xa3, 0x26, 0x42, 0xe1, 0xea, 0x4f, 0x16, 0x59, 0x72, 0x93,
-	0x96, 0xd7, 0x58, 0x8d, 0xf3, 0xae, 0x35, 0x8d, 0x63, 0xba, 0xc4, 0x5e, 0xcc, 0x76, 0x61, 0x3c,
-	0xdf, 0x1c, 0x96, 0xab, 0x6d, 0xda, 0x1a, 0xdf, 0x28, 0xae, 0x8c, 0xd5, 0xaa, 0x6f, 0xcb, 0x1d,
-	0x30, 0x9a, 0x74, 0x8c, 0xec, 0x67, 0x63, 0x50, 0xbf, 0x31, 0xb2, 0x3f, 0x18, 0x02, 0xcb, 0xca,
-	0x34, 0x9e, 0x1b, 0x2f, 0xf2, 0xd0, 0x38, 0x50, 0x43, 0x29, 0x8f, 0x80, 0xdd, 0xdb, 0x80, 0x51,
-	0x7e, 0xa0, 0x88, 0xf1, 0x74, 0x83, 0xfd, 0x01, 0x91, 0x4e, 0x7b, 0x96, 0xc9, 0xc9, 0xeb, 0xb3,
-	0xa9, 0xdd, 0x47, 0xc8, 0x19, 0x95, 0xc0, 0x9d, 0xe5, 0x67, 0xcf, 0x29, 0xea, 0xdf, 0x29, 0xeb,
-	0x6f, 0x67, 0x5c, 0x2b, 0x67, 0x2f, 0x8b, 0xf4, 0xc1, 0x57, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff,
-	0x04, 0x40, 0xa3, 0x75, 0x6e, 0x01, 0x00, 0x00,
+	// 1123 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0x5b, 0x6f, 0xdc, 0xc4,
+	0x17, 0x8f, 0xd7, 0x7b, 0xb1, 0xcf, 0x6e, 0x6e, 0xf3, 0x6f, 0x52, 0x67, 0x93, 0xbf, 0xba, 0x98,
+	0x0a, 0x6d, 0x91, 0x28, 0x28, 0x15, 0x0f, 0x80, 0xa8, 0x94, 0xa6, 0x22, 0x4d, 0x55, 0x84, 0x64,
+	0x1a, 0x1e, 0x78, 0x59, 0xb9, 0xf6, 0x34, 0x6b, 0x65, 0xd7, 0x76, 0x67, 0x66, 0xd3, 0x2e, 0x12,
+	0x0f, 0xbc, 0x96, 0x27, 0x1e, 0xf9, 0x1e, 0x7c, 0x1d, 0xbe, 0x0b, 0x9a, 0x9b, 0x3d, 0xb3, 0xeb,
+	0x04, 0x81, 0x78, 0xf3, 0xb9, 0xcc, 0x9c, 0xfb, 0xef, 0x8c, 0x01, 0x5e, 0x63, 0x9c, 0x3e, 0x2c,
+	0x49, 0xc1, 0x0a, 0xd4, 0xe6, 0xdf, 0xe1, 0x63, 0xd8, 0x3e, 0xc3, 0xec, 0x1b, 0x8c, 0x53, 0x1a,
+	0xe1, 0x37, 0x0b, 0x4c, 0x19, 0xba, 0x0b, 0xbd, 0x05, 0xc5, 0x64, 0x92, 0xa5, 0x81, 0x33, 0x72,
+	0xc6, 0x6e, 0xd4, 0xe5, 0xe4, 0x79, 0x8a, 0x10, 0xb4, 0x69, 0xf6, 0x13, 0x0e, 0x5a, 0x82, 0x2b,
+	0xbe, 0xc3, 0xc7, 0x80, 0xbe, 0xc7, 0x31, 0x49, 0xa6, 0xd6, 0x15, 0x77, 0xa0, 0xf3, 0x66, 0x81,
+	0xc9, 0x52, 0x5c, 0xe0, 0x47, 0x92, 0x68, 0x3c, 0x7f, 0x06, 0xfb, 0xda, 0xfe, 0x93, 0xe5, 0xcb,
+	0xa2, 0xcc, 0x12, 0x7d, 0xc7, 0x01, 0x78, 0x8c, 0xd3, 0xb5, 0x1f, 0x3d, 0x41, 0xdf, 0xe0, 0xc8,
+	0x97, 0xb0, 0x53, 0x07, 0x42, 0xcb, 0x22, 0xa7, 0x18, 0x7d, 0x04, 0x1d, 0x1e, 0x24, 0x0d, 0x9c,
+	0x91, 0x3b, 0xee, 0x1f, 0xef, 0x3c, 0x14, 0xe1, 0x73, 0x9d, 0x08, 0x27, 0x05, 0x49, 0x23, 0x29,
+	0x0e, 0x7f, 0x77, 0x01, 0x6a, 0x2e, 0xda, 0x82, 0x56, 0x65, 0xb3, 0x95, 0xa5, 0x66, 0x42, 0x5a,
+	0x56, 0x42, 0x02, 0xe8, 0x25, 0x45, 0xce, 0x70, 0xce, 0x02, 0x57, 0x04, 0xaa, 0x49, 0x34, 0x04,
+	0x6f, 0x1a, 0xd3, 0x29, 0x8b, 0x2f, 0x69, 0xd0, 0x1e, 0xb9, 0x63, 0x3f, 0xaa, 0x68, 0x2e, 0x9b,
+	0xe3, 0x9c, 0x65, 0x45, 0x4e, 0x83, 0x8e, 0x94, 0x69, 0x1a, 0x1d, 0x82, 0xaf, 0x83, 0xa6, 0x41,
+	0x77, 0xe4, 0x8e, 0xdd, 0xc8, 0x53, 0x51, 0x53, 0x6e, 0xee, 0x1a, 0x13, 0x9a, 0x15, 0x79, 0xd0,
+	0x93, 0x09, 0x51, 0x24, 0x97, 0xa4, 0x78, 0x86, 0x19, 0x4e, 0x03, 0x6f, 0xe4, 0x8c, 0xbd, 0x48,
+	0x93, 0xe8, 0xff, 0x00, 0xb3, 0xec, 0x0a, 0x4f, 0x92, 0x62, 0x91, 0xb3, 0xc0, 0x17, 0xc7, 0x7c,
+	0xce, 0x39, 0xe5, 0x0c, 0xf4, 0x21, 0x6c, 0x26, 0xc5, 0x9c, 0x9b, 0x57, 0x1a, 0x20, 0x34, 0x06,
+	0x8a, 0x29, 0x95, 0x8e, 0xa1, 0x1f, 0x33, 0x16, 0x27, 0x53, 0xce, 0xa2, 0x41, 0xdf, 0x4c, 0xe6,
+	0x49, 0x25, 0x88, 0x4c, 0x25, 0xde, 0x01, 0x34, 0x29, 0x08, 0x0e, 0x06, 0x23, 0x67, 0xec, 0x44,
+	0x92, 0xe0, 0xde, 0x24, 0x04, 0xc7, 0x0c, 0xa7, 0x93, 0x98, 0x05, 0x9b, 0xd2, 0x1b, 0xc5, 0x39,
+	0x61, 0xbc, 0xae, 0x53, 0x3c, 0x4b, 0x83, 0x2d, 0x11, 0x83, 0xf8, 0x0e, 0xdf, 0x3b, 0x00, 0xb5,
+	0x11, 0xa3, 0x36, 0xbe, 0xa8, 0xcd, 0x07, 0x30, 0x50, 0x39, 0x9f, 0xb0, 0x65, 0x29, 0x5b, 0xc2,
+	0x8f, 0xfa, 0x8a, 0xf7, 0x72, 0x59, 0xe2, 0xaa, 0x5b, 0xdc, 0xba, 0x5b, 0xd0, 0x0e, 0xb8, 0x0b,
+	0x32, 0x0b, 0xda, 0x42, 0x9b, 0x7f, 0xf2, 0x4c, 0xb0, 0xe9, 0x62, 0xfe, 0x2a, 0x8f, 0xb3, 0xd9,
+	0x84, 0xcb, 0x3a, 0x42, 0x36, 0xa8, 0x98, 0x17, 0x64, 0x16, 0xfe, 0xe2, 0xc0, 0xee, 0x45, 0x99,
+	0xc6, 0x0c, 0xcb, 0x76, 0x91, 0x9d, 0xfa, 0x1f, 0xf4, 0xcb, 0x03, 0xd8, 0xc1, 0xef, 0x4a, 0x9c,
+	0xf0, 0xcc, 0xe8, 0x1a, 0xb7, 0xc5, 0xd9, 0x6d, 0xcd, 0xff, 0x41, 0xb2, 0xc3, 0x4b, 0xd8, 0x7d,
+	0x2a, 0x8a, 0xfb, 0xaf, 0x5c, 0x68, 0x32, 0xe4, 0x36, 0x1b, 0x7a, 0x02, 0x7b, 0x6a, 0xa2, 0x9e,
+	0x65, 0x94, 0x15, 0x64, 0x79, 0x93, 0xb1, 0x03, 0xf0, 0x8a, 0xb7, 0xb9, 0x69, 0xad, 0x27, 0xe8,
+	0xf3, 0x34, 0xfc, 0x19, 0x06, 0xd2, 0xcd, 0xeb, 0x4c, 0x37, 0xaa, 0xb6, 0xea, 0xac, 0xb5, 0xb0,
+	0xce, 0x4d, 0xcb, 0xce, 0x8d, 0xd1, 0xdc, 0xae, 0xdd, 0xdc, 0xf7, 0xa0, 0x3f, 0x2f, 0xd2, 0xec,
+	0x75, 0x26, 0xfb, 0x49, 0x26, 0x0c, 0x34, 0xeb, 0x84, 0x85, 0xcf, 0x61, 0x7f, 0x35, 0x04, 0x05,
+	0x0d, 0x9f, 0x81, 0x4f, 0x94, 0x53, 0x1a, 0x1e, 0x90, 0x09, 0x0f, 0x52, 0x14, 0xd5, 0x4a, 0xe1,
+	0x08, 0xe0, 0xbb, 0xab, 0xea, 0xbc, 0x6e, 0x55, 0xc7, 0x68, 0xd5, 0x1f, 0xa1, 0xff, 0x22, 0xbb,
+	0xc2, 0x06, 0x8e, 0xf2, 0x0b, 0x0d, 0x1c, 0xe5, 0xe4, 0xf9, 0x2d, 0xc5, 0x31, 0x13, 0xe9, 0xda,
+	0x89, 0xfc, 0x04, 0x06, 0xf2, 0x6e, 0x65, 0xdf, 0x9e, 0x6b, 0x67, 0x65, 0xae, 0xc3, 0x3f, 0x1d,
+	0xe8, 0x9d, 0xca, 0x19, 0x6e, 0xea, 0x0d, 0xed, 0x57, 0xeb, 0x26, 0xbf, 0x5c, 0xcb, 0xaf, 0x43,
+	0xf0, 0xcb, 0x98, 0xf0, 0x19, 0xcb, 0x52, 0x95, 0x65, 0x4f, 0x32, 0xec, 0xa6, 0xee, 0xd8, 0x85,
+	0xb3, 0xa7, 0xbd, 0xbb, 0x3a, 0xed, 0xf7, 0xa0, 0x4f, 0x70, 0x39, 0x5b, 0xaa, 0x18, 0x24, 0xa4,
+	0x81, 0x60, 0x49, 0xdc, 0x31, 0xd3, 0xe1, 0xd9, 0xe9, 0xf8, 0xcd, 0x01, 0x74, 0x86, 0x99, 0x0a,
+	0x91, 0xfe, 0x6d, 0xca, 0xad, 0x08, 0x5a, 0x2b, 0x11, 0xec, 0x43, 0x37, 0x59, 0x10, 0x5a, 0x10,
+	0x1d, 0xb6, 0xa4, 0x2a, 0xe0, 0x68, 0x1b, 0xc0, 0x61, 0xfa, 0xd4, 0xb1, 0x7d, 0x8a, 0xe1, 0x7f,
+	0x96, 0x4b, 0xaa, 0x52, 0x0f, 0xc0, 0x53, 0x68, 0xaa, 0x1b, 0x6d, 0x53, 0x36, 0x9a, 0xd2, 0x8c,
+	0x2a, 0x31, 0xcf, 0x48, 0x8e, 0xdf, 0xb1, 0x89, 0xf2, 0x46, 0xfa, 0x09, 0x9c, 0x75, 0x2a, 0x38,
+	0xe1, 0xaf, 0x0e, 0x78, 0xcf, 0xf0, 0x2c, 0xe5, 0x3d, 0x8a, 0xee, 0x83, 0x58, 0xe1, 0x22, 0xd2,
+	0xa6, 0xe5, 0x26, 0xa4, 0x02, 0x88, 0x59, 0x7c, 0xa9, 0x91, 0x51, 0x12, 0x3c, 0x64, 0x82, 0x63,
+	0xaa, 0x86, 0xdf, 0x8f, 0x14, 0xc5, 0x13, 0xc8, 0x5b, 0xb9, 0x9e, 0xa6, 0x2e, 0x27, 0x25, 0x34,
+	0xe3, 0x34, 0x93, 0x25, 0xf6, 0x22, 0xf1, 0x1d, 0x7e, 0x0c, 0x77, 0x5e, 0x64, 0x94, 0x69, 0x87,
+	0xaa, 0x2a, 0xe8, 0xbc, 0x39, 0xc6, 0x7a, 0xfe, 0x1a, 0xf6, 0x56, 0x74, 0x55, 0x7a, 0xee, 0xdb,
+	0x3b, 0x7a, 0x4b, 0x86, 0xa1, 0xf5, 0xf4, 0x86, 0x7e, 0xef, 0xc0, 0x2e, 0x1f, 0x4a, 0xfc, 0xf6,
+	0x36, 0xd4, 0xbb, 0x19, 0x88, 0x78, 0x97, 0xc6, 0x65, 0x49, 0x8a, 0x6b, 0xac, 0x41, 0x44, 0x91,
+	0xe8, 0x08, 0xfc, 0x79, 0x91, 0x62, 0x12, 0xb3, 0x82, 0xa8, 0x85, 0x50, 0x33, 0x78, 0x2c, 0x79,
+	0xc1, 0xb0, 0x6a, 0x6d, 0xf1, 0x7d, 0xfc, 0x47, 0x17, 0xda, 0xa2, 0x02, 0x5f, 0x81, 0xa7, 0xdf,
+	0x1c, 0x68, 0x4f, 0x3a, 0xbe, 0xf2, 0x98, 0x1a, 0xee, 0xaf, 0xb2, 0x65, 0xd8, 0xe1, 0x06, 0x3a,
+	0x06, 0x38, 0x15, 0xb3, 0x20, 0xae, 0x5a, 0x2b, 0xdf, 0x50, 0x71, 0x6a, 0xcc, 0x09, 0x37, 0xd0,
+	0x09, 0xf4, 0x8d, 0xd7, 0x16, 0x0a, 0xa4, 0xca, 0xfa, 0x03, 0xec, 0x16, 0xb3, 0xe7, 0xb0, 0xbd,
+	0xf2, 0xe0, 0x42, 0x47, 0xb6, 0xb2, 0xfd, 0x0e, 0xbb, 0xe5, 0xaa, 0x2f, 0x00, 0xea, 0x65, 0x88,
+	0xee, 0x4a, 0xbd, 0xb5, 0xf5, 0x38, 0x5c, 0x0b, 0x4d, 0x1e, 0xad, 0x97, 0x98, 0x3e, 0xba, 0xb6,
+	0xd6, 0x1a, 0x73, 0xf0, 0x2d, 0x6c, 0xd9, 0x98, 0x8e, 0x0e, 0x2d, 0x0f, 0xed, 0x65, 0x35, 0x3c,
+	0x6a, 0x16, 0x56, 0xd7, 0x3d, 0x02, 0x8f, 0x03, 0xab, 0xf0, 0x63, 0x57, 0xea, 0x1a, 0x20, 0x3e,
+	0x44, 0x26, 0xab, 0x3a, 0xf4, 0x39, 0xc0, 0x45, 0x3e, 0xfb, 0xc7, 0xc7, 0x3e, 0x85, 0x4d, 0x59,
+	0x72, 0x0d, 0xcd, 0x36, 0x12, 0x0c, 0x6d, 0x32, 0xdc, 0x40, 0x4f, 0xa1, 0x6f, 0x40, 0x8a, 0xae,
+	0xf7, 0x3a, 0xf0, 0x0d, 0x0f, 0x1a, 0x24, 0x95, 0xd9, 0xe7, 0xb0, 0x69, 0xcd, 0x1e, 0x1a, 0x6a,
+	0xef, 0xd6, 0x87, 0x77, 0x78, 0xd8, 0x28, 0x33, 0x6b, 0x5e, 0xcf, 0xa1, 0x2e, 0xdc, 0xda, 0x64,
+	0x36, 0x15, 0xee, 0x55, 0x57, 0xfc, 0x77, 0x3c, 0xfa, 0x6b, 0x00, 0x83, 0x15, 0x0b, 0x1f, 0x85,
+	0x0c, 0x00, 0x00,
 }
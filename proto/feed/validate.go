@@ -0,0 +1,154 @@
+package feed
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/buptmiao/microservice-app/validate"
+)
+
+// MediaURL prefixes the urls of the media uploaded through the gateway,
+// followed by the id of the attachment.
+const MediaURL = "/api/media/"
+
+// mediaID is the hex sha256 of the content of an attachment.
+var mediaID = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Content limits, in characters.
+const (
+	MaxContentLength = 2000
+	MaxCommentLength = 500
+	MaxQueryLength   = 256
+	MaxAttachments   = 9
+	MaxNoteLength    = 500
+	MaxNameLength    = 64
+)
+
+// Page sizes used when the request does not set one: DefaultPageSize for the
+// feed listings and the search, DefaultHeldSize for the review queue.
+const (
+	DefaultPageSize = 20
+	DefaultHeldSize = 20
+)
+
+// The rules of the requests, see validate. The fields set by the service,
+// e.g. the version and the counters of a FeedRecord, are not checked.
+
+func (m *GetFeedsRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("user_id", m.UserId),
+		validate.PageSize("size", m.Size),
+	)
+}
+
+func (m *FeedRecord) Validate() error {
+	return validate.Check(
+		validate.Positive("id", m.Id),
+		validate.Positive("user_id", m.UserId),
+		validate.Text("content", m.Content, MaxContentLength, true),
+		validate.Items("attachments", len(m.Attachments), MaxAttachments),
+		attachments(m.Attachments),
+	)
+}
+
+// attachments requires the attachments to reference media uploaded through
+// the gateway, so that a feed never embeds a link to another site.
+func attachments(list []*Attachment) validate.Rule {
+	return func() *validate.Violation {
+		for i, a := range list {
+			field := fmt.Sprintf("attachments[%d]", i)
+			switch {
+			case !mediaID.MatchString(a.GetId()):
+				return &validate.Violation{Field: field + ".id", Description: "must be the id of an uploaded media"}
+			case a.GetUrl() != MediaURL+a.GetId():
+				return &validate.Violation{Field: field + ".url", Description: "must be " + MediaURL + "<id>"}
+			case a.GetThumbnailUrl() != "" && a.GetThumbnailUrl() != MediaURL+a.GetId()+"_thumb":
+				return &validate.Violation{Field: field + ".thumbnail_url", Description: "must be empty or " + MediaURL + "<id>_thumb"}
+			case a.GetSize() < 0:
+				return &validate.Violation{Field: field + ".size", Description: "must not be negative"}
+			}
+		}
+		return nil
+	}
+}
+
+func (m *SearchFeedsRequest) Validate() error {
+	return validate.Check(
+		validate.Text("query", m.Query, MaxQueryLength, true),
+		validate.PageSize("size", m.Size),
+	)
+}
+
+func (m *GetFeedsByTopicRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("topic_id", m.TopicId),
+		validate.PageSize("size", m.Size),
+	)
+}
+
+func (m *UpdateFeedRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("id", m.Id),
+		validate.Positive("user_id", m.UserId),
+		validate.Text("content", m.Content, MaxContentLength, true),
+		validate.NonNegative("expected_version", m.ExpectedVersion),
+	)
+}
+
+func (m *DeleteFeedRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("id", m.Id),
+		validate.Positive("user_id", m.UserId),
+		validate.NonNegative("expected_version", m.ExpectedVersion),
+	)
+}
+
+func (m *GetFeedHistoryRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("id", m.Id),
+		validate.Positive("owner_id", m.OwnerId),
+	)
+}
+
+func (m *LikeRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("feed_id", m.FeedId),
+		validate.Positive("user_id", m.UserId),
+		validate.Positive("owner_id", m.OwnerId),
+	)
+}
+
+func (m *Comment) Validate() error {
+	return validate.Check(
+		validate.Positive("feed_id", m.FeedId),
+		validate.Positive("user_id", m.UserId),
+		validate.NonNegative("parent_id", m.ParentId),
+		validate.Positive("owner_id", m.OwnerId),
+		validate.Text("content", m.Content, MaxCommentLength, true),
+	)
+}
+
+func (m *GetCommentsRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("feed_id", m.FeedId),
+		validate.NonNegative("parent_id", m.ParentId),
+		validate.NonNegative("cursor", m.Cursor),
+		validate.PageSize("size", m.Size),
+		validate.Positive("owner_id", m.OwnerId),
+	)
+}
+
+func (m *ListHeldFeedsRequest) Validate() error {
+	return validate.Check(
+		validate.PageSize("size", m.Size),
+	)
+}
+
+func (m *ReviewFeedRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("id", m.Id),
+		validate.Positive("owner_id", m.OwnerId),
+		validate.Text("moderator", m.Moderator, MaxNameLength, true),
+		validate.Text("note", m.Note, MaxNoteLength, false),
+	)
+}
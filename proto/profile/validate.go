@@ -0,0 +1,11 @@
+package profile
+
+import "github.com/buptmiao/microservice-app/validate"
+
+// The rules of the requests, see validate.
+
+func (m *GetProfileRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("user_id", m.UserId),
+	)
+}
@@ -11,6 +11,12 @@ It is generated from these files:
 It has these top-level messages:
 	GetTopicRequest
 	GetTopicResponse
+	CreateTopicRequest
+	SearchTopicsRequest
+	SearchTopicsResponse
+	ResolveTagsRequest
+	ResolveTagsResponse
+	OkResponse
 */
 package topic
 
@@ -82,9 +88,127 @@ func (m *GetTopicResponse) GetContent() string {
 	return ""
 }
 
+type CreateTopicRequest struct {
+	TopicId int64  `protobuf:"varint,1,opt,name=topic_id,json=topicId" json:"topic_id,omitempty"`
+	Subject string `protobuf:"bytes,2,opt,name=subject" json:"subject,omitempty"`
+	Content string `protobuf:"bytes,3,opt,name=content" json:"content,omitempty"`
+}
+
+func (m *CreateTopicRequest) Reset()                    { *m = CreateTopicRequest{} }
+func (m *CreateTopicRequest) String() string            { return proto.CompactTextString(m) }
+func (*CreateTopicRequest) ProtoMessage()               {}
+func (*CreateTopicRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *CreateTopicRequest) GetTopicId() int64 {
+	if m != nil {
+		return m.TopicId
+	}
+	return 0
+}
+
+func (m *CreateTopicRequest) GetSubject() string {
+	if m != nil {
+		return m.Subject
+	}
+	return ""
+}
+
+func (m *CreateTopicRequest) GetContent() string {
+	if m != nil {
+		return m.Content
+	}
+	return ""
+}
+
+type SearchTopicsRequest struct {
+	Query string `protobuf:"bytes,1,opt,name=query" json:"query,omitempty"`
+	Size  int64  `protobuf:"varint,2,opt,name=size" json:"size,omitempty"`
+}
+
+func (m *SearchTopicsRequest) Reset()                    { *m = SearchTopicsRequest{} }
+func (m *SearchTopicsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SearchTopicsRequest) ProtoMessage()               {}
+func (*SearchTopicsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *SearchTopicsRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *SearchTopicsRequest) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+type SearchTopicsResponse struct {
+	Topics []*GetTopicResponse `protobuf:"bytes,1,rep,name=topics" json:"topics,omitempty"`
+}
+
+func (m *SearchTopicsResponse) Reset()                    { *m = SearchTopicsResponse{} }
+func (m *SearchTopicsResponse) String() string            { return proto.CompactTextString(m) }
+func (*SearchTopicsResponse) ProtoMessage()               {}
+func (*SearchTopicsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *SearchTopicsResponse) GetTopics() []*GetTopicResponse {
+	if m != nil {
+		return m.Topics
+	}
+	return nil
+}
+
+type ResolveTagsRequest struct {
+	Tags []string `protobuf:"bytes,1,rep,name=tags" json:"tags,omitempty"`
+}
+
+func (m *ResolveTagsRequest) Reset()                    { *m = ResolveTagsRequest{} }
+func (m *ResolveTagsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ResolveTagsRequest) ProtoMessage()               {}
+func (*ResolveTagsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ResolveTagsRequest) GetTags() []string {
+	if m != nil {
+		return m.Tags
+	}
+	return nil
+}
+
+type ResolveTagsResponse struct {
+	TopicIds map[string]int64 `protobuf:"bytes,1,rep,name=topic_ids,json=topicIds" json:"topic_ids,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+}
+
+func (m *ResolveTagsResponse) Reset()                    { *m = ResolveTagsResponse{} }
+func (m *ResolveTagsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ResolveTagsResponse) ProtoMessage()               {}
+func (*ResolveTagsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ResolveTagsResponse) GetTopicIds() map[string]int64 {
+	if m != nil {
+		return m.TopicIds
+	}
+	return nil
+}
+
+type OkResponse struct {
+}
+
+func (m *OkResponse) Reset()                    { *m = OkResponse{} }
+func (m *OkResponse) String() string            { return proto.CompactTextString(m) }
+func (*OkResponse) ProtoMessage()               {}
+func (*OkResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
 func init() {
 	proto.RegisterType((*GetTopicRequest)(nil), "topic.GetTopicRequest")
 	proto.RegisterType((*GetTopicResponse)(nil), "topic.GetTopicResponse")
+	proto.RegisterType((*CreateTopicRequest)(nil), "topic.CreateTopicRequest")
+	proto.RegisterType((*SearchTopicsRequest)(nil), "topic.SearchTopicsRequest")
+	proto.RegisterType((*SearchTopicsResponse)(nil), "topic.SearchTopicsResponse")
+	proto.RegisterType((*ResolveTagsRequest)(nil), "topic.ResolveTagsRequest")
+	proto.RegisterType((*ResolveTagsResponse)(nil), "topic.ResolveTagsResponse")
+	proto.RegisterType((*OkResponse)(nil), "topic.OkResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -100,6 +224,9 @@ const _ = grpc.SupportPackageIsVersion4
 type TopicClient interface {
 	// Sums two integers.
 	GetTopic(ctx context.Context, in *GetTopicRequest, opts ...grpc.CallOption) (*GetTopicResponse, error)
+	CreateTopic(ctx context.Context, in *CreateTopicRequest, opts ...grpc.CallOption) (*OkResponse, error)
+	SearchTopics(ctx context.Context, in *SearchTopicsRequest, opts ...grpc.CallOption) (*SearchTopicsResponse, error)
+	ResolveTags(ctx context.Context, in *ResolveTagsRequest, opts ...grpc.CallOption) (*ResolveTagsResponse, error)
 }
 
 type topicClient struct {
@@ -119,11 +246,41 @@ func (c *topicClient) GetTopic(ctx context.Context, in *GetTopicRequest, opts ..
 	return out, nil
 }
 
+func (c *topicClient) CreateTopic(ctx context.Context, in *CreateTopicRequest, opts ...grpc.CallOption) (*OkResponse, error) {
+	out := new(OkResponse)
+	err := grpc.Invoke(ctx, "/topic.Topic/CreateTopic", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *topicClient) SearchTopics(ctx context.Context, in *SearchTopicsRequest, opts ...grpc.CallOption) (*SearchTopicsResponse, error) {
+	out := new(SearchTopicsResponse)
+	err := grpc.Invoke(ctx, "/topic.Topic/SearchTopics", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *topicClient) ResolveTags(ctx context.Context, in *ResolveTagsRequest, opts ...grpc.CallOption) (*ResolveTagsResponse, error) {
+	out := new(ResolveTagsResponse)
+	err := grpc.Invoke(ctx, "/topic.Topic/ResolveTags", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Topic service
 
 type TopicServer interface {
 	// Sums two integers.
 	GetTopic(context.Context, *GetTopicRequest) (*GetTopicResponse, error)
+	CreateTopic(context.Context, *CreateTopicRequest) (*OkResponse, error)
+	SearchTopics(context.Context, *SearchTopicsRequest) (*SearchTopicsResponse, error)
+	ResolveTags(context.Context, *ResolveTagsRequest) (*ResolveTagsResponse, error)
 }
 
 func RegisterTopicServer(s *grpc.Server, srv TopicServer) {
@@ -148,6 +305,60 @@ func _Topic_GetTopic_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Topic_CreateTopic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTopicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TopicServer).CreateTopic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/topic.Topic/CreateTopic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TopicServer).CreateTopic(ctx, req.(*CreateTopicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Topic_SearchTopics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchTopicsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TopicServer).SearchTopics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/topic.Topic/SearchTopics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TopicServer).SearchTopics(ctx, req.(*SearchTopicsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Topic_ResolveTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TopicServer).ResolveTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/topic.Topic/ResolveTags",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TopicServer).ResolveTags(ctx, req.(*ResolveTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Topic_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "topic.Topic",
 	HandlerType: (*TopicServer)(nil),
@@ -156,6 +367,18 @@ var _Topic_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetTopic",
 			Handler:    _Topic_GetTopic_Handler,
 		},
+		{
+			MethodName: "CreateTopic",
+			Handler:    _Topic_CreateTopic_Handler,
+		},
+		{
+			MethodName: "SearchTopics",
+			Handler:    _Topic_SearchTopics_Handler,
+		},
+		{
+			MethodName: "ResolveTags",
+			Handler:    _Topic_ResolveTags_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "topic.proto",
@@ -164,15 +387,30 @@ var _Topic_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("topic.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 159 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0xc9, 0x2f, 0xc8,
-	0x4c, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x05, 0x73, 0x94, 0x74, 0xb8, 0xf8, 0xdd,
-	0x53, 0x4b, 0x42, 0x40, 0xec, 0xa0, 0xd4, 0xc2, 0xd2, 0xd4, 0xe2, 0x12, 0x21, 0x49, 0x2e, 0x0e,
-	0xb0, 0x5c, 0x7c, 0x66, 0x8a, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0x73, 0x10, 0x3b, 0x98, 0xef, 0x99,
-	0xa2, 0x94, 0xc8, 0x25, 0x80, 0x50, 0x5d, 0x5c, 0x90, 0x9f, 0x57, 0x9c, 0x8a, 0x47, 0xb9, 0x90,
-	0x04, 0x17, 0x7b, 0x71, 0x69, 0x52, 0x56, 0x6a, 0x72, 0x89, 0x04, 0x13, 0x50, 0x86, 0x33, 0x08,
-	0xc6, 0x05, 0xc9, 0x24, 0xe7, 0xe7, 0x95, 0xa4, 0xe6, 0x95, 0x48, 0x30, 0x43, 0x64, 0xa0, 0x5c,
-	0x23, 0x37, 0x2e, 0x56, 0xb0, 0xf9, 0x42, 0xb6, 0x5c, 0x1c, 0x30, 0xbb, 0x84, 0xc4, 0xf4, 0x20,
-	0x4e, 0x47, 0x73, 0xaa, 0x94, 0x38, 0x86, 0x38, 0xc4, 0x51, 0x4a, 0x0c, 0x49, 0x6c, 0x60, 0x6f,
-	0x1a, 0x03, 0x02, 0x00, 0x00, 0xff, 0xff, 0x22, 0x0a, 0xad, 0x52, 0xf5, 0x00, 0x00, 0x00,
+	// 386 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x93, 0x4f, 0x6f, 0xe2, 0x30,
+	0x10, 0xc5, 0x09, 0xe1, 0x5f, 0x26, 0xac, 0x96, 0x1d, 0xd0, 0x6e, 0xc8, 0x5e, 0x90, 0x4f, 0x39,
+	0xac, 0xb2, 0x12, 0xbd, 0x54, 0xad, 0x50, 0x0f, 0x15, 0x45, 0x9c, 0x2a, 0xb9, 0xdc, 0xab, 0x10,
+	0x2c, 0x4a, 0x41, 0x09, 0xc4, 0x0e, 0x12, 0xfd, 0x24, 0xed, 0xb7, 0xad, 0xe2, 0xd8, 0xfc, 0x29,
+	0x50, 0x55, 0xbd, 0xcd, 0xd3, 0x3c, 0xcd, 0x9b, 0xfc, 0xc6, 0x01, 0x5b, 0xc4, 0xcb, 0x59, 0xe8,
+	0x2f, 0x93, 0x58, 0xc4, 0x58, 0x96, 0x82, 0xfc, 0x83, 0x9f, 0x03, 0x26, 0x46, 0x59, 0x4d, 0xd9,
+	0x2a, 0x65, 0x5c, 0x60, 0x1b, 0x6a, 0xb2, 0xf7, 0x38, 0x9b, 0x38, 0x46, 0xc7, 0xf0, 0x4c, 0x5a,
+	0x95, 0x7a, 0x38, 0x21, 0x01, 0x34, 0x76, 0x6e, 0xbe, 0x8c, 0x23, 0xce, 0x3e, 0xb1, 0xa3, 0x03,
+	0x55, 0x9e, 0x8e, 0x9f, 0x59, 0x28, 0x9c, 0x62, 0xc7, 0xf0, 0x2c, 0xaa, 0x65, 0xd6, 0x09, 0xe3,
+	0x48, 0xb0, 0x48, 0x38, 0x66, 0xde, 0x51, 0x92, 0x84, 0x80, 0xb7, 0x09, 0x0b, 0x04, 0xfb, 0xe2,
+	0x4e, 0xdf, 0x0a, 0xb9, 0x81, 0xe6, 0x03, 0x0b, 0x92, 0xf0, 0x49, 0x86, 0x70, 0x9d, 0xd2, 0x82,
+	0xf2, 0x2a, 0x65, 0xc9, 0x46, 0x46, 0x58, 0x34, 0x17, 0x88, 0x50, 0xe2, 0xb3, 0x17, 0x26, 0xa7,
+	0x9b, 0x54, 0xd6, 0x64, 0x00, 0xad, 0xc3, 0x01, 0x0a, 0xc6, 0x7f, 0xa8, 0xc8, 0xbd, 0xb8, 0x63,
+	0x74, 0x4c, 0xcf, 0xee, 0xfe, 0xf1, 0xa5, 0xf4, 0x3f, 0x52, 0xa3, 0xca, 0x46, 0x3c, 0x40, 0xca,
+	0x78, 0xbc, 0x58, 0xb3, 0x51, 0x30, 0xdd, 0x2e, 0x82, 0x50, 0x12, 0xc1, 0x34, 0x1f, 0x62, 0x51,
+	0x59, 0x93, 0x37, 0x03, 0x9a, 0x07, 0x56, 0x15, 0xd9, 0x07, 0x4b, 0xa3, 0xd1, 0xa9, 0x9e, 0x4a,
+	0x3d, 0x61, 0xf7, 0x47, 0x39, 0x36, 0xde, 0x8f, 0x44, 0xb2, 0xa1, 0x35, 0x45, 0x91, 0xbb, 0xd7,
+	0xf0, 0xe3, 0xa0, 0x85, 0x0d, 0x30, 0xe7, 0x4c, 0xa3, 0xc8, 0xca, 0x0c, 0xcf, 0x3a, 0x58, 0xa4,
+	0x9a, 0x44, 0x2e, 0xae, 0x8a, 0x97, 0x06, 0xa9, 0x03, 0xdc, 0xcf, 0x75, 0x44, 0xf7, 0xb5, 0x08,
+	0x65, 0x39, 0x0b, 0x7b, 0x50, 0xd3, 0x5f, 0x8e, 0xbf, 0x8f, 0x50, 0xc8, 0x6f, 0x75, 0xcf, 0x21,
+	0x22, 0x05, 0xec, 0x81, 0xbd, 0xf7, 0x16, 0xb0, 0xad, 0x9c, 0xc7, 0xef, 0xc3, 0xfd, 0xa5, 0x5a,
+	0xbb, 0x2d, 0x48, 0x01, 0x87, 0x50, 0xdf, 0x3f, 0x12, 0xba, 0xca, 0x74, 0xe2, 0xf4, 0xee, 0xdf,
+	0x93, 0xbd, 0xed, 0xa8, 0x3b, 0xb0, 0xf7, 0x60, 0x6e, 0x37, 0x39, 0x3e, 0x9d, 0xeb, 0x9e, 0x67,
+	0x4f, 0x0a, 0xe3, 0x8a, 0xfc, 0xf9, 0x2e, 0xde, 0x07, 0x00, 0x86, 0xf5, 0x6e, 0x8f, 0x8b, 0x03,
+	0x00, 0x00,
 }
@@ -0,0 +1,44 @@
+package topic
+
+import "github.com/buptmiao/microservice-app/validate"
+
+// Content limits, in characters.
+const (
+	MaxSubjectLength = 100
+	MaxContentLength = 2000
+	MaxQueryLength   = 256
+	MaxTags          = 20
+	MaxTagLength     = 64
+)
+
+// DefaultPageSize is the number of topics found when the request does not
+// set a size.
+const DefaultPageSize = 20
+
+// The rules of the requests, see validate.
+
+func (m *GetTopicRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("topic_id", m.TopicId),
+	)
+}
+
+func (m *CreateTopicRequest) Validate() error {
+	return validate.Check(
+		validate.Positive("topic_id", m.TopicId),
+		validate.Text("subject", m.Subject, MaxSubjectLength, true),
+		validate.Text("content", m.Content, MaxContentLength, false),
+	)
+}
+
+func (m *SearchTopicsRequest) Validate() error {
+	return validate.Check(
+		validate.Text("query", m.Query, MaxQueryLength, true),
+		validate.PageSize("size", m.Size),
+	)
+}
+
+func (m *ResolveTagsRequest) Validate() error {
+	rules := []validate.Rule{validate.Items("tags", len(m.Tags), MaxTags)}
+	return validate.Check(append(rules, validate.Texts("tags", m.Tags, MaxTagLength)...)...)
+}
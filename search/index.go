@@ -0,0 +1,220 @@
+// Package search implements a small in-memory inverted index used by the
+// services to answer full-text queries.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// fieldGap separates the positions of two fields of one document, so that a
+// phrase never matches across a field boundary.
+const fieldGap = 1000
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Hit is a matching document and its relevance score.
+type Hit struct {
+	ID    int64
+	Score float64
+}
+
+type document struct {
+	length int
+	terms  []string
+}
+
+// Index is an inverted index from terms to the documents and positions
+// containing them. It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	postings map[string]map[int64][]int
+	docs     map[int64]*document
+	totalLen int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[int64][]int),
+		docs:     make(map[int64]*document),
+	}
+}
+
+// Tokenize lower-cases s and splits it on anything which is not a letter or a digit.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Tag normalizes s into a hashtag: its lower-cased letters and digits only,
+// so that the topic "Go Kit" and the hashtag #gokit match.
+func Tag(s string) string {
+	return strings.Join(Tokenize(s), "")
+}
+
+// Add indexes the fields of document id, replacing any previous version.
+func (idx *Index) Add(id int64, fields ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+
+	doc := &document{}
+	seen := make(map[string]bool)
+	pos := 0
+	for _, field := range fields {
+		for _, term := range Tokenize(field) {
+			docs, ok := idx.postings[term]
+			if !ok {
+				docs = make(map[int64][]int)
+				idx.postings[term] = docs
+			}
+			docs[id] = append(docs[id], pos)
+			if !seen[term] {
+				seen[term] = true
+				doc.terms = append(doc.terms, term)
+			}
+			doc.length++
+			pos++
+		}
+		pos += fieldGap
+	}
+	idx.docs[id] = doc
+	idx.totalLen += doc.length
+}
+
+// Remove drops document id from the index.
+func (idx *Index) Remove(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int64) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.totalLen -= doc.length
+	delete(idx.docs, id)
+}
+
+// Search returns at most limit documents matching every clause of query,
+// best match first. A clause is a term, a "quoted phrase" or a prefix*.
+func (idx *Index) Search(query string, limit int) []Hit {
+	clauses := Parse(query)
+	if len(clauses) == 0 || limit <= 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var scores map[int64]float64
+	for _, c := range clauses {
+		matched := idx.match(c)
+		if scores == nil {
+			scores = matched
+			continue
+		}
+		for id, s := range scores {
+			if ms, ok := matched[id]; ok {
+				scores[id] = s + ms
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, s := range scores {
+		hits = append(hits, Hit{ID: id, Score: s})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// match returns the documents matching the clause with their score.
+func (idx *Index) match(c Clause) map[int64]float64 {
+	res := make(map[int64]float64)
+	switch {
+	case c.Prefix:
+		for term, docs := range idx.postings {
+			if !strings.HasPrefix(term, c.Terms[0]) {
+				continue
+			}
+			for id, positions := range docs {
+				res[id] += idx.score(len(positions), len(docs), id)
+			}
+		}
+	case len(c.Terms) == 1:
+		docs := idx.postings[c.Terms[0]]
+		for id, positions := range docs {
+			res[id] = idx.score(len(positions), len(docs), id)
+		}
+	default:
+		first := idx.postings[c.Terms[0]]
+		for id, positions := range first {
+			if n := idx.phraseCount(id, positions, c.Terms[1:]); n > 0 {
+				for _, term := range c.Terms {
+					res[id] += idx.score(n, len(idx.postings[term]), id)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// phraseCount counts the occurrences of the phrase starting at positions.
+func (idx *Index) phraseCount(id int64, positions []int, rest []string) int {
+	n := 0
+	for _, p := range positions {
+		ok := true
+		for i, term := range rest {
+			if !contains(idx.postings[term][id], p+i+1) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+func contains(positions []int, p int) bool {
+	i := sort.SearchInts(positions, p)
+	return i < len(positions) && positions[i] == p
+}
+
+// score is the BM25 weight of a term occurring tf times in document id and
+// in df documents overall.
+func (idx *Index) score(tf, df int, id int64) float64 {
+	n := float64(len(idx.docs))
+	idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+	avg := float64(idx.totalLen) / n
+	length := float64(idx.docs[id].length)
+	return idf * float64(tf) * (k1 + 1) / (float64(tf) + k1*(1-b+b*length/avg))
+}
@@ -0,0 +1,47 @@
+package search
+
+import "strings"
+
+// Clause is one part of a query. A clause with several terms is a phrase.
+type Clause struct {
+	Terms  []string
+	Prefix bool
+}
+
+// Parse splits a query into clauses: "quoted phrases", prefix* and plain terms.
+func Parse(query string) []Clause {
+	var clauses []Clause
+	for len(query) > 0 {
+		query = strings.TrimSpace(query)
+		if len(query) == 0 {
+			break
+		}
+		if query[0] == '"' {
+			end := strings.IndexByte(query[1:], '"')
+			var phrase string
+			if end < 0 {
+				phrase, query = query[1:], ""
+			} else {
+				phrase, query = query[1:end+1], query[end+2:]
+			}
+			if terms := Tokenize(phrase); len(terms) > 0 {
+				clauses = append(clauses, Clause{Terms: terms})
+			}
+			continue
+		}
+		word := query
+		if i := strings.IndexAny(query, " \t\""); i >= 0 {
+			word, query = query[:i], query[i:]
+		} else {
+			query = ""
+		}
+		terms := Tokenize(word)
+		for _, term := range terms {
+			clauses = append(clauses, Clause{Terms: []string{term}})
+		}
+		if len(terms) > 0 && strings.HasSuffix(word, "*") {
+			clauses[len(clauses)-1].Prefix = true
+		}
+	}
+	return clauses
+}
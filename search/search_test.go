@@ -0,0 +1,97 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got := Parse(`Go "service  Discovery" disc* "unterminated phrase`)
+	want := []Clause{
+		{Terms: []string{"go"}},
+		{Terms: []string{"service", "discovery"}},
+		{Terms: []string{"disc"}, Prefix: true},
+		{Terms: []string{"unterminated", "phrase"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if clauses := Parse(`  "" * `); len(clauses) != 0 {
+		t.Errorf("empty query parsed to %+v", clauses)
+	}
+}
+
+func TestTag(t *testing.T) {
+	if got := Tag("Go Kit!"); got != "gokit" {
+		t.Errorf("got %q, want gokit", got)
+	}
+}
+
+func ids(hits []Hit) []int64 {
+	res := []int64{}
+	for _, h := range hits {
+		res = append(res, h.ID)
+	}
+	return res
+}
+
+func TestSearch(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "Go microservices", "service discovery with etcd")
+	idx.Add(2, "Cooking", "how to discover new recipes")
+	idx.Add(3, "etcd etcd etcd", "etcd")
+	idx.Add(4, "service", "discovery")
+
+	for _, c := range []struct {
+		query string
+		want  []int64
+	}{
+		// the document repeating the term ranks first.
+		{"etcd", []int64{3, 1}},
+		{"ETCD go", []int64{1}},
+		{`"service discovery"`, []int64{1}},
+		// a phrase does not match across fields.
+		{`"discovery service"`, []int64{}},
+		{"disc*", []int64{4, 2, 1}},
+		{"missing", []int64{}},
+	} {
+		got := ids(idx.Search(c.query, 10))
+		if c.query == "disc*" {
+			// the prefix matches every document with discover*, in any order.
+			got = sorted(got)
+			c.want = sorted(c.want)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("query %s: got %v, want %v", c.query, got, c.want)
+		}
+	}
+	if got := idx.Search("etcd", 1); len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("limit 1: got %v", got)
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "old content")
+	idx.Add(1, "new content")
+	if hits := idx.Search("old", 10); len(hits) != 0 {
+		t.Errorf("replaced version still found: %v", hits)
+	}
+	if got := ids(idx.Search("new", 10)); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("got %v, want [1]", got)
+	}
+	idx.Remove(1)
+	if hits := idx.Search("content", 10); len(hits) != 0 {
+		t.Errorf("removed document found: %v", hits)
+	}
+	if len(idx.postings) != 0 || idx.totalLen != 0 {
+		t.Errorf("index not empty after removal: %d terms, length %d", len(idx.postings), idx.totalLen)
+	}
+}
+
+func sorted(s []int64) []int64 {
+	res := append([]int64(nil), s...)
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
@@ -0,0 +1,120 @@
+// Package slo declares the service level objectives of the services and the
+// gateway, counts the requests they are measured on, and generates the
+// Prometheus burn-rate alerts and the Grafana dashboard tracking them, see
+// cmd/slogen.
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Config lists the objectives of every service, see monitor/slo.json.
+type Config struct {
+	Services []Service `json:"services"`
+}
+
+// Service holds the objectives of one service, the gateway included.
+type Service struct {
+	Name       string      `json:"name"`
+	Objectives []Objective `json:"objectives"`
+}
+
+// Objective is what the requests to one route are expected to meet over the
+// 30 days window.
+type Objective struct {
+	// Route is a gRPC method of a service, e.g. GetFeeds, or a route of the
+	// gateway, e.g. "GET /api/feed/get_feeds".
+	Route string `json:"route"`
+	// Availability is the percentage of the requests which must not fail,
+	// e.g. 99.9, none when zero.
+	Availability float64 `json:"availability,omitempty"`
+	// Latency is the objective on the duration of the requests, if any.
+	Latency *Latency `json:"latency,omitempty"`
+}
+
+// Latency is the percentage of the requests which must be faster than
+// Threshold.
+type Latency struct {
+	Threshold Duration `json:"threshold"`
+	Target    float64  `json:"target"`
+}
+
+// Duration is a time.Duration written as "250ms" in JSON.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Load reads the Config of a JSON file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Validate checks the targets are percentages below 100, as an objective of
+// 100% leaves no error budget.
+func (c *Config) Validate() error {
+	for _, s := range c.Services {
+		if s.Name == "" {
+			return fmt.Errorf("service without a name")
+		}
+		for _, o := range s.Objectives {
+			if o.Route == "" {
+				return fmt.Errorf("%s: objective without a route", s.Name)
+			}
+			if o.Availability < 0 || o.Availability >= 100 {
+				return fmt.Errorf("%s %s: availability %v is not in [0, 100)", s.Name, o.Route, o.Availability)
+			}
+			if o.Latency != nil {
+				if o.Latency.Target <= 0 || o.Latency.Target >= 100 {
+					return fmt.Errorf("%s %s: latency target %v is not in (0, 100)", s.Name, o.Route, o.Latency.Target)
+				}
+				if o.Latency.Threshold <= 0 {
+					return fmt.Errorf("%s %s: latency threshold must be positive", s.Name, o.Route)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Service returns the objectives of the service called name.
+func (c *Config) Service(name string) (Service, bool) {
+	for _, s := range c.Services {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Service{}, false
+}
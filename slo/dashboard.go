@@ -0,0 +1,153 @@
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// period is the window the objectives are met over.
+const period = "30d"
+
+type panel map[string]interface{}
+
+// Dashboard returns the Grafana dashboard of the objectives of c: a row per
+// objective showing the indicator and the error budget left over the 30
+// days, and the burn rates the alerts fire on.
+func Dashboard(c *Config) ([]byte, error) {
+	rows := []interface{}{}
+	id := 0
+	next := func() int {
+		id++
+		return id
+	}
+	for _, s := range c.Services {
+		for _, o := range s.Objectives {
+			selector := fmt.Sprintf("{service=%q,route=%q}", s.Name, o.Route)
+			panels := []interface{}{}
+			for _, ind := range indicators(o) {
+				bad := "slo_errors_total"
+				title := fmt.Sprintf("availability, %s%% objective", number(ind.Target))
+				if ind.Name == "latency" {
+					bad = "slo_slow_requests_total"
+					title = fmt.Sprintf("requests faster than %s, %s%% objective", o.Latency.Threshold, number(ind.Target))
+				}
+				ratio := fmt.Sprintf("sum(increase(%s%s[%s])) / sum(increase(slo_requests_total%s[%s]))", bad, selector, period, selector, period)
+				panels = append(panels,
+					singlestat(next(), title, "percentunit",
+						"1 - "+ratio,
+						fmt.Sprintf("%s,%s", number(ind.Target/100-budget(ind.Target)), number(ind.Target/100))),
+					singlestat(next(), "error budget left", "percentunit",
+						fmt.Sprintf("1 - (%s) / %s", ratio, number(budget(ind.Target))),
+						"0,0.25"),
+					graph(next(), ind.Name+" burn rate", ind.Ratio, selector, budget(ind.Target)),
+				)
+			}
+			rows = append(rows, map[string]interface{}{
+				"title":     fmt.Sprintf("%s %s", s.Name, o.Route),
+				"showTitle": true,
+				"collapse":  false,
+				"editable":  true,
+				"height":    "200px",
+				"panels":    panels,
+			})
+		}
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"__inputs": []interface{}{
+			map[string]interface{}{
+				"name":       "DS_PROMSOURCE",
+				"label":      "PromSource",
+				"type":       "datasource",
+				"pluginId":   "prometheus",
+				"pluginName": "Prometheus",
+			},
+		},
+		"id":              nil,
+		"title":           "SLO Dashboard",
+		"description":     "Generated by cmd/slogen from the SLO config, do not edit.",
+		"tags":            []string{"slo"},
+		"style":           "dark",
+		"timezone":        "browser",
+		"editable":        true,
+		"sharedCrosshair": true,
+		"rows":            rows,
+		"time":            map[string]string{"from": "now-1d", "to": "now"},
+		"refresh":         "1m",
+		"schemaVersion":   12,
+		"version":         1,
+	}, "", "  ")
+}
+
+func singlestat(id int, title, format, expr, thresholds string) panel {
+	return panel{
+		"id":              id,
+		"type":            "singlestat",
+		"title":           title,
+		"datasource":      "${DS_PROMSOURCE}",
+		"span":            2,
+		"format":          format,
+		"decimals":        3,
+		"valueName":       "current",
+		"colorValue":      true,
+		"colors":          []string{"rgba(245, 54, 54, 0.9)", "rgba(237, 129, 40, 0.89)", "rgba(50, 172, 45, 0.97)"},
+		"thresholds":      thresholds,
+		"targets":         []interface{}{map[string]interface{}{"expr": expr, "intervalFactor": 2, "refId": "A"}},
+		"sparkline":       map[string]interface{}{"show": false},
+		"gauge":           map[string]interface{}{"show": false},
+		"nullPointMode":   "connected",
+		"valueFontSize":   "80%",
+		"editable":        true,
+		"mappingType":     1,
+		"rangeMaps":       []interface{}{},
+		"valueMaps":       []interface{}{},
+		"postfix":         "",
+		"prefix":          "",
+		"cacheTimeout":    nil,
+		"interval":        nil,
+		"links":           []interface{}{},
+		"maxDataPoints":   100,
+		"colorBackground": false,
+	}
+}
+
+// graph plots the ratio of bad requests over the windows of the fastest
+// page divided by the budget, 1 being the rate spending the budget exactly
+// over the 30 days.
+func graph(id int, title, ratio, selector string, budget float64) panel {
+	targets := []interface{}{}
+	for i, window := range []string{"1h", "6h", "1d"} {
+		targets = append(targets, map[string]interface{}{
+			"expr":           fmt.Sprintf("%s:rate%s%s / %s", ratio, window, selector, number(budget)),
+			"legendFormat":   window,
+			"intervalFactor": 2,
+			"refId":          string(rune('A' + i)),
+		})
+	}
+	return panel{
+		"id":            id,
+		"type":          "graph",
+		"title":         title,
+		"datasource":    "${DS_PROMSOURCE}",
+		"span":          8,
+		"targets":       targets,
+		"lines":         true,
+		"linewidth":     2,
+		"fill":          1,
+		"nullPointMode": "connected",
+		"legend":        map[string]interface{}{"show": true, "current": true, "values": true},
+		"tooltip":       map[string]interface{}{"shared": true, "value_type": "cumulative"},
+		"grid": map[string]interface{}{
+			"threshold1":      1,
+			"threshold1Color": "rgba(237, 129, 40, 0.22)",
+			"threshold2":      14.4,
+			"threshold2Color": "rgba(234, 112, 112, 0.22)",
+		},
+		"yaxes": []interface{}{
+			map[string]interface{}{"format": "short", "logBase": 1, "min": 0, "show": true},
+			map[string]interface{}{"format": "short", "logBase": 1, "show": false},
+		},
+		"xaxis":    map[string]interface{}{"show": true},
+		"editable": true,
+		"links":    []interface{}{},
+	}
+}
@@ -0,0 +1,105 @@
+package slo
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The counters the objectives are measured on. Only the routes with an
+// objective are counted, and their series exist from the start, so that the
+// ratios of the recording rules are defined before the first failure.
+var (
+	requests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "slo",
+		Name:      "requests_total",
+		Help:      "Number of requests to the routes with an objective.",
+	}, []string{"service", "route"})
+	failures metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "slo",
+		Name:      "errors_total",
+		Help:      "Number of requests failed by the fault of the server, for the availability objectives.",
+	}, []string{"service", "route"})
+	slowRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "slo",
+		Name:      "slow_requests_total",
+		Help:      "Number of requests slower than the threshold of the latency objectives.",
+	}, []string{"service", "route"})
+)
+
+// tracked is the Service of the process, set by Init.
+var tracked atomic.Value
+
+// Init counts the requests of the routes of the service called name which
+// have an objective in c.
+func Init(c *Config, name string) {
+	s, _ := c.Service(name)
+	s.Name = name
+	for _, o := range s.Objectives {
+		requests.With("service", name, "route", o.Route).Add(0)
+		failures.With("service", name, "route", o.Route).Add(0)
+		slowRequests.With("service", name, "route", o.Route).Add(0)
+	}
+	tracked.Store(s)
+}
+
+func lookup(route string) (string, Objective, bool) {
+	s, ok := tracked.Load().(Service)
+	if !ok {
+		return "", Objective{}, false
+	}
+	for _, o := range s.Objectives {
+		if o.Route == route {
+			return s.Name, o, true
+		}
+	}
+	return "", Objective{}, false
+}
+
+// Record counts a request to route which took took, failed telling whether
+// it failed by the fault of the server. Routes without an objective are not
+// counted.
+func Record(route string, failed bool, took time.Duration) {
+	name, o, ok := lookup(route)
+	if !ok {
+		return
+	}
+	requests.With("service", name, "route", route).Add(1)
+	if failed {
+		failures.With("service", name, "route", route).Add(1)
+	}
+	if o.Latency != nil && took > time.Duration(o.Latency.Threshold) {
+		slowRequests.With("service", name, "route", route).Add(1)
+	}
+}
+
+// Middleware records the requests of the endpoint of method.
+func Middleware(method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func(begin time.Time) {
+				Record(method, ServerFault(err), time.Since(begin))
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+// ServerFault tells whether err is a failure of the server rather than of
+// the request. The services return their domain errors, e.g. feed not
+// found, with the codes of the request faults, and errors without a code,
+// Unknown, are not counted either.
+func ServerFault(err error) bool {
+	switch status.Code(err) {
+	case codes.Internal, codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.DataLoss, codes.Unimplemented:
+		return true
+	}
+	return err == context.DeadlineExceeded
+}
@@ -0,0 +1,107 @@
+package slo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Windows are the ranges of the ratios recorded for the burn-rate alerts.
+var Windows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+
+// burn is an alert firing when the error budget is spent Factor times faster
+// than allowed over both the Long and the Short window: the long window
+// avoids paging on a blip, the short one resets the alert soon after the
+// failures stop. The factors spend 2% of a 30 days budget in 1h, 5% in 6h,
+// 10% in 1d and 10% in 3d.
+type burn struct {
+	Severity    string
+	Factor      float64
+	Long, Short string
+}
+
+var burns = []burn{
+	{"page", 14.4, "1h", "5m"},
+	{"page", 6, "6h", "30m"},
+	{"ticket", 3, "1d", "2h"},
+	{"ticket", 1, "3d", "6h"},
+}
+
+// indicator is what an objective is measured on.
+type indicator struct {
+	Name   string // availability or latency
+	Ratio  string // the recorded ratio of bad requests
+	Target float64
+}
+
+func indicators(o Objective) []indicator {
+	var res []indicator
+	if o.Availability > 0 {
+		res = append(res, indicator{"availability", "slo:error_ratio", o.Availability})
+	}
+	if o.Latency != nil {
+		res = append(res, indicator{"latency", "slo:slow_ratio", o.Latency.Target})
+	}
+	return res
+}
+
+// budget is the ratio of bad requests allowed by a target percentage.
+func budget(target float64) float64 {
+	return 1 - target/100
+}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// WriteRules writes the recording rules and the multi-window burn-rate
+// alerts of the objectives of c, in the rule format of Prometheus 1.x run
+// by monitor/docker-compose.yml. `promtool update rules` converts them for
+// Prometheus 2.
+func WriteRules(w io.Writer, c *Config) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Generated by cmd/slogen from the SLO config, DO NOT EDIT.")
+	fmt.Fprintln(bw)
+	for _, r := range []struct{ ratio, bad string }{
+		{"slo:error_ratio", "slo_errors_total"},
+		{"slo:slow_ratio", "slo_slow_requests_total"},
+	} {
+		for _, window := range Windows {
+			fmt.Fprintf(bw, "%s:rate%s = sum(rate(%s[%s])) by (service, route) / sum(rate(slo_requests_total[%s])) by (service, route)\n",
+				r.ratio, window, r.bad, window, window)
+		}
+		fmt.Fprintln(bw)
+	}
+
+	for _, s := range c.Services {
+		for _, o := range s.Objectives {
+			selector := fmt.Sprintf("{service=%q,route=%q}", s.Name, o.Route)
+			for _, ind := range indicators(o) {
+				for _, severity := range []string{"page", "ticket"} {
+					expr := ""
+					for _, b := range burns {
+						if b.Severity != severity {
+							continue
+						}
+						threshold := number(b.Factor * budget(ind.Target))
+						if expr != "" {
+							expr += "\n     or "
+						}
+						expr += fmt.Sprintf("(%s:rate%s%s > %s and %s:rate%s%s > %s)",
+							ind.Ratio, b.Long, selector, threshold, ind.Ratio, b.Short, selector, threshold)
+					}
+					fmt.Fprintf(bw, "ALERT slo_%s_budget_burn\n", ind.Name)
+					fmt.Fprintf(bw, "  IF %s\n", expr)
+					fmt.Fprintf(bw, "  LABELS { severity = %q, objective = %q }\n", severity, number(ind.Target))
+					fmt.Fprintln(bw, "  ANNOTATIONS {")
+					fmt.Fprintf(bw, "      summary = %q,\n", fmt.Sprintf("%s %s burns its %s error budget", s.Name, o.Route, ind.Name))
+					fmt.Fprintf(bw, "      description = %q,\n", fmt.Sprintf("%s %s is out of its %s%% %s objective, {{ $value }} of its requests are bad.", s.Name, o.Route, number(ind.Target), ind.Name))
+					fmt.Fprintln(bw, "  }")
+					fmt.Fprintln(bw)
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
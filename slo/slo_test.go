@@ -0,0 +1,74 @@
+package slo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testConfig(t *testing.T) *Config {
+	c := &Config{}
+	err := json.Unmarshal([]byte(`{"services": [{"name": "feed", "objectives": [
+		{"route": "GetFeeds", "availability": 99.9, "latency": {"threshold": "100ms", "target": 99}}
+	]}]}`), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []*Config{
+		{Services: []Service{{Name: "feed", Objectives: []Objective{{Route: "GetFeeds", Availability: 100}}}}},
+		{Services: []Service{{Name: "feed", Objectives: []Objective{{Route: "GetFeeds", Latency: &Latency{Target: 99}}}}}},
+		{Services: []Service{{Name: "feed", Objectives: []Objective{{Availability: 99}}}}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v is valid", c.Services[0].Objectives[0])
+		}
+	}
+	if d := testConfig(t).Services[0].Objectives[0].Latency.Threshold; time.Duration(d) != 100*time.Millisecond {
+		t.Errorf("threshold %v", time.Duration(d))
+	}
+}
+
+func TestWriteRules(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRules(&buf, testConfig(t)); err != nil {
+		t.Fatal(err)
+	}
+	rules := buf.String()
+	for _, want := range []string{
+		"slo:error_ratio:rate3d = sum(rate(slo_errors_total[3d])) by (service, route) / sum(rate(slo_requests_total[3d])) by (service, route)",
+		`(slo:error_ratio:rate1h{service="feed",route="GetFeeds"} > 0.0144 and slo:error_ratio:rate5m{service="feed",route="GetFeeds"} > 0.0144)`,
+		`(slo:slow_ratio:rate3d{service="feed",route="GetFeeds"} > 0.01 and slo:slow_ratio:rate6h{service="feed",route="GetFeeds"} > 0.01)`,
+		"ALERT slo_latency_budget_burn",
+	} {
+		if !strings.Contains(rules, want) {
+			t.Errorf("missing %s in\n%s", want, rules)
+		}
+	}
+}
+
+func TestServerFault(t *testing.T) {
+	for err, want := range map[error]bool{
+		nil:                                        false,
+		errors.New("feed not found"):               false,
+		status.Error(codes.NotFound, "x"):          false,
+		status.Error(codes.Unavailable, "x"):       true,
+		status.Error(codes.ResourceExhausted, "x"): true,
+	} {
+		if got := ServerFault(err); got != want {
+			t.Errorf("ServerFault(%v) = %v", err, got)
+		}
+	}
+}
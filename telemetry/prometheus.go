@@ -0,0 +1,119 @@
+package telemetry
+
+import (
+	"context"
+	"math"
+	"time"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// prometheusProducer exports the metrics of a Prometheus registry, where the
+// go-kit metrics of the process live, through OpenTelemetry. The Prometheus
+// metrics are cumulative since the start of the process.
+type prometheusProducer struct {
+	gatherer stdprometheus.Gatherer
+	start    time.Time
+}
+
+func newPrometheusProducer() *prometheusProducer {
+	return &prometheusProducer{gatherer: stdprometheus.DefaultGatherer, start: time.Now()}
+}
+
+func (p *prometheusProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	// Gather returns what it could collect along with the error.
+	if err != nil && len(families) == 0 {
+		return nil, err
+	}
+	now := time.Now()
+	scope := metricdata.ScopeMetrics{Scope: instrumentation.Scope{Name: "github.com/prometheus/client_golang"}}
+	for _, f := range families {
+		m := metricdata.Metrics{Name: f.GetName(), Description: f.GetHelp()}
+		switch f.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+			for _, pm := range f.GetMetric() {
+				sum.DataPoints = append(sum.DataPoints, p.point(pm, now, pm.GetCounter().GetValue()))
+			}
+			m.Data = sum
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			gauge := metricdata.Gauge[float64]{}
+			for _, pm := range f.GetMetric() {
+				v := pm.GetGauge().GetValue()
+				if f.GetType() == dto.MetricType_UNTYPED {
+					v = pm.GetUntyped().GetValue()
+				}
+				gauge.DataPoints = append(gauge.DataPoints, p.point(pm, now, v))
+			}
+			m.Data = gauge
+		case dto.MetricType_HISTOGRAM:
+			hist := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+			for _, pm := range f.GetMetric() {
+				hist.DataPoints = append(hist.DataPoints, p.histogram(pm, now))
+			}
+			m.Data = hist
+		case dto.MetricType_SUMMARY:
+			summary := metricdata.Summary{}
+			for _, pm := range f.GetMetric() {
+				s := pm.GetSummary()
+				dp := metricdata.SummaryDataPoint{
+					Attributes: attributes(pm),
+					StartTime:  p.start,
+					Time:       now,
+					Count:      s.GetSampleCount(),
+					Sum:        s.GetSampleSum(),
+				}
+				for _, q := range s.GetQuantile() {
+					dp.QuantileValues = append(dp.QuantileValues, metricdata.QuantileValue{Quantile: q.GetQuantile(), Value: q.GetValue()})
+				}
+				summary.DataPoints = append(summary.DataPoints, dp)
+			}
+			m.Data = summary
+		default:
+			continue
+		}
+		scope.Metrics = append(scope.Metrics, m)
+	}
+	return []metricdata.ScopeMetrics{scope}, nil
+}
+
+func (p *prometheusProducer) point(pm *dto.Metric, now time.Time, v float64) metricdata.DataPoint[float64] {
+	return metricdata.DataPoint[float64]{Attributes: attributes(pm), StartTime: p.start, Time: now, Value: v}
+}
+
+// histogram converts the cumulative buckets of Prometheus into the
+// per-bucket counts of OpenTelemetry, the last one being +Inf.
+func (p *prometheusProducer) histogram(pm *dto.Metric, now time.Time) metricdata.HistogramDataPoint[float64] {
+	h := pm.GetHistogram()
+	dp := metricdata.HistogramDataPoint[float64]{
+		Attributes: attributes(pm),
+		StartTime:  p.start,
+		Time:       now,
+		Count:      h.GetSampleCount(),
+		Sum:        h.GetSampleSum(),
+	}
+	var below uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			break
+		}
+		dp.Bounds = append(dp.Bounds, b.GetUpperBound())
+		dp.BucketCounts = append(dp.BucketCounts, b.GetCumulativeCount()-below)
+		below = b.GetCumulativeCount()
+	}
+	dp.BucketCounts = append(dp.BucketCounts, h.GetSampleCount()-below)
+	return dp
+}
+
+func attributes(pm *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(pm.GetLabel()))
+	for _, l := range pm.GetLabel() {
+		kvs = append(kvs, attribute.String(l.GetName(), l.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
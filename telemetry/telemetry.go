@@ -0,0 +1,156 @@
+// Package telemetry sets up OpenTelemetry for the services, the clients and
+// the gateway. The tracer it returns is an OpenTracing bridge: the go-kit
+// tracing middlewares keep working, while the spans are OpenTelemetry ones
+// propagated in the W3C trace-context headers. The go-kit metrics are
+// exported along with them, see prometheus.go.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters, as accepted by Config.
+const (
+	// ExporterOTLP sends the traces and metrics to a collector over OTLP/HTTP.
+	ExporterOTLP = "otlp"
+	// ExporterStdout prints them as JSON, for local use.
+	ExporterStdout = "stdout"
+	// ExporterFile appends them as JSON to a file, for local use.
+	ExporterFile = "file"
+)
+
+// defaultInterval is the default period of the metrics export.
+const defaultInterval = 15 * time.Second
+
+type Config struct {
+	// Service names the process in the traces and metrics.
+	Service string
+	// Exporter is one of the Exporter constants.
+	Exporter string
+	// Endpoint is the host:port of the OTLP collector, by default taken from
+	// OTEL_EXPORTER_OTLP_ENDPOINT, or the path of the file of ExporterFile.
+	Endpoint string
+	// Sample is the fraction of the traces started here which are recorded,
+	// between 0 and 1. The traces started by a caller follow its decision.
+	Sample float64
+	// Interval is the period of the metrics export, 15s when zero.
+	Interval time.Duration
+}
+
+// Telemetry holds the tracer of a process and the exporters behind it.
+type Telemetry struct {
+	Tracer stdopentracing.Tracer
+
+	traces  *sdktrace.TracerProvider
+	metrics *sdkmetric.MeterProvider
+	closer  io.Closer
+}
+
+// New starts exporting the traces and metrics of the process as c says, and
+// installs the W3C trace-context propagator. Errors of the exporters are
+// logged to logger.
+func New(ctx context.Context, c Config, logger log.Logger) (*Telemetry, error) {
+	t := &Telemetry{}
+	var (
+		spanExporter   sdktrace.SpanExporter
+		metricExporter sdkmetric.Exporter
+		err            error
+	)
+	switch c.Exporter {
+	case ExporterOTLP:
+		var traceOpts []otlptracehttp.Option
+		var metricOpts []otlpmetrichttp.Option
+		if c.Endpoint != "" {
+			traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(c.Endpoint), otlptracehttp.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetrichttp.WithEndpoint(c.Endpoint), otlpmetrichttp.WithInsecure())
+		}
+		if spanExporter, err = otlptracehttp.New(ctx, traceOpts...); err != nil {
+			return nil, err
+		}
+		if metricExporter, err = otlpmetrichttp.New(ctx, metricOpts...); err != nil {
+			return nil, err
+		}
+	case ExporterStdout, ExporterFile:
+		var w io.Writer = os.Stdout
+		if c.Exporter == ExporterFile {
+			f, err := os.OpenFile(c.Endpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			w, t.closer = f, f
+		}
+		if spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(w)); err != nil {
+			return nil, err
+		}
+		if metricExporter, err = stdoutmetric.New(stdoutmetric.WithWriter(w)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", c.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", c.Service)))
+	if err != nil {
+		return nil, err
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	t.traces = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.Sample))),
+		sdktrace.WithResource(res),
+	)
+	t.metrics = sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter,
+			sdkmetric.WithInterval(interval),
+			sdkmetric.WithProducer(newPrometheusProducer()),
+		)),
+		sdkmetric.WithResource(res),
+	)
+
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	bridge, provider := otelbridge.NewTracerPair(t.traces.Tracer("github.com/buptmiao/microservice-app"))
+	bridge.SetTextMapPropagator(propagator)
+	bridge.SetWarningHandler(func(msg string) { logger.Log("warn", msg) })
+	otel.SetTracerProvider(provider)
+	otel.SetMeterProvider(t.metrics)
+	otel.SetTextMapPropagator(propagator)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) { logger.Log("err", err) }))
+	t.Tracer = bridge
+	return t, nil
+}
+
+// Shutdown flushes the pending spans and metrics and stops the exporters.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	err := t.traces.Shutdown(ctx)
+	if merr := t.metrics.Shutdown(ctx); err == nil {
+		err = merr
+	}
+	if t.closer != nil {
+		if cerr := t.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	stdopentracing "github.com/opentracing/opentracing-go"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestTraceContextPropagation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	tel, err := New(context.Background(), Config{Service: "test", Exporter: ExporterFile, Endpoint: path, Sample: 1}, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := tel.Tracer.StartSpan("parent")
+	headers := stdopentracing.HTTPHeadersCarrier{}
+	if err := tel.Tracer.Inject(span.Context(), stdopentracing.HTTPHeaders, headers); err != nil {
+		t.Fatal(err)
+	}
+	if len(headers["Traceparent"]) != 1 {
+		t.Fatalf("no W3C traceparent header in %v", headers)
+	}
+	parent, err := tel.Tracer.Extract(stdopentracing.HTTPHeaders, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tel.Tracer.StartSpan("child", stdopentracing.ChildOf(parent)).Finish()
+	span.Finish()
+
+	if err := tel.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Fatalf("nothing exported to %s: %v", path, err)
+	}
+}
+
+func TestPrometheusHistogram(t *testing.T) {
+	registry := stdprometheus.NewRegistry()
+	h := stdprometheus.NewHistogram(stdprometheus.HistogramOpts{Name: "latency_seconds", Buckets: []float64{.1, 1}})
+	registry.MustRegister(h)
+	for _, v := range []float64{.05, .5, .5, 5} {
+		h.Observe(v)
+	}
+
+	p := newPrometheusProducer()
+	p.gatherer = registry
+	scopes, err := p.Produce(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp := scopes[0].Metrics[0].Data.(metricdata.Histogram[float64]).DataPoints[0]
+	want := []uint64{1, 2, 1}
+	if dp.Count != 4 || len(dp.Bounds) != 2 || len(dp.BucketCounts) != len(want) {
+		t.Fatalf("got %+v", dp)
+	}
+	for i := range want {
+		if dp.BucketCounts[i] != want[i] {
+			t.Fatalf("bucket counts %v, want %v", dp.BucketCounts, want)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package topic
 
 import (
 	"context"
-	"fmt"
+	"github.com/buptmiao/microservice-app/limit"
+	"github.com/buptmiao/microservice-app/logging"
 	"github.com/buptmiao/microservice-app/proto/topic"
+	"github.com/buptmiao/microservice-app/slo"
+	"github.com/buptmiao/microservice-app/util"
+	"github.com/buptmiao/microservice-app/validate"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
 	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/prometheus"
 	"github.com/go-kit/kit/tracing/opentracing"
@@ -17,11 +22,27 @@ import (
 )
 
 var (
-	duration metrics.Histogram = prometheus.NewSummaryFrom(stdprometheus.SummaryOpts{
+	duration metrics.Histogram = prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
 		Namespace: "topic",
-		Name:      "request_duration_ns",
-		Help:      "Request duration in nanoseconds.",
-	}, []string{"method", "success"})
+		Name:      "request_duration_seconds",
+		Help:      "Request duration in seconds, by method and gRPC code.",
+		Buckets:   util.LatencyBuckets,
+	}, []string{"method", "code"})
+	requests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "topic",
+		Name:      "requests_total",
+		Help:      "Number of requests by method and gRPC code, the shed ones included.",
+	}, []string{"method", "code"})
+	concurrencyLimit metrics.Gauge = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Namespace: "topic",
+		Name:      "concurrency_limit",
+		Help:      "Current adaptive limit of concurrent requests.",
+	}, []string{})
+	shedRequests metrics.Counter = prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "topic",
+		Name:      "shed_requests_total",
+		Help:      "Requests rejected by the concurrency limit.",
+	}, []string{"method", "priority"})
 )
 
 func EndpointInstrumentingMiddleware(duration metrics.Histogram) endpoint.Middleware {
@@ -29,19 +50,41 @@ func EndpointInstrumentingMiddleware(duration metrics.Histogram) endpoint.Middle
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				duration.With("success", fmt.Sprint(err == nil)).Observe(time.Since(begin).Seconds())
+				duration.With("code", util.Code(err)).Observe(time.Since(begin).Seconds())
 			}(time.Now())
 			return next(ctx, request)
 		}
 	}
 }
 
+// EndpointCountingMiddleware counts the requests by gRPC code. It wraps the
+// concurrency limit, so that the shed requests are counted too.
+func EndpointCountingMiddleware(requests metrics.Counter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func() {
+				requests.With("code", util.Code(err)).Add(1)
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// EndpointLoggingMiddleware logs every request with the trace and request
+// ids of its context, failures as errors. The payloads are logged at the
+// debug level, redacted.
 func EndpointLoggingMiddleware(logger log.Logger) endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 
 			defer func(begin time.Time) {
-				logger.Log("error", err, "took", time.Since(begin))
+				logger := logging.With(ctx, logger)
+				if err != nil {
+					level.Error(logger).Log("error", err, "took", time.Since(begin))
+				} else {
+					level.Info(logger).Log("took", time.Since(begin))
+				}
+				level.Debug(logger).Log("request", logging.Payload(request), "response", logging.Payload(response))
 			}(time.Now())
 			return next(ctx, request)
 		}
@@ -61,24 +104,92 @@ func MakeGetTopicEndpoint(s topic.TopicServer, tracer stdopentracing.Tracer, log
 	return ep
 }
 
+func MakeCreateTopicEndpoint(s topic.TopicServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*topic.CreateTopicRequest)
+		return s.CreateTopic(ctx, req)
+	}
+	epduration := duration.With("method", "CreateTopic")
+	eplog := log.With(logger, "method", "CreateTopic")
+	ep = opentracing.TraceServer(tracer, "CreateTopic")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeSearchTopicsEndpoint(s topic.TopicServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*topic.SearchTopicsRequest)
+		return s.SearchTopics(ctx, req)
+	}
+	epduration := duration.With("method", "SearchTopics")
+	eplog := log.With(logger, "method", "SearchTopics")
+	ep = opentracing.TraceServer(tracer, "SearchTopics")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
+func MakeResolveTagsEndpoint(s topic.TopicServer, tracer stdopentracing.Tracer, logger log.Logger) endpoint.Endpoint {
+	ep := func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(*topic.ResolveTagsRequest)
+		return s.ResolveTags(ctx, req)
+	}
+	epduration := duration.With("method", "ResolveTags")
+	eplog := log.With(logger, "method", "ResolveTags")
+	ep = opentracing.TraceServer(tracer, "ResolveTags")(ep)
+	ep = EndpointInstrumentingMiddleware(epduration)(ep)
+	ep = EndpointLoggingMiddleware(eplog)(ep)
+	return ep
+}
+
 // MakeGRPCServer makes a set of endpoints available as a gRPC AddServer.
 func MakeGRPCServer(ctx context.Context, s topic.TopicServer, tracer stdopentracing.Tracer, logger log.Logger) topic.TopicServer {
 	options := []grpctransport.ServerOption{
 		grpctransport.ServerErrorLogger(logger),
+		grpctransport.ServerBefore(logging.GRPCToContext),
+	}
+	limiter := limit.NewLimiter(concurrencyLimit)
+	limited := func(method string, p limit.Priority, ep endpoint.Endpoint) endpoint.Endpoint {
+		ep = limit.Middleware(limiter, p, shedRequests.With("method", method))(ep)
+		ep = validate.Middleware()(ep)
+		ep = slo.Middleware(method)(ep)
+		return EndpointCountingMiddleware(requests.With("method", method))(ep)
 	}
 
 	return &grpcServer{
 		gettopic: grpctransport.NewServer(
-			MakeGetTopicEndpoint(s, tracer, logger),
+			limited("GetTopic", limit.Read, MakeGetTopicEndpoint(s, tracer, logger)),
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
 			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "GetTopic", logger)))...,
 		),
+		createtopic: grpctransport.NewServer(
+			limited("CreateTopic", limit.Write, MakeCreateTopicEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "CreateTopic", logger)))...,
+		),
+		searchtopics: grpctransport.NewServer(
+			limited("SearchTopics", limit.Read, MakeSearchTopicsEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "SearchTopics", logger)))...,
+		),
+		resolvetags: grpctransport.NewServer(
+			limited("ResolveTags", limit.Read, MakeResolveTagsEndpoint(s, tracer, logger)),
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			append(options, grpctransport.ServerBefore(opentracing.GRPCToContext(tracer, "ResolveTags", logger)))...,
+		),
 	}
 }
 
 type grpcServer struct {
-	gettopic grpctransport.Handler
+	gettopic     grpctransport.Handler
+	createtopic  grpctransport.Handler
+	searchtopics grpctransport.Handler
+	resolvetags  grpctransport.Handler
 }
 
 func (s *grpcServer) GetTopic(ctx oldcontext.Context, req *topic.GetTopicRequest) (*topic.GetTopicResponse, error) {
@@ -88,3 +199,27 @@ func (s *grpcServer) GetTopic(ctx oldcontext.Context, req *topic.GetTopicRequest
 	}
 	return rep.(*topic.GetTopicResponse), nil
 }
+
+func (s *grpcServer) CreateTopic(ctx oldcontext.Context, req *topic.CreateTopicRequest) (*topic.OkResponse, error) {
+	_, rep, err := s.createtopic.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*topic.OkResponse), nil
+}
+
+func (s *grpcServer) SearchTopics(ctx oldcontext.Context, req *topic.SearchTopicsRequest) (*topic.SearchTopicsResponse, error) {
+	_, rep, err := s.searchtopics.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*topic.SearchTopicsResponse), nil
+}
+
+func (s *grpcServer) ResolveTags(ctx oldcontext.Context, req *topic.ResolveTagsRequest) (*topic.ResolveTagsResponse, error) {
+	_, rep, err := s.resolvetags.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return rep.(*topic.ResolveTagsResponse), nil
+}
@@ -1,21 +1,36 @@
 package topic
 
 import (
-	"errors"
 	"github.com/buptmiao/microservice-app/proto/topic"
+	"github.com/buptmiao/microservice-app/search"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"sync"
 )
 
+// The errors are gRPC statuses, so that the gateway can tell the faults of
+// the request from those of the service.
 var (
-	ErrTopicNotFound = errors.New("topic not found")
+	ErrTopicNotFound = status.Error(codes.NotFound, "topic not found")
 )
 
 var (
 	mem map[int64]*Topic
 	mu  sync.RWMutex
+
+	// full-text index over subject and content, keyed by topic id.
+	index *search.Index
+	// hashtag of the subject to topic id.
+	tags map[string]int64
 )
 
+func init() {
+	mem = make(map[int64]*Topic)
+	index = search.NewIndex()
+	tags = make(map[string]int64)
+}
+
 type Topic struct {
 	TopicID int64
 	Subject string
@@ -42,3 +57,59 @@ func (s service) GetTopic(_ context.Context, req *topic.GetTopicRequest) (*topic
 	}
 	return nil, ErrTopicNotFound
 }
+
+func (s service) CreateTopic(_ context.Context, req *topic.CreateTopicRequest) (*topic.OkResponse, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if old, ok := mem[req.TopicId]; ok {
+		// the tag may have been taken over by another topic since.
+		if tag := search.Tag(old.Subject); tags[tag] == old.TopicID {
+			delete(tags, tag)
+		}
+	}
+	mem[req.TopicId] = &Topic{
+		TopicID: req.TopicId,
+		Subject: req.Subject,
+		Content: req.Content,
+	}
+	index.Add(req.TopicId, req.Subject, req.Content)
+	if tag := search.Tag(req.Subject); tag != "" {
+		tags[tag] = req.TopicId
+	}
+	return &topic.OkResponse{}, nil
+}
+
+func (s service) SearchTopics(_ context.Context, req *topic.SearchTopicsRequest) (*topic.SearchTopicsResponse, error) {
+	size := req.GetSize()
+	if size <= 0 {
+		size = topic.DefaultPageSize
+	}
+	hits := index.Search(req.GetQuery(), int(size))
+	topics := []*topic.GetTopicResponse{}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, hit := range hits {
+		if ti, ok := mem[hit.ID]; ok {
+			topics = append(topics, &topic.GetTopicResponse{
+				TopicId: ti.TopicID,
+				Subject: ti.Subject,
+				Content: ti.Content,
+			})
+		}
+	}
+	return &topic.SearchTopicsResponse{Topics: topics}, nil
+}
+
+// ResolveTags maps hashtags to the topics whose subject they name. Unknown
+// tags are left out of the response.
+func (s service) ResolveTags(_ context.Context, req *topic.ResolveTagsRequest) (*topic.ResolveTagsResponse, error) {
+	resp := &topic.ResolveTagsResponse{TopicIds: make(map[string]int64)}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, tag := range req.GetTags() {
+		if id, ok := tags[search.Tag(tag)]; ok {
+			resp.TopicIds[tag] = id
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LatencyBuckets are the histogram buckets of the request durations, in
+// seconds. The in-memory services answer well under a millisecond, a request
+// through the gateway may take seconds.
+var LatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Code returns the gRPC code of err for the metric labels, "OK" for nil.
+func Code(err error) string {
+	switch err {
+	case context.Canceled:
+		return codes.Canceled.String()
+	case context.DeadlineExceeded:
+		return codes.DeadlineExceeded.String()
+	}
+	return status.Code(err).String()
+}
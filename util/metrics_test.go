@@ -0,0 +1,29 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCode(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		want string
+	}{
+		{nil, "OK"},
+		{context.Canceled, "Canceled"},
+		{context.DeadlineExceeded, "DeadlineExceeded"},
+		{status.Error(codes.NotFound, "feed not found"), "NotFound"},
+		{status.Error(codes.Unavailable, "transport is closing"), "Unavailable"},
+		// errors which are not statuses have no code.
+		{errors.New("boom"), "Unknown"},
+	} {
+		if got := Code(c.err); got != c.want {
+			t.Errorf("Code(%v) = %s, want %s", c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package validate checks the requests of the services against the rules
+// declared next to their messages, see proto/*/validate.go. The services
+// enforce them with Middleware and the gateway mirrors them, the failures
+// are InvalidArgument errors listing the field violations.
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-kit/kit/endpoint"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxPageSize is the largest page a request may ask for.
+const MaxPageSize = 100
+
+// Validator is a request with rules.
+type Validator interface {
+	Validate() error
+}
+
+// Violation is a field of a request breaking a rule, the Field being its
+// name in the proto message.
+type Violation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// Error lists the violations of a request. It is an InvalidArgument status
+// with the violations as errdetails.BadRequest details.
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Field+": "+v.Description)
+	}
+	return "invalid argument: " + strings.Join(parts, "; ")
+}
+
+// GRPCStatus makes gRPC send e as an InvalidArgument status.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, e.Error())
+	br := &errdetails.BadRequest{}
+	for _, v := range e.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	if detailed, err := st.WithDetails(br); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// Rule checks one field, it returns nil when the field is valid.
+type Rule func() *Violation
+
+// Check applies the rules and returns an *Error with every violation, nil
+// if there is none.
+func Check(rules ...Rule) error {
+	var violations []Violation
+	for _, r := range rules {
+		if v := r(); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: violations}
+}
+
+// Request checks request if it is a Validator.
+func Request(request interface{}) error {
+	if v, ok := request.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Middleware rejects the requests breaking their rules before they reach
+// next.
+func Middleware() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := Request(request); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+func violation(field, format string, args ...interface{}) *Violation {
+	return &Violation{Field: field, Description: fmt.Sprintf(format, args...)}
+}
+
+// Positive requires an id to be set.
+func Positive(field string, v int64) Rule {
+	return func() *Violation {
+		if v <= 0 {
+			return violation(field, "must be positive")
+		}
+		return nil
+	}
+}
+
+// NonNegative accepts zero, for the optional ids and versions.
+func NonNegative(field string, v int64) Rule {
+	return func() *Violation {
+		if v < 0 {
+			return violation(field, "must not be negative")
+		}
+		return nil
+	}
+}
+
+// PageSize accepts zero, which asks for the default size of the method, up
+// to MaxPageSize.
+func PageSize(field string, v int64) Rule {
+	return func() *Violation {
+		if v < 0 || v > MaxPageSize {
+			return violation(field, "must be between 0 and %d", MaxPageSize)
+		}
+		return nil
+	}
+}
+
+// Text requires s to be valid UTF-8 of at most max characters, and not
+// blank when required.
+func Text(field, s string, max int, required bool) Rule {
+	return func() *Violation {
+		switch {
+		case !utf8.ValidString(s):
+			return violation(field, "must be valid UTF-8")
+		case required && strings.TrimSpace(s) == "":
+			return violation(field, "is required")
+		case utf8.RuneCountInString(s) > max:
+			return violation(field, "must be at most %d characters", max)
+		}
+		return nil
+	}
+}
+
+// Items limits the number of items of a repeated field.
+func Items(field string, n, max int) Rule {
+	return func() *Violation {
+		if n > max {
+			return violation(field, "must have at most %d items", max)
+		}
+		return nil
+	}
+}
+
+// Texts applies Text to every item of a repeated field, naming them
+// field[i].
+func Texts(field string, items []string, max int) []Rule {
+	rules := make([]Rule, 0, len(items))
+	for i, s := range items {
+		rules = append(rules, Text(fmt.Sprintf("%s[%d]", field, i), s, max, true))
+	}
+	return rules
+}
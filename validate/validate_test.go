@@ -0,0 +1,73 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheck(t *testing.T) {
+	if err := Check(Positive("id", 1), PageSize("size", MaxPageSize), Text("content", "hi", 2, true)); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	err := Check(
+		Positive("id", 0),
+		NonNegative("version", -1),
+		PageSize("size", MaxPageSize+1),
+		Text("content", " ", 10, true),
+		Text("query", strings.Repeat("é", 3), 2, false),
+		Text("name", "\xff", 10, false),
+		Items("tags", 3, 2),
+	)
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("got %v, want *Error", err)
+	}
+	want := []string{"id", "version", "size", "content", "query", "name", "tags"}
+	if len(e.Violations) != len(want) {
+		t.Fatalf("got %v, want violations of %v", e.Violations, want)
+	}
+	for i, f := range want {
+		if e.Violations[i].Field != f {
+			t.Errorf("violation %d on %s, want %s", i, e.Violations[i].Field, f)
+		}
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	err := Check(Texts("tags", []string{"go", ""}, 10)...)
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("got %v, want InvalidArgument", err)
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("got %d details, want 1", len(details))
+	}
+	br, ok := details[0].(*errdetails.BadRequest)
+	if !ok || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "tags[1]" {
+		t.Errorf("got %v, want a violation of tags[1]", details[0])
+	}
+}
+
+type request struct{ err error }
+
+func (r request) Validate() error { return r.err }
+
+func TestMiddleware(t *testing.T) {
+	called := false
+	ep := Middleware()(func(context.Context, interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	if _, err := ep(context.Background(), request{err: Check(Positive("id", 0))}); err == nil || called {
+		t.Errorf("invalid request reached the endpoint")
+	}
+	if _, err := ep(context.Background(), request{}); err != nil || !called {
+		t.Errorf("valid request did not reach the endpoint: %v", err)
+	}
+}